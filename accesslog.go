@@ -0,0 +1,106 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AccessLog returns middleware writing a line per request to w, e.g.
+//
+//	method=GET pattern="/user/:name" status=200 duration=1.2ms
+//
+// The pattern is the path the matched route was registered with, so requests
+// of a route can be aggregated regardless of their parameter values. Lines are
+// written after the handle returned, serialized so concurrent requests don't
+// interleave.
+func AccessLog(w io.Writer) Middleware {
+	var mu sync.Mutex
+	return func(next Handle) Handle {
+		return func(rw http.ResponseWriter, req *http.Request, ps Params) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: rw}
+			next(rec, req, ps)
+			elapsed := time.Since(start)
+
+			var pattern string
+			if rt := RouteFromContext(req.Context()); rt != nil {
+				pattern = rt.Path
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			fmt.Fprintf(w, "method=%s pattern=%q status=%d duration=%s\n", req.Method, pattern, rec.status(), elapsed)
+		}
+	}
+}
+
+// statusRecorder is a http.ResponseWriter recording the status code of the
+// response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush sends the buffered data to the client if the wrapped
+// http.ResponseWriter supports it, see http.Flusher.
+func (r *statusRecorder) Flush() {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack takes over the connection if the wrapped http.ResponseWriter
+// supports it, see http.Hijacker, e.g. for websockets.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && r.code == 0 {
+		r.code = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Push initiates an HTTP/2 server push if the wrapped http.ResponseWriter
+// supports it, see http.Pusher.
+func (r *statusRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := r.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// status returns the status code of the response, 200 OK if the handle wrote
+// nothing.
+func (r *statusRecorder) status() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
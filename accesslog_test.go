@@ -0,0 +1,47 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	var log strings.Builder
+	router := New()
+	router.Use(AccessLog(&log))
+	router.GET("/user/:name", func(w http.ResponseWriter, _ *http.Request, _ Params) {
+		w.WriteHeader(http.StatusTeapot)
+		w.WriteHeader(http.StatusOK)
+	})
+	router.GET("/ok", func(w http.ResponseWriter, _ *http.Request, _ Params) {
+		w.Write([]byte("ok"))
+	})
+
+	for _, path := range []string{"/user/gopher", "/ok"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	lines := strings.Split(strings.TrimSuffix(log.String(), "\n"), "\n")
+	want := []*regexp.Regexp{
+		regexp.MustCompile(`^method=GET pattern="/user/:name" status=418 duration=\S+$`),
+		regexp.MustCompile(`^method=GET pattern="/ok" status=200 duration=\S+$`),
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("wrong number of lines: %q", log.String())
+	}
+	for i, line := range lines {
+		if !want[i].MatchString(line) {
+			t.Errorf("wrong line: %q", line)
+		}
+	}
+	if strings.Contains(log.String(), "gopher") {
+		t.Errorf("raw path logged: %q", log.String())
+	}
+}
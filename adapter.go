@@ -0,0 +1,50 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RegisterAdapter adds an adapter converting handles of other shapes, e.g.
+// http.Handler or functions returning an error, to a Handle. Once adapters
+// are registered, Handle, Replace and their variants store every handle as a
+// Handle: handles which are a Handle already are stored as is, other handles
+// are passed to the adapters in the order they were registered, and the
+// Handle returned by the first adapter reporting a match is stored. Lookups
+// return the stored Handle. Handles matching no adapter are rejected with an
+// error wrapping ErrHandleType, unless PermissiveHandles is set.
+// RegisterAdapter must be called before registering routes.
+func (r *Router) RegisterAdapter(match func(interface{}) (Handle, bool)) {
+	r.adapters = append(r.adapters, match)
+}
+
+// adapt returns handle as stored by the router, see RegisterAdapter.
+func (r *Router) adapt(path string, handle interface{}) (interface{}, error) {
+	if len(r.adapters) == 0 {
+		return handle, nil
+	}
+	switch h := handle.(type) {
+	case Handle:
+		return h, nil
+	case func(http.ResponseWriter, *http.Request, Params):
+		return Handle(h), nil
+	case Placeholder:
+		return h, nil
+	}
+	for _, match := range r.adapters {
+		if h, ok := match(handle); ok {
+			if h == nil {
+				return nil, fmt.Errorf("path '%s': %w", path, ErrNilHandle)
+			}
+			return h, nil
+		}
+	}
+	if r.PermissiveHandles {
+		return handle, nil
+	}
+	return nil, fmt.Errorf("path '%s': %w: no adapter matches %T", path, ErrHandleType, handle)
+}
@@ -0,0 +1,89 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ctxHandler is a handler shape of an application, see TestRouterAdapters.
+type ctxHandler func(ctx context.Context, w io.Writer, ps Params) error
+
+func TestRouterAdapters(t *testing.T) {
+	router := New()
+	router.RegisterAdapter(func(handle interface{}) (Handle, bool) {
+		h, ok := handle.(http.Handler)
+		if !ok {
+			return nil, false
+		}
+		return func(w http.ResponseWriter, req *http.Request, ps Params) {
+			h.ServeHTTP(w, req)
+		}, true
+	})
+	router.RegisterAdapter(func(handle interface{}) (Handle, bool) {
+		h, ok := handle.(ctxHandler)
+		if !ok {
+			return nil, false
+		}
+		return func(w http.ResponseWriter, req *http.Request, ps Params) {
+			if err := h(req.Context(), w, ps); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		}, true
+	})
+
+	router.GET("/classic/:name", func(w http.ResponseWriter, _ *http.Request, ps Params) {
+		io.WriteString(w, "classic "+ps.ByName("name"))
+	})
+	router.GET("/handler", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, "handler")
+	}))
+	router.GET("/ctx/:name", ctxHandler(func(_ context.Context, w io.Writer, ps Params) error {
+		if ps.ByName("name") == "fail" {
+			return errors.New("failed")
+		}
+		_, err := io.WriteString(w, "ctx "+ps.ByName("name"))
+		return err
+	}))
+
+	if err := router.GET("/string", "string"); !errors.Is(err, ErrHandleType) {
+		t.Errorf("wrong error for an unmatched handle: %v", err)
+	}
+	router.PermissiveHandles = true
+	if err := router.GET("/string", "string"); err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/classic/gopher", http.StatusOK, "classic gopher"},
+		{"/handler", http.StatusOK, "handler"},
+		{"/ctx/gopher", http.StatusOK, "ctx gopher"},
+		{"/ctx/fail", http.StatusInternalServerError, "failed\n"},
+	}
+	for _, test := range tests {
+		if handle, _, _ := router.Lookup("GET", test.path); handle == nil {
+			t.Errorf("no handle for %s", test.path)
+		} else if _, ok := handle.(Handle); !ok {
+			t.Errorf("handle for %s not adapted: %T", test.path, handle)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("wrong response for %s: %d %q", test.path, w.Code, w.Body.String())
+		}
+	}
+	if handle, _, _ := router.Lookup("GET", "/string"); handle != "string" {
+		t.Errorf("wrong handle stored permissively: %v", handle)
+	}
+}
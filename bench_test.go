@@ -0,0 +1,850 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func benchHandle(_ http.ResponseWriter, _ *http.Request, _ Params) {}
+
+// allocRoutes are registered for the allocation tests and benchmarks below.
+var allocRoutes = [...]string{
+	"/",
+	"/static/path/to/somewhere",
+	"/user/:name",
+	"/user/:name/repos/:repo",
+	"/user/:name/repos/:repo/issues/:number",
+	"/src/*filepath",
+	"/files/:dir/*filepath",
+}
+
+func newAllocRouter() *Router {
+	router := New()
+	for _, route := range allocRoutes {
+		router.GET(route, benchHandle)
+	}
+	return router
+}
+
+func TestLookupAllocs(t *testing.T) {
+	tests := []struct {
+		path   string
+		allocs float64
+	}{
+		{"/", 0},
+		{"/static/path/to/somewhere", 0},
+		{"/user/gopher", 1},
+		{"/user/gopher/repos/xrouter", 1},
+		{"/user/gopher/repos/xrouter/issues/42", 1},
+		{"/src/some/file.go", 1},
+		{"/files/js/inc/framework.js", 1},
+	}
+	forBoth(t, newAllocRouter(), func(t *testing.T, router lookupRouter) {
+		for _, test := range tests {
+			handle, ps, _ := router.Lookup("GET", test.path)
+			if handle == nil {
+				t.Fatalf("no handle for %s", test.path)
+			}
+			if cap(ps) != len(ps) {
+				t.Errorf("params for %s not allocated with exact capacity: len %d, cap %d", test.path, len(ps), cap(ps))
+			}
+
+			allocs := testing.AllocsPerRun(100, func() {
+				router.Lookup("GET", test.path)
+			})
+			if allocs != test.allocs {
+				t.Errorf("wrong number of allocations for %s: want %v, got %v", test.path, test.allocs, allocs)
+			}
+		}
+	})
+}
+
+func TestLookupFuncAllocs(t *testing.T) {
+	var n int
+	visit := func(key, value string) { n++ }
+	forBoth(t, newAllocRouter(), func(t *testing.T, router lookupRouter) {
+		for _, path := range [...]string{
+			"/user/gopher",
+			"/user/gopher/repos/xrouter",
+			"/user/gopher/repos/xrouter/issues/42",
+			"/files/js/inc/framework.js",
+		} {
+			if handle, _ := router.LookupFunc("GET", path, visit); handle == nil {
+				t.Fatalf("no handle for %s", path)
+			}
+			allocs := testing.AllocsPerRun(100, func() {
+				router.LookupFunc("GET", path, visit)
+			})
+			if allocs != 0 {
+				t.Errorf("LookupFunc allocates for %s: %v allocs", path, allocs)
+			}
+		}
+	})
+}
+
+// largeParams returns n Params with the keys "p0" to "p<n-1>".
+func largeParams(n int) Params {
+	ps := make(Params, n)
+	for i := range ps {
+		ps[i] = Param{Key: "p" + strconv.Itoa(i), Value: strconv.Itoa(i)}
+	}
+	return ps
+}
+
+func TestParamsByNameAllocs(t *testing.T) {
+	ps := largeParams(64)
+	for _, name := range []string{"p0", "p63", "missing"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			ps.ByName(name)
+		})
+		if allocs != 0 {
+			t.Errorf("ByName allocates for %s: %v allocs", name, allocs)
+		}
+	}
+}
+
+func BenchmarkParamsByName(b *testing.B) {
+	for _, n := range []int{2, 8, 64} {
+		ps := largeParams(n)
+		last := ps[n-1].Key
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if ps.ByName(last) == "" {
+					b.Fatal("no value")
+				}
+			}
+		})
+	}
+}
+
+func benchLookup(b *testing.B, router lookupRouter, method, path string) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.Lookup(method, path)
+	}
+}
+
+func BenchmarkLookupStatic(b *testing.B) {
+	benchLookup(b, newAllocRouter(), "GET", "/static/path/to/somewhere")
+}
+
+func BenchmarkLookupParam(b *testing.B) {
+	benchLookup(b, newAllocRouter(), "GET", "/user/gopher")
+}
+
+func BenchmarkLookupParams3(b *testing.B) {
+	benchLookup(b, newAllocRouter(), "GET", "/user/gopher/repos/xrouter/issues/42")
+}
+
+func BenchmarkLookupParams5(b *testing.B) {
+	benchLookup(b, loadRoutes(deepRoutes), "GET", "/api/v1/orgs/acme/projects/rocket/envs/prod/services/api")
+}
+
+func BenchmarkLookupCatchAll(b *testing.B) {
+	benchLookup(b, newAllocRouter(), "GET", "/src/some/file.go")
+}
+
+// BenchmarkLookupFrozen compares lookups of a router before and after
+// Freeze, from parallel goroutines contending for the lock before.
+func BenchmarkLookupFrozen(b *testing.B) {
+	const path = "/user/gopher/repos/xrouter"
+	bench := func(b *testing.B, router lookupRouter) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				router.Lookup("GET", path)
+			}
+		})
+	}
+	b.Run("Locked", func(b *testing.B) {
+		bench(b, newAllocRouter())
+	})
+	b.Run("Frozen", func(b *testing.B) {
+		router := newAllocRouter()
+		router.Freeze()
+		bench(b, router)
+	})
+	b.Run("Snapshot", func(b *testing.B) {
+		bench(b, newAllocRouter().Freeze())
+	})
+}
+
+// BenchmarkLookupCatchAllTail shows that the cost of capturing a catch-all
+// value does not depend on its length.
+func BenchmarkLookupCatchAllTail(b *testing.B) {
+	router := newAllocRouter()
+	for _, n := range [...]int{16, 256, 2048} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			benchLookup(b, router, "GET", "/src/"+strings.Repeat("a", n))
+		})
+	}
+}
+
+func BenchmarkLookupFuncParams2(b *testing.B) {
+	router := newAllocRouter()
+	var repo string
+	visit := func(key, value string) {
+		if key == "repo" {
+			repo = value
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.LookupFunc("GET", "/user/gopher/repos/xrouter", visit)
+	}
+	if repo != "xrouter" {
+		b.Fatalf("wrong repo: %q", repo)
+	}
+}
+
+// TestLookupParamsCapacity verifies that the Params of each route are
+// allocated with a capacity of exactly its number of parameters, for trees
+// whose maximum number of parameters per path exceeds the route's.
+func TestLookupParamsCapacity(t *testing.T) {
+	for _, routes := range [...][]benchRoute{githubAPI, deepRoutes} {
+		router := loadRoutes(routes)
+		forBoth(t, router, func(t *testing.T, router lookupRouter) {
+			for _, route := range routes {
+				path := requestPath(route.path)
+				_, ps, _ := router.Lookup(route.method, path)
+				if want := int(countParams(route.path)); len(ps) != want || cap(ps) != want {
+					t.Errorf("wrong params for %s %s: len %d, cap %d, want %d", route.method, path, len(ps), cap(ps), want)
+				}
+			}
+		})
+	}
+}
+
+func loadRoutes(routes []benchRoute) *Router {
+	router := New()
+	for _, route := range routes {
+		if err := router.Handle(route.method, route.path, route.path); err != nil {
+			panic(err)
+		}
+	}
+	return router
+}
+
+// requestPath returns a request path matched by the route pattern, filling
+// in a value for each wildcard.
+func requestPath(pattern string) string {
+	var path strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case ':':
+			end := strings.IndexByte(pattern[i:], '/')
+			if end < 0 {
+				end = len(pattern) - i
+			}
+			path.WriteString("x" + pattern[i+1:i+end])
+			i += end - 1
+		case '*':
+			path.WriteString("mixed/Case/sub/dir.file")
+			i = len(pattern)
+		default:
+			path.WriteByte(pattern[i])
+		}
+	}
+	return path.String()
+}
+
+func TestBenchRoutes(t *testing.T) {
+	tables := map[string][]benchRoute{
+		"github": githubAPI,
+		"static": staticRoutes,
+		"deep":   deepRoutes,
+	}
+	for name, routes := range tables {
+		router := loadRoutes(routes)
+		for _, route := range routes {
+			path := requestPath(route.path)
+			handle, _, _ := router.Lookup(route.method, path)
+			if handle != route.path {
+				t.Errorf("%s: %s %s matched %v, want %s", name, route.method, path, handle, route.path)
+			}
+		}
+	}
+}
+
+func benchRoutes(b *testing.B, router lookupRouter, routes []benchRoute) {
+	paths := make([]string, len(routes))
+	for i, route := range routes {
+		paths[i] = requestPath(route.path)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, route := range routes {
+			router.Lookup(route.method, paths[j])
+		}
+	}
+}
+
+func BenchmarkGithubAll(b *testing.B) {
+	benchRoutes(b, loadRoutes(githubAPI), githubAPI)
+}
+
+func BenchmarkGithubStatic(b *testing.B) {
+	benchLookup(b, loadRoutes(githubAPI), "GET", "/user/repos")
+}
+
+func BenchmarkGithubParam(b *testing.B) {
+	benchLookup(b, loadRoutes(githubAPI), "GET", "/repos/julienschmidt/httprouter/stargazers")
+}
+
+func BenchmarkGithubParam5(b *testing.B) {
+	benchLookup(b, loadRoutes(githubAPI), "GET", "/legacy/issues/search/julienschmidt/httprouter/open/lookup")
+}
+
+func BenchmarkStaticAll(b *testing.B) {
+	benchRoutes(b, loadRoutes(staticRoutes), staticRoutes)
+}
+
+func BenchmarkStaticDeep(b *testing.B) {
+	benchLookup(b, loadRoutes(staticRoutes), "GET", "/gopher/pencil/gopherswrench.jpg")
+}
+
+func BenchmarkDeepAll(b *testing.B) {
+	benchRoutes(b, loadRoutes(deepRoutes), deepRoutes)
+}
+
+func BenchmarkDeepParam7(b *testing.B) {
+	benchLookup(b, loadRoutes(deepRoutes), "GET", "/api/v1/orgs/acme/projects/rocket/envs/prod/services/api/instances/i-42/metrics/cpu")
+}
+
+// newLargeRouter returns a router with 500 GET routes, a fifth of them with
+// parameters.
+func newLargeRouter() *Router {
+	router := New()
+	for i := 0; i < 500; i++ {
+		path := fmt.Sprintf("/service%02d/resource%02d", i/20, i%20)
+		if i%5 == 0 {
+			path += "/:id/details/:section"
+		}
+		router.GET(path, benchHandle)
+	}
+	return router
+}
+
+const (
+	largeStaticPath = "/service24/resource19"
+	largeParamPath  = "/service24/resource15/42/details/history"
+)
+
+func BenchmarkLargeLookupString(b *testing.B) {
+	router := newLargeRouter()
+	path := []byte(largeStaticPath)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.Lookup("GET", string(path))
+	}
+}
+
+func BenchmarkLargeLookupBytes(b *testing.B) {
+	router := newLargeRouter()
+	path := []byte(largeStaticPath)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.LookupBytes("GET", path)
+	}
+}
+
+func BenchmarkLargeLookupStringParam(b *testing.B) {
+	router := newLargeRouter()
+	path := []byte(largeParamPath)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.Lookup("GET", string(path))
+	}
+}
+
+func BenchmarkLargeLookupBytesParam(b *testing.B) {
+	router := newLargeRouter()
+	path := []byte(largeParamPath)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.LookupBytes("GET", path)
+	}
+}
+
+// adminRoutes are the settings pages of an admin interface, mostly static.
+var adminRoutes = []string{
+	"/api/v1/admin/settings",
+	"/api/v1/admin/settings/general",
+	"/api/v1/admin/settings/security",
+	"/api/v1/admin/settings/security/sessions",
+	"/api/v1/admin/settings/security/keys/:id",
+	"/api/v1/admin/settings/notifications",
+	"/api/v1/admin/settings/notifications/email",
+	"/api/v1/admin/settings/notifications/slack",
+	"/api/v1/admin/settings/notifications/webhooks/:id",
+	"/api/v1/admin/users",
+	"/api/v1/admin/users/:id",
+	"/api/v1/admin/users/:id/roles",
+}
+
+const deepStaticPath = "/api/v1/admin/settings/notifications/email"
+
+func newAdminRouter() *Router {
+	router := New()
+	for _, route := range adminRoutes {
+		router.GET(route, benchHandle)
+	}
+	return router
+}
+
+func BenchmarkDeepStatic(b *testing.B) {
+	benchLookup(b, newAdminRouter(), "GET", deepStaticPath)
+}
+
+// BenchmarkDeepStaticTree walks the tree, bypassing the static route map.
+func BenchmarkDeepStaticTree(b *testing.B) {
+	root := newAdminRouter().tree("GET").root
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.getValue(deepStaticPath)
+	}
+}
+
+func BenchmarkDeepStaticParam(b *testing.B) {
+	benchLookup(b, newAdminRouter(), "GET", "/api/v1/admin/settings/notifications/webhooks/42")
+}
+
+func BenchmarkGithubAllCompact(b *testing.B) {
+	router := loadRoutes(githubAPI)
+	router.Compact()
+	benchRoutes(b, router, githubAPI)
+}
+
+func BenchmarkDeepAllCompact(b *testing.B) {
+	router := loadRoutes(deepRoutes)
+	router.Compact()
+	benchRoutes(b, router, deepRoutes)
+}
+
+// newGeneratedRouter returns a router with 40k GET routes, spread over
+// tenants and services, most of them with parameters.
+func newGeneratedRouter() *Router {
+	router := New()
+	for i := 0; i < 40000; i++ {
+		path := fmt.Sprintf("/tenant%03d/service%02d/resource%d", i/400, i/20%20, i%20)
+		if i%4 != 0 {
+			path += "/:id/revisions/:rev"
+		}
+		router.GET(path, benchHandle)
+	}
+	return router
+}
+
+const generatedParamPath = "/tenant042/service13/resource7/42/revisions/3"
+
+// treeSize returns the number of bytes allocated for the tree below n,
+// excluding the values held by the nodes.
+func treeSize(n *node) (size uintptr) {
+	n.walk(func(n *node) {
+		size += unsafe.Sizeof(*n) + uintptr(len(n.path)+len(n.indices)) +
+			uintptr(cap(n.children))*unsafe.Sizeof(n)
+	})
+	return size
+}
+
+// compactSize returns the number of bytes allocated for t, excluding the
+// values held by the nodes.
+func compactSize(t *compactTree) uintptr {
+	return unsafe.Sizeof(*t) + uintptr(cap(t.nodes))*unsafe.Sizeof(compactNode{}) +
+		uintptr(len(t.paths)+len(t.indices)) + uintptr(cap(t.data))*unsafe.Sizeof(t.data[0])
+}
+
+// BenchmarkGeneratedCompact measures the compaction of 40k routes and reports
+// the memory used by the tree and by its compact form.
+func BenchmarkGeneratedCompact(b *testing.B) {
+	root := newGeneratedRouter().tree("GET").root
+	var nodes int
+	root.walk(func(*node) { nodes++ })
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var ct *compactTree
+	for i := 0; i < b.N; i++ {
+		ct = newCompactTree(root)
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(nodes), "nodes")
+	b.ReportMetric(float64(treeSize(root)), "tree-B")
+	b.ReportMetric(float64(compactSize(ct)), "compact-B")
+}
+
+func BenchmarkGeneratedLookup(b *testing.B) {
+	benchLookup(b, newGeneratedRouter(), "GET", generatedParamPath)
+}
+
+func BenchmarkGeneratedLookupCompact(b *testing.B) {
+	router := newGeneratedRouter()
+	router.Compact()
+	benchLookup(b, router, "GET", generatedParamPath)
+}
+
+// newWideRouter returns a router whose nodes have 62 children each, for the
+// first two bytes of the paths.
+func newWideRouter(impl Impl) (*Router, []string) {
+	const chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	router := NewWithImpl(impl)
+	var paths []string
+	for i := 0; i < len(chars); i++ {
+		for j := 0; j < len(chars); j++ {
+			path := "/" + chars[i:i+1] + chars[j:j+1] + "/:id"
+			router.GET(path, benchHandle)
+			paths = append(paths, requestPath(path))
+		}
+	}
+	return router, paths
+}
+
+// newDeepRouter returns a router whose routes branch in two at each of 8
+// levels.
+func newDeepRouter(impl Impl) (*Router, []string) {
+	router := NewWithImpl(impl)
+	var paths []string
+	for i := 0; i < 256; i++ {
+		var path strings.Builder
+		for bit := 7; bit >= 0; bit-- {
+			if i>>bit&1 == 0 {
+				path.WriteString("/left")
+			} else {
+				path.WriteString("/right")
+			}
+		}
+		path.WriteString("/:id")
+		router.GET(path.String(), benchHandle)
+		paths = append(paths, requestPath(path.String()))
+	}
+	return router, paths
+}
+
+// BenchmarkImpl compares the lookups of the implementations on wide and deep
+// trees.
+func BenchmarkImpl(b *testing.B) {
+	shapes := []struct {
+		name   string
+		router func(Impl) (*Router, []string)
+	}{
+		{"wide", newWideRouter},
+		{"deep", newDeepRouter},
+	}
+	for _, shape := range shapes {
+		for _, impl := range []Impl{ImplIndexed, ImplBinarySearch} {
+			b.Run(shape.name+"/"+impl.String(), func(b *testing.B) {
+				router, paths := shape.router(impl)
+				router.Lookup("GET", "/")
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					router.Lookup("GET", paths[i%len(paths)])
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGithubAllFrozen(b *testing.B) {
+	benchRoutes(b, loadRoutes(githubAPI).Freeze(), githubAPI)
+}
+
+func BenchmarkStaticAllFrozen(b *testing.B) {
+	benchRoutes(b, loadRoutes(staticRoutes).Freeze(), staticRoutes)
+}
+
+func BenchmarkDeepAllFrozen(b *testing.B) {
+	benchRoutes(b, loadRoutes(deepRoutes).Freeze(), deepRoutes)
+}
+
+func BenchmarkGeneratedLookupFrozen(b *testing.B) {
+	benchLookup(b, newGeneratedRouter().Freeze(), "GET", generatedParamPath)
+}
+
+// missPaths returns n random paths which mostly miss the routes, half of them
+// derived from the request paths of routes, so the lookup walks deep into the
+// tree before missing.
+func missPaths(routes []benchRoute, n int) []string {
+	rnd := rand.New(rand.NewSource(42))
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789/"
+	paths := make([]string, n)
+	for i := range paths {
+		suffix := make([]byte, 1+rnd.Intn(8))
+		for j := range suffix {
+			suffix[j] = chars[rnd.Intn(len(chars))]
+		}
+		if i%2 == 0 {
+			paths[i] = requestPath(routes[rnd.Intn(len(routes))].path) + "/" + string(suffix)
+		} else {
+			paths[i] = "/" + string(suffix)
+		}
+	}
+	return paths
+}
+
+func BenchmarkGithubMiss(b *testing.B) {
+	router := loadRoutes(githubAPI)
+	paths := missPaths(githubAPI, 1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.Lookup("GET", paths[i%len(paths)])
+	}
+}
+
+func BenchmarkGithubMissNoTSR(b *testing.B) {
+	router := loadRoutes(githubAPI)
+	paths := missPaths(githubAPI, 1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.LookupNoTSR("GET", paths[i%len(paths)])
+	}
+}
+
+var treeSink *methodTree
+
+// BenchmarkMethodTree isolates the selection of the tree of the method,
+// comparing the array of the standard methods with a map.
+func BenchmarkMethodTree(b *testing.B) {
+	router := loadRoutes(githubAPI)
+	methods := [...]string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	trees := make(map[string]*methodTree)
+	for _, method := range methods {
+		trees[method] = router.tree(method)
+	}
+
+	b.Run("Array", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			treeSink = router.tree(methods[i%len(methods)])
+		}
+	})
+	b.Run("Map", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			treeSink = trees[methods[i%len(methods)]]
+		}
+	})
+}
+
+// BenchmarkLookupCache compares cached and uncached lookups of paths with an
+// increasing number of parameters. A hit costs hashing the path and copying
+// the values, so the cache only wins once the tree walk gets longer, from
+// about two parameters on.
+func BenchmarkLookupCache(b *testing.B) {
+	paths := [...]string{
+		"/api/v1/orgs/acme",
+		"/api/v1/orgs/acme/projects/rocket/envs/prod",
+		"/api/v1/orgs/acme/projects/rocket/envs/prod/services/api/instances/i-42/metrics/cpu",
+	}
+	router := loadRoutes(deepRoutes)
+	cached := loadRoutes(deepRoutes)
+	cached.EnableLookupCache(16)
+
+	for _, path := range paths {
+		_, ps, _ := router.Lookup("GET", path)
+		b.Run(fmt.Sprintf("Params%d", len(ps)), func(b *testing.B) {
+			b.Run("Uncached", func(b *testing.B) {
+				benchLookup(b, router, "GET", path)
+			})
+			b.Run("Cached", func(b *testing.B) {
+				benchLookup(b, cached, "GET", path)
+			})
+		})
+	}
+}
+
+// BenchmarkGeneratedTree measures building the tree of 40k routes and reports
+// the memory taken by its nodes.
+func BenchmarkGeneratedTree(b *testing.B) {
+	b.ReportAllocs()
+	var root *node
+	for i := 0; i < b.N; i++ {
+		root = newGeneratedRouter().tree("GET").root
+	}
+	b.StopTimer()
+
+	var nodes int
+	root.walk(func(*node) { nodes++ })
+	b.ReportMetric(float64(nodes), "nodes")
+	b.ReportMetric(float64(unsafe.Sizeof(*root)), "node-B")
+	b.ReportMetric(float64(treeSize(root)), "tree-B")
+}
+
+// newSkewedRouter returns a router whose root has 20 children, the first
+// holding the most routes and the last, "/t", only one.
+func newSkewedRouter() *Router {
+	router := New()
+	for c := 0; c < 20; c++ {
+		for j := 0; j < 20-c; j++ {
+			router.GET(fmt.Sprintf("/%c/r%d/:id", 'a'+c, j), benchHandle)
+		}
+	}
+	return router
+}
+
+// skewedPaths returns lookups of which 9 in 10 request the route "/t/r0/:id".
+func skewedPaths() []string {
+	rnd := rand.New(rand.NewSource(1))
+	paths := make([]string, 1000)
+	for i := range paths {
+		if i%10 != 0 {
+			paths[i] = "/t/r0/42"
+			continue
+		}
+		c := rnd.Intn(20)
+		paths[i] = fmt.Sprintf("/%c/r%d/42", 'a'+c, rnd.Intn(20-c))
+	}
+	return paths
+}
+
+// siblingChecks returns the number of index chars compared while walking
+// the tree down path.
+func siblingChecks(n *node, path string) (checks int) {
+	for len(path) > len(n.path) {
+		path = path[len(n.path):]
+		if !n.wildChild {
+			i := strings.IndexByte(n.indices, path[0])
+			if i < 0 {
+				return
+			}
+			checks += i + 1
+			n = n.children[i]
+			continue
+		}
+
+		n = n.children[0]
+		end := strings.IndexByte(path, '/')
+		if n.nType == catchAll || end < 0 || len(n.children) == 0 {
+			return
+		}
+		path = path[end:]
+		n = n.children[0]
+	}
+	return
+}
+
+// BenchmarkRebalance compares lookups of a skewed workload in a tree ordered
+// by the number of routes with the rebalanced tree, reporting the average
+// number of siblings checked per lookup.
+func BenchmarkRebalance(b *testing.B) {
+	paths := skewedPaths()
+	bench := func(b *testing.B, router *Router) {
+		var checks int
+		for _, path := range paths {
+			checks += siblingChecks(router.tree("GET").root, path)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			router.Lookup("GET", paths[i%len(paths)])
+		}
+		b.ReportMetric(float64(checks)/float64(len(paths)), "checks/op")
+	}
+
+	b.Run("Priority", func(b *testing.B) {
+		bench(b, newSkewedRouter())
+	})
+	b.Run("Sampling", func(b *testing.B) {
+		router := newSkewedRouter()
+		router.EnableHitSampling(64)
+		bench(b, router)
+	})
+	b.Run("Rebalanced", func(b *testing.B) {
+		router := newSkewedRouter()
+		router.EnableHitSampling(1)
+		for _, path := range paths {
+			router.Lookup("GET", path)
+		}
+		router.Rebalance()
+		router.EnableHitSampling(0)
+		bench(b, router)
+	})
+}
+
+// BenchmarkServeMiddleware measures composing the middleware added by Use at
+// dispatch time, for matched and unmatched requests.
+func BenchmarkServeMiddleware(b *testing.B) {
+	noop := func(next Handle) Handle {
+		return func(w http.ResponseWriter, req *http.Request, ps Params) { next(w, req, ps) }
+	}
+	for _, n := range []int{0, 1, 3} {
+		router := New()
+		router.MiddlewareOnMiss = true
+		router.GET("/user/:name", func(http.ResponseWriter, *http.Request, Params) {})
+		router.NotFound = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+		for i := 0; i < n; i++ {
+			router.Use(noop)
+		}
+		for _, path := range []string{"/user/gopher", "/missing"} {
+			req := httptest.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+			b.Run(fmt.Sprintf("%d%s", n, path), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					router.ServeHTTP(w, req)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkMatchedPattern compares serving requests without reading the
+// pattern with reading it in a handler wrapping the router, and compares
+// PatternFor with Lookup.
+func BenchmarkMatchedPattern(b *testing.B) {
+	router := New()
+	router.GET("/user/:name", func(http.ResponseWriter, *http.Request, Params) {})
+	req := httptest.NewRequest("GET", "/user/gopher", nil)
+	w := httptest.NewRecorder()
+
+	b.Run("Serve", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			router.ServeHTTP(w, req)
+		}
+	})
+	b.Run("ServeCapture", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ctx, capture := CapturePattern(req.Context())
+			router.ServeHTTP(w, req.WithContext(ctx))
+			_ = capture.Pattern()
+		}
+	})
+	b.Run("Lookup", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			router.Lookup("GET", "/user/gopher")
+		}
+	})
+	b.Run("PatternFor", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			router.PatternFor("GET", "/user/gopher")
+		}
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// BindTyped sets the fields of the struct dst points to from the Params named
+// by their `param` struct tag, e.g.
+//
+//	var req struct {
+//		ID   int64  `param:"id"`
+//		Name string `param:"name"`
+//	}
+//	err := ps.BindTyped(req.Context(), &req)
+//
+// Fields of string, bool, integer and floating-point kinds are parsed with
+// strconv, fields implementing encoding.TextUnmarshaler with UnmarshalText.
+// Fields without tag, tagged "-" or whose Param is missing are left
+// unchanged. BindTyped returns ctx.Err() if ctx is done before all fields are
+// set, so expensive work for a request whose client disconnected is skipped,
+// and an error naming the parameter if a value can't be parsed.
+func (ps Params) BindTyped(ctx context.Context, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind target must be a non-nil pointer to a struct, has: %T", dst)
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		field := t.Field(i)
+		name := field.Tag.Get("param")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		value, ok := ps.Get(name)
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(i), value); err != nil {
+			return fmt.Errorf("parameter '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setField parses value into the field f, see BindTyped.
+func setField(f reflect.Value, value string) error {
+	if f.Addr().Type().Implements(textUnmarshalerType) {
+		return f.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(value, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(x)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParamsBindTyped(t *testing.T) {
+	type target struct {
+		ID       int64     `param:"id"`
+		Name     string    `param:"name"`
+		Page     uint8     `param:"page"`
+		Draft    bool      `param:"draft"`
+		Score    float64   `param:"score"`
+		Since    time.Time `param:"since"`
+		Missing  string    `param:"missing"`
+		Ignored  string    `param:"-"`
+		Untagged string
+	}
+	ps := Params{
+		{"id", "42"}, {"name", "gopher"}, {"page", "3"}, {"draft", "true"},
+		{"score", "1.5"}, {"since", "2024-01-02T03:04:05Z"}, {"-", "x"}, {"Untagged", "x"},
+	}
+	dst := target{Missing: "kept"}
+	if err := ps.BindTyped(context.Background(), &dst); err != nil {
+		t.Fatal(err)
+	}
+	want := target{
+		ID: 42, Name: "gopher", Page: 3, Draft: true, Score: 1.5,
+		Since: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Missing: "kept",
+	}
+	if dst != want {
+		t.Errorf("wrong result:\ngot  %+v\nwant %+v", dst, want)
+	}
+
+	err := Params{{"page", "300"}}.BindTyped(context.Background(), &dst)
+	if !errors.Is(err, strconv.ErrRange) || !strings.Contains(err.Error(), "parameter 'page'") {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dst = target{}
+	if err := ps.BindTyped(ctx, &dst); !errors.Is(err, context.Canceled) || dst.ID != 0 {
+		t.Errorf("wrong result for a canceled context: %v, %+v", err, dst)
+	}
+
+	for _, invalid := range []interface{}{nil, dst, new(int), (*target)(nil)} {
+		if err := ps.BindTyped(context.Background(), invalid); err == nil {
+			t.Errorf("no error for %#v", invalid)
+		}
+	}
+}
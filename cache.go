@@ -0,0 +1,181 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// EnableLookupCache makes the router cache the results of up to size recently
+// looked up method + path combos which matched a route, evicting entries which
+// were not used recently. This pays off for traffic concentrated on few paths with
+// parameters, for which a cache hit is cheaper than walking the tree.
+// Paths which are not cleaned or contain escapes are never cached. The cache
+// is emptied whenever the routes change.
+// A size of 0 disables the cache. EnableLookupCache must not be called while
+// the router serves requests.
+func (r *Router) EnableLookupCache(size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if size <= 0 {
+		r.cache = nil
+		return
+	}
+	r.cache = &lookupCache{
+		size:    size,
+		entries: make(map[cacheKey]*cacheEntry, size),
+	}
+}
+
+// invalidateCache empties the lookup cache, if enabled. It must be called
+// whenever the result of a lookup might change.
+func (r *Router) invalidateCache() {
+	if r.cache != nil {
+		r.cache.clear()
+	}
+}
+
+// lookupCached implements LookupRoute for a router with a lookup cache.
+func (r *Router) lookupCached(method, path string) (*Route, Params, bool) {
+	c := r.cache
+	e, gen, ok := c.get(method, path)
+	if ok {
+		// counted and sampled like the lookup which added the entry;
+		// subtree defaults are neither
+		if e.rt.lookups != nil {
+			if r.sampler != nil {
+				r.sampler.sample(e.rt)
+			}
+			if r.CountLookups {
+				e.rt.lookups.Add(1)
+			}
+		}
+		// the cached values are shared, hand out a copy
+		var ps Params
+		if len(e.ps) > 0 {
+			ps = make(Params, len(e.ps))
+			copy(ps, e.ps)
+		}
+		return e.rt, ps, e.tsr
+	}
+
+	// uncacheable paths are never added, so they can be looked up as well
+	rt, ps, tsr := r.lookupRoute(method, path)
+	if rt != nil && cacheable(path) {
+		// the path and thereby the values might refer to a reused buffer,
+		// see LookupBytes
+		c.put(&cacheEntry{
+			key: cacheKey{method, strings.Clone(path)},
+			rt:  rt,
+			ps:  ps.Clone(),
+			tsr: tsr,
+		}, gen)
+	}
+	return rt, ps, tsr
+}
+
+// cacheable reports whether the result of looking up path may be cached.
+// Paths which are not clean or contain escapes are unlikely to be requested
+// repeatedly and might be handled differently in the future.
+func cacheable(path string) bool {
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c < 0x20 || c == 0x7f || c == '%' || c == '?' || c == '#':
+			return false
+		case c == '/' && i+1 < len(path) && (path[i+1] == '/' || path[i+1] == '.'):
+			return false
+		}
+	}
+	return true
+}
+
+type cacheKey struct {
+	method, path string
+}
+
+type cacheEntry struct {
+	key cacheKey
+	rt  *Route
+	ps  Params
+	tsr bool
+
+	// set by lookups, cleared by the clock hand passing by
+	used atomic.Bool
+}
+
+// lookupCache is a concurrency-safe cache of lookup results. Entries are
+// evicted by the CLOCK approximation of LRU, so hits only need a read lock.
+type lookupCache struct {
+	mu   sync.RWMutex
+	size int
+
+	// incremented by each clear, so results computed before cannot be added
+	// afterwards
+	gen uint64
+
+	entries map[cacheKey]*cacheEntry
+	ring    []*cacheEntry // entries in insertion order, starting at hand
+	hand    int
+}
+
+// get returns the cached entry for the method + path combo. If there is none,
+// it returns the generation of the cache to pass to put.
+func (c *lookupCache) get(method, path string) (*cacheEntry, uint64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if e, ok := c.entries[cacheKey{method, path}]; ok {
+		if !e.used.Load() {
+			e.used.Store(true)
+		}
+		return e, c.gen, true
+	}
+	return nil, c.gen, false
+}
+
+// put adds e to the cache, unless the cache was cleared since generation gen.
+// If the cache is full, the first entry not used since the clock hand passed
+// it last is evicted.
+func (c *lookupCache) put(e *cacheEntry, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	if _, ok := c.entries[e.key]; ok {
+		return
+	}
+	c.entries[e.key] = e
+	if len(c.ring) < c.size {
+		c.ring = append(c.ring, e)
+		return
+	}
+	for {
+		old := c.ring[c.hand]
+		if old.used.Load() {
+			old.used.Store(false)
+			c.hand = (c.hand + 1) % c.size
+			continue
+		}
+		delete(c.entries, old.key)
+		c.ring[c.hand] = e
+		c.hand = (c.hand + 1) % c.size
+		return
+	}
+}
+
+func (c *lookupCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	c.entries = make(map[cacheKey]*cacheEntry, c.size)
+	c.ring = c.ring[:0]
+	c.hand = 0
+}
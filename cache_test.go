@@ -0,0 +1,108 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRouterLookupCache(t *testing.T) {
+	router := New()
+	router.ConcurrentRegistration = true
+	router.GET("/user/:name", "user")
+	router.GET("/src/*filepath", "src")
+	router.EnableLookupCache(2)
+
+	wantParams := Params{Param{"name", "gopher"}}
+	for i := 0; i < 2; i++ {
+		handle, ps, _ := router.Lookup("GET", "/user/gopher")
+		if handle != "user" || !reflect.DeepEqual(ps, wantParams) {
+			t.Fatalf("wrong result for lookup %d: %v, %v", i, handle, ps)
+		}
+		// modifying the returned values must not corrupt the cache
+		ps[0].Value = "modified"
+	}
+	if n := len(router.cache.entries); n != 1 {
+		t.Errorf("wrong number of cached entries: %d", n)
+	}
+
+	// the cached path must not alias the buffer it was looked up from
+	path := []byte("/src/a.go")
+	router.LookupBytes("GET", path)
+	copy(path, "/src/b.go")
+	if _, ps, _ := router.Lookup("GET", "/src/a.go"); ps.ByName("filepath") != "/a.go" {
+		t.Errorf("wrong cached params: %v", ps)
+	}
+
+	// the least recently used entry is evicted
+	router.Lookup("GET", "/user/gordon")
+	if _, ok := router.cache.entries[cacheKey{"GET", "/user/gopher"}]; ok {
+		t.Error("least recently used entry not evicted")
+	}
+
+	// misses and uncacheable paths are not cached
+	router.Lookup("GET", "/user/gordon")
+	router.Lookup("GET", "/nope")
+	router.Lookup("GET", "/user/%20")
+	router.Lookup("GET", "/src/../etc")
+	for key := range router.cache.entries {
+		if key.path != "/src/a.go" && key.path != "/user/gordon" {
+			t.Errorf("unexpected cache entry: %v", key)
+		}
+	}
+
+	// changing the routes invalidates the cache
+	router.SubtreeDefault("/admin", "admin")
+	if handle, _, _ := router.Lookup("GET", "/admin/users"); handle != "admin" {
+		t.Fatalf("wrong handle for subtree default: %v", handle)
+	}
+	router.GET("/admin/users", "users")
+	if handle, _, _ := router.Lookup("GET", "/admin/users"); handle != "users" {
+		t.Errorf("stale result after Handle: %v", handle)
+	}
+	router.Lookup("GET", "/user/gordon")
+	router.Remove("GET", "/user/:name")
+	if handle, _, _ := router.Lookup("GET", "/user/gordon"); handle != nil {
+		t.Errorf("stale result after Remove: %v", handle)
+	}
+	router.Lookup("GET", "/src/a.go")
+	router.Replace("GET", "/src/*filepath", "replaced")
+	if handle, _, _ := router.Lookup("GET", "/src/a.go"); handle != "replaced" {
+		t.Errorf("stale result after Replace: %v", handle)
+	}
+
+	router.EnableLookupCache(0)
+	if handle, _, _ := router.Lookup("GET", "/src/a.go"); handle != "replaced" || router.cache != nil {
+		t.Errorf("wrong result with disabled cache: %v", handle)
+	}
+}
+
+func TestRouterLookupCacheConcurrent(t *testing.T) {
+	router := New()
+	router.GET("/user/:name", "user")
+	router.EnableLookupCache(8)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				name := fmt.Sprint((i + j) % 16)
+				if _, ps, _ := router.Lookup("GET", "/user/"+name); ps.ByName("name") != name {
+					t.Errorf("wrong params: %v", ps)
+					return
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 100; i++ {
+		router.GET(fmt.Sprintf("/static/%d", i), "static")
+	}
+	wg.Wait()
+}
@@ -0,0 +1,125 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ChiCatchAll is the name of the catch-all ConvertChiPattern converts the
+// wildcard "*" of chi patterns to.
+const ChiCatchAll = "rest"
+
+// ConvertChiPattern converts a route pattern of the chi router, e.g.
+// "/users/{id:[0-9]+}/*", to a path of this router and the options of the
+// route, e.g. "/users/:id/*rest" with a predicate of id in Match, to move
+// routes to this router without rewriting them. A parameter "{name}" becomes
+// ":name". A parameter "{name:regexp}" additionally gets a predicate matching
+// the value against regexp, anchored at both ends like chi does. A trailing
+// wildcard "*" becomes the catch-all "*rest", whose value unlike in chi
+// begins with '/'.
+// Patterns without equivalent return an error naming the offending token,
+// wrapping ErrInvalidWildcard: text following a parameter within its
+// segment, like "{name}.json", a wildcard not at the end or not following
+// '/', and the characters ':' and '*' within literal text.
+func ConvertChiPattern(pattern string) (string, RouteOptions, error) {
+	var opts RouteOptions
+	if pattern == "" || pattern[0] != '/' {
+		return "", opts, fmt.Errorf("chi pattern must begin with '/' in pattern '%s': %w", pattern, ErrInvalidPath)
+	}
+	fail := func(reason, token string) (string, RouteOptions, error) {
+		return "", RouteOptions{}, fmt.Errorf("%s, has: '%s' in chi pattern '%s': %w", reason, token, pattern, ErrInvalidWildcard)
+	}
+
+	var path strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '{':
+			end := chiParamEnd(pattern, i)
+			if end < 0 {
+				return fail("unterminated parameter", pattern[i:])
+			}
+			token := pattern[i : end+1]
+			if end+1 < len(pattern) && pattern[end+1] != '/' {
+				return fail("a parameter must end its segment", chiSegment(pattern, i))
+			}
+			name, expr, hasExpr := strings.Cut(pattern[i+1:end], ":")
+			if name == "" || strings.ContainsAny(name, "/:*{}") {
+				return fail("invalid parameter name", token)
+			}
+			if hasExpr {
+				// anchored like chi does, so alternations like "a|b" must be
+				// grouped to be anchored as a whole
+				if !strings.HasPrefix(expr, "^") {
+					expr = "^" + expr
+				}
+				if !strings.HasSuffix(expr, "$") {
+					expr += "$"
+				}
+				re, err := regexp.Compile(expr)
+				if err != nil {
+					return fail(fmt.Sprintf("invalid regular expression (%v)", err), token)
+				}
+				if opts.Match == nil {
+					opts.Match = make(map[string]func(string) bool)
+				}
+				opts.Match[name] = re.MatchString
+			}
+			path.WriteString(":" + name)
+			i = end
+		case '*':
+			if i != len(pattern)-1 || pattern[i-1] != '/' {
+				return fail("a wildcard must be the last segment", chiSegment(pattern, i))
+			}
+			path.WriteString("*" + ChiCatchAll)
+		case ':', '}':
+			return fail(fmt.Sprintf("'%c' in literal text is not supported", c), chiSegment(pattern, i))
+		default:
+			path.WriteByte(c)
+		}
+	}
+	return path.String(), opts, nil
+}
+
+// chiParamEnd returns the index of the brace closing the parameter of pattern
+// beginning at i, skipping the braces of its regular expression, or -1 if the
+// parameter is not closed.
+func chiParamEnd(pattern string, i int) int {
+	depth := 0
+	for ; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// chiSegment returns the segment of pattern containing the byte at i.
+func chiSegment(pattern string, i int) string {
+	start := strings.LastIndexByte(pattern[:i], '/') + 1
+	end := strings.IndexByte(pattern[i:], '/')
+	if end < 0 {
+		return pattern[start:]
+	}
+	return pattern[start : i+end]
+}
+
+// HandleChi registers a new request handle with the given chi pattern and
+// method, see ConvertChiPattern.
+func (r *Router) HandleChi(method, pattern string, handle interface{}) error {
+	path, opts, err := ConvertChiPattern(pattern)
+	if err != nil {
+		return err
+	}
+	return r.HandleOptions(method, path, handle, opts)
+}
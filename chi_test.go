@@ -0,0 +1,166 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chiCorpus holds patterns of chi services along with requests chi matches,
+// with the expected values, and requests it doesn't.
+var chiCorpus = []struct {
+	pattern string
+	path    string
+	matches map[string]Params
+	misses  []string
+}{
+	{"/", "/", map[string]Params{"/": nil}, []string{"/x"}},
+	{"/ping", "/ping", map[string]Params{"/ping": nil}, []string{"/pong", "/ping/x"}},
+	{"/healthz", "/healthz", map[string]Params{"/healthz": nil}, []string{"/health"}},
+	{"/metrics", "/metrics", map[string]Params{"/metrics": nil}, []string{"/metric"}},
+	{"/webhooks/github", "/webhooks/github", map[string]Params{"/webhooks/github": nil}, []string{"/webhooks/gitlab"}},
+	{"/oauth/callback", "/oauth/callback", map[string]Params{"/oauth/callback": nil}, []string{"/oauth"}},
+	{"/users", "/users", map[string]Params{"/users": nil}, []string{"/users/1"}},
+	{"/users/{userID}", "/users/:userID", map[string]Params{
+		"/users/42":     {{"userID", "42"}},
+		"/users/gopher": {{"userID", "gopher"}},
+	}, []string{"/users/", "/users/42/x"}},
+	{"/users/{userID:[0-9]+}", "/users/:userID", map[string]Params{
+		"/users/42": {{"userID", "42"}},
+	}, []string{"/users/abc", "/users/4a", "/users/"}},
+	{"/users/{userID}/avatar", "/users/:userID/avatar", map[string]Params{
+		"/users/42/avatar": {{"userID", "42"}},
+	}, []string{"/users/42", "/users/42/avatars"}},
+	{"/users/{userID}/posts/{postID}", "/users/:userID/posts/:postID", map[string]Params{
+		"/users/1/posts/2": {{"userID", "1"}, {"postID", "2"}},
+	}, []string{"/users/1/posts", "/users/1/posts/2/3"}},
+	{"/articles/{articleSlug:[a-z-]+}", "/articles/:articleSlug", map[string]Params{
+		"/articles/hello-world": {{"articleSlug", "hello-world"}},
+	}, []string{"/articles/Hello", "/articles/hello_world"}},
+	{`/articles/{year:\d{4}}/{month:\d{2}}`, "/articles/:year/:month", map[string]Params{
+		"/articles/2024/05": {{"year", "2024"}, {"month", "05"}},
+	}, []string{"/articles/24/05", "/articles/2024/5", "/articles/20245/05"}},
+	{"/v{version:[0-9]+}/status", "/v:version/status", map[string]Params{
+		"/v2/status":  {{"version", "2"}},
+		"/v10/status": {{"version", "10"}},
+	}, []string{"/vx/status", "/v/status"}},
+	{"/api/v1/orgs/{orgID}/repos/{repoID}/issues", "/api/v1/orgs/:orgID/repos/:repoID/issues", map[string]Params{
+		"/api/v1/orgs/go/repos/chi/issues": {{"orgID", "go"}, {"repoID", "chi"}},
+	}, []string{"/api/v1/orgs/go/repos/chi", "/api/v2/orgs/go/repos/chi/issues"}},
+	{"/search/{query}", "/search/:query", map[string]Params{
+		"/search/golang": {{"query", "golang"}},
+	}, []string{"/search"}},
+	{"/images/{size:(small|medium|large)}/{file}", "/images/:size/:file", map[string]Params{
+		"/images/small/a.png": {{"size", "small"}, {"file", "a.png"}},
+		"/images/large/b.png": {{"size", "large"}, {"file", "b.png"}},
+	}, []string{"/images/huge/a.png", "/images/smaller/a.png"}},
+	{"/todos/{todoID}/complete", "/todos/:todoID/complete", map[string]Params{
+		"/todos/7/complete": {{"todoID", "7"}},
+	}, []string{"/todos/7/done"}},
+	{"/accounts/{accountID:^[0-9]+$}", "/accounts/:accountID", map[string]Params{
+		"/accounts/123": {{"accountID", "123"}},
+	}, []string{"/accounts/12a"}},
+	{"/docs/page.{format:(html|pdf)}", "/docs/page.:format", map[string]Params{
+		"/docs/page.pdf": {{"format", "pdf"}},
+	}, []string{"/docs/page.txt", "/docs/page"}},
+	{"/{tenant}/dashboard", "/:tenant/dashboard", map[string]Params{
+		"/acme/dashboard": {{"tenant", "acme"}},
+	}, []string{"/acme", "/acme/dashboard/x"}},
+	{"/{lang:[a-z]{2}}/about", "/:lang/about", map[string]Params{
+		"/en/about": {{"lang", "en"}},
+	}, []string{"/eng/about", "/EN/about"}},
+	{"/blog/{slug}/comments/{commentID:[0-9]+}", "/blog/:slug/comments/:commentID", map[string]Params{
+		"/blog/hello/comments/3": {{"slug", "hello"}, {"commentID", "3"}},
+	}, []string{"/blog/hello/comments/x"}},
+	{"/orders/{orderID:[A-Z]{3}[0-9]{4}}", "/orders/:orderID", map[string]Params{
+		"/orders/ABC1234": {{"orderID", "ABC1234"}},
+	}, []string{"/orders/abc1234", "/orders/ABC123"}},
+	{"/stores/{storeID:[0-9]+}/items/{sku}", "/stores/:storeID/items/:sku", map[string]Params{
+		"/stores/9/items/X-1": {{"storeID", "9"}, {"sku", "X-1"}},
+	}, []string{"/stores/nine/items/X-1"}},
+	{"/sessions/{id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}", "/sessions/:id", map[string]Params{
+		"/sessions/123e4567-e89b-12d3-a456-426614174000": {{"id", "123e4567-e89b-12d3-a456-426614174000"}},
+	}, []string{"/sessions/123e4567"}},
+	{"/static/*", "/static/*rest", map[string]Params{
+		"/static/":            {{"rest", "/"}},
+		"/static/css/app.css": {{"rest", "/css/app.css"}},
+	}, []string{"/static", "/stat"}},
+	{"/assets/*", "/assets/*rest", map[string]Params{
+		"/assets/logo.svg": {{"rest", "/logo.svg"}},
+	}, []string{"/asset/logo.svg"}},
+	{"/debug/*", "/debug/*rest", map[string]Params{
+		"/debug/pprof/heap": {{"rest", "/pprof/heap"}},
+	}, []string{"/debugging"}},
+	{"/api/{resource}/*", "/api/:resource/*rest", map[string]Params{
+		"/api/users/1/2": {{"resource", "users"}, {"rest", "/1/2"}},
+	}, []string{"/api/users"}},
+	{"/*", "/*rest", map[string]Params{
+		"/":        {{"rest", "/"}},
+		"/any/one": {{"rest", "/any/one"}},
+	}, nil},
+}
+
+func TestConvertChiPattern(t *testing.T) {
+	for _, test := range chiCorpus {
+		path, _, err := ConvertChiPattern(test.pattern)
+		if err != nil {
+			t.Errorf("%s: %v", test.pattern, err)
+			continue
+		}
+		if path != test.path {
+			t.Errorf("%s: wrong path %s, want %s", test.pattern, path, test.path)
+		}
+
+		router := New()
+		if err := router.HandleChi("GET", test.pattern, test.pattern); err != nil {
+			t.Errorf("%s: %v", test.pattern, err)
+			continue
+		}
+		for reqPath, want := range test.matches {
+			handle, ps, _ := router.Lookup("GET", reqPath)
+			if handle != test.pattern || !reflect.DeepEqual(ps, want) {
+				t.Errorf("%s: wrong result for %s: %v, %v, want %v", test.pattern, reqPath, handle, ps, want)
+			}
+		}
+		for _, reqPath := range test.misses {
+			if handle, ps, _ := router.Lookup("GET", reqPath); handle != nil {
+				t.Errorf("%s: %s matches: %v", test.pattern, reqPath, ps)
+			}
+		}
+	}
+}
+
+func TestConvertChiPatternUnsupported(t *testing.T) {
+	for _, test := range []struct {
+		pattern, token string
+	}{
+		{"/files/{name}.json", "'{name}.json'"},
+		{"/{a}-{b}", "'{a}-{b}'"},
+		{"/a/*/b", "'*'"},
+		{"/files*", "'files*'"},
+		{"/lit/a:b", "'a:b'"},
+		{"/users/{}", "'{}'"},
+		{"/users/{:[0-9]+}", "'{:[0-9]+}'"},
+		{"/users/{id", "'{id'"},
+		{"/users/{id:[0-9+}", "'{id:[0-9+}'"},
+		{"/users}", "'users}'"},
+	} {
+		path, _, err := ConvertChiPattern(test.pattern)
+		if !errors.Is(err, ErrInvalidWildcard) || !strings.Contains(err.Error(), test.token) {
+			t.Errorf("%s: expected ErrInvalidWildcard naming %s, got %q, %v", test.pattern, test.token, path, err)
+		}
+	}
+
+	if _, _, err := ConvertChiPattern("users"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("expected ErrInvalidPath for a relative pattern, got %v", err)
+	}
+	router := New()
+	if err := router.HandleChi("GET", "/files/{name}.json", "files"); err == nil {
+		t.Error("HandleChi registered an unsupported pattern")
+	}
+}
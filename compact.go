@@ -0,0 +1,336 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import "strings"
+
+// Compact rewrites the trees of the router into a compact, read-only form
+// used by all subsequent lookups: all nodes of a tree are stored in one slice,
+// referencing their children by index, and all path fragments share one
+// string. This reduces the memory used by large routing tables and improves
+// cache locality during lookups. For routers using ImplBinarySearch, the
+// children of each node are sorted for binary search.
+// Registering a route after Compact transparently falls back to the mutable
+// tree of the method; Compact can be called again once all routes are
+// registered.
+func (r *Router) Compact() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.immutable.Load() {
+		return
+	}
+
+	r.eachTree(func(_ string, t *methodTree) {
+		t.compact = r.compactTree(t.root)
+	})
+}
+
+// compactTree is an immutable copy of a tree, laid out in contiguous memory:
+// all nodes are stored in one slice, with the children of each node stored
+// consecutively and referenced by index, and the path fragments of all nodes
+// share one string.
+type compactTree struct {
+	nodes []compactNode
+
+	// path fragments of all nodes
+	paths string
+
+	// index char of each node, i.e. the byte its parent selects it by, at
+	// the position of the node in nodes
+	indices string
+
+	// the values held by the nodes
+	data []interface{}
+
+	// whether the indexed children of each node are ordered by their index
+	// char instead of their priority, see sortChildren
+	sorted bool
+}
+
+type compactNode struct {
+	pathStart uint32
+	pathEnd   uint32
+	children  uint32 // position of the first child in nodes
+	nChildren uint16
+	nIndices  uint16 // number of children selected by an index char
+	data      uint32 // 1-based position in data, 0 if the node holds nothing
+	wildChild bool
+	nType     nodeType
+	maxParams uint8
+}
+
+// newCompactTree returns the compact form of the tree below root.
+func newCompactTree(root *node) *compactTree {
+	var count int
+	root.walk(func(*node) { count++ })
+
+	t := &compactTree{
+		nodes: make([]compactNode, 0, count),
+	}
+	var paths strings.Builder
+	indices := make([]byte, 0, count)
+
+	// breadth-first, so the children of each node end up next to each other
+	queue := make([]*node, 1, count)
+	queue[0] = root
+	t.nodes = append(t.nodes, compactNode{})
+	indices = append(indices, 0)
+	for i := 0; i < len(queue); i++ {
+		n := queue[i]
+		cn := &t.nodes[i]
+		cn.pathStart = uint32(paths.Len())
+		paths.WriteString(n.path)
+		cn.pathEnd = uint32(paths.Len())
+		cn.children = uint32(len(queue))
+		cn.nChildren = uint16(len(n.children))
+		cn.nIndices = uint16(len(n.indices))
+		if n.data != nil {
+			t.data = append(t.data, n.data)
+			cn.data = uint32(len(t.data))
+		}
+		cn.wildChild = n.wildChild
+		cn.nType = n.nType
+		cn.maxParams = n.maxParams
+
+		for j, child := range n.children {
+			var c byte
+			if j < len(n.indices) {
+				c = n.indices[j]
+			}
+			queue = append(queue, child)
+			t.nodes = append(t.nodes, compactNode{})
+			indices = append(indices, c)
+		}
+	}
+	t.paths = paths.String()
+	t.indices = string(indices)
+	return t
+}
+
+// sortChildren orders the indexed children of each node by their index char,
+// so they can be found by binary search.
+func (t *compactTree) sortChildren() {
+	indices := []byte(t.indices)
+	for i := range t.nodes {
+		start := int(t.nodes[i].children)
+		children := t.nodes[start : start+int(t.nodes[i].nIndices)]
+		chars := indices[start : start+len(children)]
+
+		// insertion sort, nodes rarely have more than a few children
+		for j := 1; j < len(children); j++ {
+			for k := j; k > 0 && chars[k] < chars[k-1]; k-- {
+				chars[k], chars[k-1] = chars[k-1], chars[k]
+				children[k], children[k-1] = children[k-1], children[k]
+			}
+		}
+	}
+	t.indices = string(indices)
+	t.sorted = true
+}
+
+func (t *compactTree) path(n *compactNode) string {
+	return t.paths[n.pathStart:n.pathEnd]
+}
+
+func (t *compactTree) value(n *compactNode) interface{} {
+	if n.data == 0 {
+		return nil
+	}
+	return t.data[n.data-1]
+}
+
+// getValue is the equivalent of node.getValue for the compact form.
+func (t *compactTree) getValue(path string) (data interface{}, p Params, tsr bool) {
+	var buf [paramsBufSize]Param
+	data, values, tsr := t.find(path, buf[:0], false)
+	if len(values) > 0 {
+		p = make(Params, len(values))
+		copy(p, values)
+	}
+	return data, p, tsr
+}
+
+// getValueNoTSR is the equivalent of node.getValueNoTSR for the compact form.
+func (t *compactTree) getValueNoTSR(path string) (data interface{}, p Params) {
+	var buf [paramsBufSize]Param
+	data, values, _ := t.find(path, buf[:0], true)
+	if data != nil && len(values) > 0 {
+		p = make(Params, len(values))
+		copy(p, values)
+	}
+	return data, p
+}
+
+// emptyCatchAll is the equivalent of node.emptyCatchAll for the compact form.
+func (t *compactTree) emptyCatchAll(n *compactNode) *compactNode {
+	for i, end := n.children, n.children+uint32(n.nIndices); i < end; i++ {
+		if t.indices[i] == '/' {
+			if c := &t.nodes[i]; c.nType == catchAll && matchesEmpty(t.value(&t.nodes[c.children])) {
+				return &t.nodes[c.children]
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// find is the equivalent of node.find for the compact form.
+func (t *compactTree) find(path string, buf Params, noTSR bool) (data interface{}, p Params, tsr bool) {
+	p = buf
+	n := &t.nodes[0]
+walk: // outer loop for walking the tree
+	for {
+		prefix := t.path(n)
+		if len(path) > len(prefix) {
+			if path[:len(prefix)] == prefix {
+				path = path[len(prefix):]
+				// If this node does not have a wildcard (param or catchAll)
+				// child, we can just look up the next child node and continue
+				// to walk down the tree
+				if !n.wildChild {
+					c := path[0]
+					if t.sorted {
+						// binary search over the sorted index chars
+						i, end := n.children, n.children+uint32(n.nIndices)
+						for j := end; i < j; {
+							h := (i + j) / 2
+							if t.indices[h] < c {
+								i = h + 1
+							} else {
+								j = h
+							}
+						}
+						if i < end && t.indices[i] == c {
+							n = &t.nodes[i]
+							continue walk
+						}
+					} else {
+						for i, end := n.children, n.children+uint32(n.nIndices); i < end; i++ {
+							if c == t.indices[i] {
+								n = &t.nodes[i]
+								continue walk
+							}
+						}
+					}
+
+					// Nothing found.
+					// We can recommend to redirect to the same URL without a
+					// trailing slash if a leaf exists for that path.
+					tsr = !noTSR && path == "/" && n.data != 0
+					return
+				}
+
+				// handle wildcard child
+				n = &t.nodes[n.children]
+				switch n.nType {
+				case param:
+					// find param end (either '/' or path end)
+					end := 0
+					for end < len(path) && path[end] != '/' {
+						end++
+					}
+
+					// save param value
+					if cap(p)-len(p) < int(n.maxParams) {
+						// grow once to hold all remaining values
+						p = append(make(Params, 0, len(p)+int(n.maxParams)), p...)
+					}
+					i := len(p)
+					p = p[:i+1] // expand slice within preallocated capacity
+					p[i].Key = t.paths[n.pathStart+1 : n.pathEnd]
+					p[i].Value = path[:end]
+
+					// we need to go deeper!
+					if end < len(path) {
+						if n.nChildren > 0 {
+							path = path[end:]
+							n = &t.nodes[n.children]
+							continue walk
+						}
+
+						// ... but we can't
+						tsr = !noTSR && len(path) == end+1
+						return
+					}
+
+					if data = t.value(n); data != nil {
+						return
+					} else if n.nChildren == 1 && t.path(&t.nodes[n.children]) == "" {
+						// a catch-all follows the param, see node.find
+						path, n = "", &t.nodes[n.children]
+						continue walk
+					} else if !noTSR && n.nChildren == 1 {
+						// No handle found. Check if a handle for this path + a
+						// trailing slash exists for TSR recommendation
+						n = &t.nodes[n.children]
+						tsr = (t.path(n) == "/" && n.data != 0)
+					}
+
+					return
+
+				case catchAll:
+					// save param value
+					if cap(p)-len(p) < int(n.maxParams) {
+						// grow once to hold all remaining values
+						p = append(make(Params, 0, len(p)+int(n.maxParams)), p...)
+					}
+					i := len(p)
+					p = p[:i+1] // expand slice within preallocated capacity
+					p[i].Key = t.paths[n.pathStart+2 : n.pathEnd]
+					p[i].Value = path
+
+					data = t.value(n)
+					return
+
+				default:
+					// a corrupted tree matches nothing
+					return nil, nil, false
+				}
+			}
+		} else if path == prefix {
+			// We should have reached the node containing the handle.
+			// Check if this node has a handle registered.
+			if data = t.value(n); data != nil {
+				return
+			}
+
+			if leaf := t.emptyCatchAll(n); leaf != nil {
+				data = t.value(leaf)
+				p = append(p, Param{Key: t.paths[leaf.pathStart+2 : leaf.pathEnd]})
+				return
+			}
+
+			if noTSR {
+				return
+			}
+
+			if path == "/" && n.wildChild && n.nType != root {
+				tsr = true
+				return
+			}
+
+			// No handle found. Check if a handle for this path + a
+			// trailing slash exists for trailing slash recommendation
+			for i, end := n.children, n.children+uint32(n.nIndices); i < end; i++ {
+				if t.indices[i] == '/' {
+					n = &t.nodes[i]
+					tsr = (n.pathEnd-n.pathStart == 1 && n.data != 0) ||
+						(n.nType == catchAll && t.nodes[n.children].data != 0)
+					return
+				}
+			}
+
+			return
+		}
+
+		// Nothing found. We can recommend to redirect to the same URL with an
+		// extra trailing slash if a leaf exists for that path
+		tsr = !noTSR && (path == "/" ||
+			(len(prefix) == len(path)+1 && prefix[len(path)] == '/' &&
+				path == prefix[:len(prefix)-1] && n.data != 0))
+		return
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// checkCompact verifies that the compact forms of the tree built from routes,
+// with and without sorted children, give the same results as the tree for all
+// routes and probes.
+func checkCompact(t *testing.T, routes, probes []string) {
+	tree := &node{}
+	for _, route := range routes {
+		if err := tree.addRoute(route, route); err != nil {
+			t.Fatalf("error inserting route '%s': %v", route, err)
+		}
+	}
+	ct := newCompactTree(tree)
+	sorted := newCompactTree(tree)
+	sorted.sortChildren()
+
+	for _, path := range append(routes, probes...) {
+		data, ps, tsr := tree.getValue(path)
+		for _, ct := range [...]*compactTree{ct, sorted} {
+			cdata, cps, ctsr := ct.getValue(path)
+			if cdata != data {
+				t.Errorf("data mismatch for path '%s' (sorted %t): compact %v, tree %v", path, ct.sorted, cdata, data)
+			}
+			if !reflect.DeepEqual(cps, ps) {
+				t.Errorf("Params mismatch for path '%s' (sorted %t): compact %v, tree %v", path, ct.sorted, cps, ps)
+			}
+			if ctsr != tsr {
+				t.Errorf("TSR mismatch for path '%s' (sorted %t): compact %t, tree %t", path, ct.sorted, ctsr, tsr)
+			}
+
+			data, ps := tree.getValueNoTSR(path)
+			cdata, cps = ct.getValueNoTSR(path)
+			if cdata != data || !reflect.DeepEqual(cps, ps) {
+				t.Errorf("mismatch without TSR for path '%s' (sorted %t): compact %v, %v, tree %v, %v", path, ct.sorted, cdata, cps, data, ps)
+			}
+		}
+	}
+}
+
+func TestCompactTree(t *testing.T) {
+	checkCompact(t, []string{
+		"/hi",
+		"/contact",
+		"/co",
+		"/c",
+		"/a",
+		"/ab",
+		"/doc/",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/α",
+		"/β",
+	}, []string{
+		"/",
+		"/con",
+		"/cona",
+		"/no",
+		"/doc",
+	})
+
+	checkCompact(t, []string{
+		"/",
+		"/cmd/:tool/:sub",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/search/",
+		"/search/:query",
+		"/user_:name",
+		"/user_:name/about",
+		"/files/:dir/*filepath",
+		"/doc/",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/info/:user/public",
+		"/info/:user/project/:project",
+	}, []string{
+		"/cmd/test/",
+		"/cmd/test",
+		"/cmd/test/3",
+		"/src/",
+		"/src/some/file.png",
+		"/search/someth!ng+in+ünìcodé",
+		"/search/someth!ng+in+ünìcodé/",
+		"/user_gopher",
+		"/user_gopher/about",
+		"/files/js/inc/framework.js",
+		"/info/gordon/public",
+		"/info/gordon/project/go",
+		"/info/gordon",
+	})
+
+	checkCompact(t, []string{
+		"/hi",
+		"/b/",
+		"/search/:query",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/x",
+		"/x/y",
+		"/y/",
+		"/y/z",
+		"/0/:id",
+		"/0/:id/1",
+		"/1/:id/",
+		"/1/:id/2",
+		"/aa",
+		"/a/",
+		"/admin",
+		"/admin/:category",
+		"/admin/:category/:page",
+		"/doc",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/no/a",
+		"/no/b",
+		"/api/hello/:name",
+	}, []string{
+		"/hi/",
+		"/b",
+		"/search/gopher/",
+		"/cmd/vet",
+		"/src",
+		"/x/",
+		"/y",
+		"/0/go/",
+		"/1/go",
+		"/a",
+		"/admin/",
+		"/admin/config/",
+		"/admin/config/permissions/",
+		"/doc/",
+		"/",
+		"/no",
+		"/no/",
+		"/_",
+		"/_/",
+		"/api/world/abc",
+	})
+
+	checkCompact(t, []string{"/:test"}, []string{"/", "/x", "/x/"})
+}
+
+func TestRouterCompact(t *testing.T) {
+	router := New()
+	router.ConcurrentRegistration = true
+	router.GET("/user/:name", benchHandle)
+	router.GET("/files/*filepath", benchHandle)
+	router.POST("/user/:name", benchHandle)
+	router.Compact()
+
+	if router.tree("GET").compact == nil || router.tree("POST").compact == nil {
+		t.Fatal("router is not compacted")
+	}
+	if _, ps, _ := router.Lookup("GET", "/user/gopher"); ps.ByName("name") != "gopher" {
+		t.Errorf("wrong Params for compacted route: %v", ps)
+	}
+
+	// replacing a handle keeps the compact form up to date
+	replaced := false
+	router.Replace("GET", "/files/*filepath", Handle(func(_ http.ResponseWriter, _ *http.Request, _ Params) {
+		replaced = true
+	}))
+	handle, _, _ := router.Lookup("GET", "/files/a.txt")
+	handle.(Handle)(nil, nil, nil)
+	if !replaced {
+		t.Error("replaced handle not found in the compact form")
+	}
+
+	// registering a route falls back to the mutable tree of the method
+	router.GET("/about", benchHandle)
+	if router.tree("GET").compact != nil {
+		t.Error("tree is still compacted after registering a route")
+	}
+	if router.tree("POST").compact == nil {
+		t.Error("tree of another method not compacted anymore")
+	}
+	if handle, _, _ := router.Lookup("GET", "/about"); handle == nil {
+		t.Error("route registered after Compact not found")
+	}
+	if handle, _, _ := router.Lookup("GET", "/user/gopher"); handle == nil {
+		t.Error("route registered before Compact not found")
+	}
+}
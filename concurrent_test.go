@@ -0,0 +1,161 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestRouterConcurrentLookup hammers a router with lookups from many
+// goroutines, so any mutation during lookups is reported when run with -race.
+func TestRouterConcurrentLookup(t *testing.T) {
+	routers := []struct {
+		name   string
+		router func() lookupRouter
+	}{
+		{"tree", func() lookupRouter { return loadRoutes(githubAPI) }},
+		{"compact", func() lookupRouter {
+			router := loadRoutes(githubAPI)
+			router.Compact()
+			return router
+		}},
+		{"cached", func() lookupRouter {
+			router := loadRoutes(githubAPI)
+			router.EnableLookupCache(len(githubAPI) / 2)
+			return router
+		}},
+		{"frozen", func() lookupRouter { return loadRoutes(githubAPI).Freeze() }},
+	}
+
+	goroutines, rounds := 64, 20
+	if testing.Short() {
+		goroutines, rounds = 8, 2
+	}
+
+	for _, test := range routers {
+		t.Run(test.name, func(t *testing.T) {
+			router := test.router()
+			paths := make([]string, len(githubAPI))
+			want := make([]Params, len(githubAPI))
+			wantNext := make([]interface{}, len(githubAPI))
+			reference := loadRoutes(githubAPI)
+			for i, route := range githubAPI {
+				paths[i] = requestPath(route.path)
+				_, want[i], _ = reference.Lookup(route.method, paths[i])
+				wantNext[i], _, _ = reference.Lookup(route.method, paths[i]+"/x")
+			}
+
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for round := 0; round < rounds; round++ {
+						for i, route := range githubAPI {
+							// start at a different route in each goroutine
+							i = (i + g) % len(githubAPI)
+							route = githubAPI[i]
+
+							var handle interface{}
+							var ps Params
+							switch (g + round) % 3 {
+							case 0:
+								handle, ps, _ = router.Lookup(route.method, paths[i])
+							case 1:
+								handle, ps, _ = router.LookupBytes(route.method, []byte(paths[i]))
+							case 2:
+								handle, _ = router.LookupFunc(route.method, paths[i], func(key, value string) {
+									ps = append(ps, Param{key, value})
+								})
+							}
+							if handle != route.path || !reflect.DeepEqual(ps, want[i]) {
+								t.Errorf("wrong result for %s %s: got %v, %v", route.method, paths[i], handle, ps)
+								return
+							}
+							// mostly misses
+							if handle, _, _ := router.Lookup(route.method, paths[i]+"/x"); handle != wantNext[i] {
+								t.Errorf("wrong handle for %s/x: got %v, want %v", paths[i], handle, wantNext[i])
+								return
+							}
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestRouterSealed(t *testing.T) {
+	router := New()
+	router.GET("/user/:name", "user")
+	router.GET("/src/*filepath", "src")
+	router.Lookup("GET", "/user/gopher")
+
+	err := router.GET("/about", "about")
+	if !errors.Is(err, ErrSealed) || !strings.Contains(err.Error(), "GET /about") {
+		t.Fatalf("expected ErrSealed naming the route, got %v", err)
+	}
+	if handle, _, _ := router.Lookup("GET", "/about"); handle != nil {
+		t.Errorf("route registered despite the error: %v", handle)
+	}
+
+	// replacing and removing handles remains possible
+	if err := router.Replace("GET", "/user/:name", "user2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.Remove("GET", "/src/*filepath"); err != nil {
+		t.Fatal(err)
+	}
+
+	router.ConcurrentRegistration = true
+	if err := router.GET("/about", "about"); err != nil {
+		t.Fatal(err)
+	}
+	if handle, _, _ := router.Lookup("GET", "/about"); handle != "about" {
+		t.Errorf("wrong handle after registration: %v", handle)
+	}
+}
+
+// TestRouterConcurrentRegistration registers routes while other goroutines
+// look up the ones registered before.
+func TestRouterConcurrentRegistration(t *testing.T) {
+	router := New()
+	router.ConcurrentRegistration = true
+	router.GET("/", "root")
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if handle, _, _ := router.Lookup("GET", "/"); handle != "root" {
+					t.Errorf("wrong handle for /: %v", handle)
+					return
+				}
+			}
+		}()
+	}
+	for _, route := range githubAPI {
+		if route.method == "GET" && route.path != "/" {
+			if err := router.GET(route.path, route.path); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	close(done)
+	wg.Wait()
+}
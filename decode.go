@@ -0,0 +1,79 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// servedPath returns the path req is matched against, see DecodeParams, and
+// whether the parameter values captured from it need decoding.
+func (r *Router) servedPath(req *http.Request) (string, bool) {
+	if r.DecodeParams && req.URL.RawPath != "" {
+		return req.URL.RawPath, true
+	}
+	return req.URL.Path, false
+}
+
+// decodeParams returns ps with the percent-encoding of the values decoded.
+// ps is only copied if a value contains an escape.
+func decodeParams(ps Params) (Params, error) {
+	decoded, copied := ps, false
+	for i, p := range ps {
+		if strings.IndexByte(p.Value, '%') < 0 {
+			continue
+		}
+		value, err := url.PathUnescape(p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s': %w", p.Key, err)
+		}
+		if !copied {
+			decoded, copied = ps.Clone(), true
+		}
+		decoded[i].Value = value
+	}
+	return decoded, nil
+}
+
+// malformedParam responds to a request with parameter values which can't be
+// decoded, see MalformedParamHandler.
+func (r *Router) malformedParam(w http.ResponseWriter, req *http.Request, err error) {
+	if r.MalformedParamHandler != nil {
+		r.MalformedParamHandler.ServeHTTP(w, req)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// traversal reports whether the catch-all value of rt in ps has a ".."
+// segment, see StrictCatchAll.
+func traversal(rt *Route, ps Params) bool {
+	i := strings.LastIndex(rt.Path, "/*")
+	if i < 0 {
+		return false
+	}
+	value, _ := ps.Get(rt.Path[i+2:])
+	return dotDotSegment(value)
+}
+
+// dotDotSegment reports whether value has a ".." segment, separated by '/' or
+// '\\'. Segments with escapes are checked again decoded, so values decoded
+// once more by the handle, e.g. "%2e%2e", are caught as well.
+func dotDotSegment(value string) bool {
+	for _, segment := range strings.FieldsFunc(value, func(c rune) bool { return c == '/' || c == '\\' }) {
+		if segment == ".." {
+			return true
+		}
+		if strings.IndexByte(segment, '%') >= 0 {
+			if decoded, err := url.PathUnescape(segment); err == nil && dotDotSegment(decoded) {
+				return true
+			}
+		}
+	}
+	return false
+}
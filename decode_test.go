@@ -0,0 +1,131 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// rawRequest returns a GET request whose path is sent as rawPath, which
+// http.NewRequest would reject if malformed.
+func rawRequest(rawPath, path string) *http.Request {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.URL.Path = path
+	req.URL.RawPath = rawPath
+	return req
+}
+
+func TestRouterDecodeParams(t *testing.T) {
+	var got Params
+	router := New()
+	router.DecodeParams = true
+	router.GET("/user/:name", func(_ http.ResponseWriter, _ *http.Request, ps Params) { got = ps })
+	router.GET("/files/:name", func(_ http.ResponseWriter, _ *http.Request, ps Params) { got = ps })
+	router.GET("/src/*filepath", func(_ http.ResponseWriter, _ *http.Request, ps Params) { got = ps })
+
+	tests := []struct {
+		rawPath, path string
+		want          Params
+	}{
+		// decoded by net/http already
+		{"", "/user/go pher", Params{{"name", "go pher"}}},
+		{"/files/a%2Fb", "/files/a/b", Params{{"name", "a/b"}}},
+		{"/src/a%2Fb/c%20d", "/src/a/b/c d", Params{{"filepath", "/a/b/c d"}}},
+	}
+	for _, test := range tests {
+		got = nil
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, rawRequest(test.rawPath, test.path))
+		if w.Code != http.StatusOK || len(got) != 1 || got[0] != test.want[0] {
+			t.Errorf("wrong result for '%s': %d, %v, want %v", test.rawPath, w.Code, got, test.want)
+		}
+	}
+
+	// malformed encoding
+	got = nil
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, rawRequest("/user/%ZZ", "/user/%ZZ"))
+	if w.Code != http.StatusBadRequest || got != nil {
+		t.Errorf("wrong response for malformed encoding: %d, %v", w.Code, got)
+	}
+
+	router.MalformedParamHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, rawRequest("/user/%ZZ", "/user/%ZZ"))
+	if w.Code != http.StatusUnprocessableEntity || got != nil {
+		t.Errorf("MalformedParamHandler not used: %d, %v", w.Code, got)
+	}
+
+	// without decoding, the encoded slash separates segments
+	router.DecodeParams = false
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, rawRequest("/files/a%2Fb", "/files/a/b"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("wrong status without DecodeParams: %d", w.Code)
+	}
+}
+
+func TestRouterDecodeParamsRedirect(t *testing.T) {
+	router := New()
+	router.DecodeParams = true
+	router.GET("/files/:name", "file")
+	router.GET("/dirs/:name/", "dir")
+
+	for _, test := range []struct {
+		rawPath, path, query, location string
+	}{
+		{"/files/a%2Fb/", "/files/a/b/", "", "/files/a%2Fb"},
+		{"/files/a%2Fb/", "/files/a/b/", "x=1", "/files/a%2Fb?x=1"},
+		{"/dirs/a%2Fb", "/dirs/a/b", "", "/dirs/a%2Fb/"},
+		{"", "/files/go pher/", "", "/files/go%20pher"},
+	} {
+		req := rawRequest(test.rawPath, test.path)
+		req.URL.RawQuery = test.query
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != test.location {
+			t.Errorf("wrong redirect for '%s': %d to %q, want %q", test.rawPath, w.Code, w.Header().Get("Location"), test.location)
+		}
+	}
+}
+
+func TestRouterStrictCatchAll(t *testing.T) {
+	for _, decode := range []bool{false, true} {
+		served := false
+		router := New()
+		router.StrictCatchAll = true
+		router.DecodeParams = decode
+		router.GET("/files/*path", func(http.ResponseWriter, *http.Request, Params) { served = true })
+
+		tests := []struct {
+			rawPath, path string
+			code          int
+		}{
+			{"/files/..%2f..%2fetc/passwd", "/files/../../etc/passwd", http.StatusBadRequest},
+			{"", "/files/a/../b", http.StatusBadRequest},
+			{"", "/files/..", http.StatusBadRequest},
+			{"", `/files/a\..\b`, http.StatusBadRequest},
+			{"/files/%252e%252e/etc", "/files/%2e%2e/etc", http.StatusBadRequest},
+
+			// dots in file names
+			{"", "/files/v1..2.tar.gz", http.StatusOK},
+			{"", "/files/.config/a.b", http.StatusOK},
+			{"", "/files/.../x", http.StatusOK},
+			{"/files/100%25.txt", "/files/100%.txt", http.StatusOK},
+		}
+		for _, test := range tests {
+			served = false
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, rawRequest(test.rawPath, test.path))
+			if w.Code != test.code || served != (test.code == http.StatusOK) {
+				t.Errorf("decode=%v: wrong result for %s: code %d, served %v", decode, test.path, w.Code, served)
+			}
+		}
+	}
+}
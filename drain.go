@@ -0,0 +1,33 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import "net/http"
+
+// SetDraining sets whether the router rejects new requests, e.g. once a
+// graceful shutdown of the server began, see http.Server.Shutdown, so load
+// balancers move the traffic to other instances. While draining, ServeHTTP
+// passes every request to DrainingHandler without looking it up, or
+// answers with 503 Service Unavailable and "Connection: close" if it is not
+// set. Requests already being served finish as usual.
+// SetDraining is safe to call while the router serves requests.
+func (r *Router) SetDraining(draining bool) {
+	r.draining.Store(draining)
+}
+
+// Draining reports whether the router rejects new requests, see SetDraining.
+func (r *Router) Draining() bool {
+	return r.draining.Load()
+}
+
+// drain answers a request received while draining.
+func (r *Router) drain(w http.ResponseWriter, req *http.Request) {
+	if r.DrainingHandler != nil {
+		r.DrainingHandler.ServeHTTP(w, req)
+		return
+	}
+	w.Header().Set("Connection", "close")
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
@@ -0,0 +1,51 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterDraining(t *testing.T) {
+	router := New()
+	router.GET("/", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	serve := func(router http.Handler) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w
+	}
+
+	if w := serve(router); w.Code != http.StatusOK {
+		t.Errorf("wrong status code before draining: %d", w.Code)
+	}
+	router.SetDraining(true)
+	if !router.Draining() {
+		t.Error("not draining")
+	}
+	if w := serve(router); w.Code != http.StatusServiceUnavailable || w.Header().Get("Connection") != "close" {
+		t.Errorf("wrong response while draining: %d, %v", w.Code, w.Header())
+	}
+	router.DrainingHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	if w := serve(router); w.Code != http.StatusTeapot {
+		t.Errorf("DrainingHandler not invoked: %d", w.Code)
+	}
+	router.SetDraining(false)
+	if w := serve(router); w.Code != http.StatusOK {
+		t.Errorf("wrong status code after draining: %d", w.Code)
+	}
+
+	frozen := router.Freeze()
+	frozen.SetDraining(true)
+	if w := serve(frozen); w.Code != http.StatusTeapot {
+		t.Errorf("frozen router not draining: %d", w.Code)
+	}
+	if router.Draining() {
+		t.Error("draining the frozen router affected the router")
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Errors returned by Handle and its variants. The returned errors wrap them
+// with the details of the failure, so they can be checked for with errors.Is.
+var (
+	// ErrInvalidPath is returned for a path not beginning with '/' and,
+	// unless PermissivePaths is set, for a path with whitespace, control
+	// characters, empty segments or invalid UTF-8.
+	ErrInvalidPath = errors.New("invalid path")
+
+	// ErrInvalidMethod is returned for an empty method or a method with
+	// characters not allowed in a token by RFC 7230, e.g. a space.
+	ErrInvalidMethod = errors.New("invalid method")
+
+	// ErrNilHandle is returned for a nil handle. Placeholder reserves a path
+	// without a handle.
+	ErrNilHandle = errors.New("nil handle")
+
+	// ErrConflict is returned for a route conflicting with a route
+	// registered before: routes with the same path, wildcards which would
+	// shadow other routes or routes shadowed by them. A *ConflictError
+	// holding the conflicting paths is returned for the conflicts between
+	// routes.
+	ErrConflict = errors.New("conflict with existing route")
+
+	// ErrNotRegistered is returned for replacing or removing a route which
+	// is not registered for exactly the given method and path.
+	ErrNotRegistered = errors.New("no handle is registered")
+
+	// ErrInvalidWildcard is returned for a wildcard without name, for
+	// several wildcards in one path segment and, with StrictParamCase, for
+	// parameter names of a path differing only by case.
+	ErrInvalidWildcard = errors.New("invalid wildcard")
+
+	// ErrCatchAllPosition is returned for a catch-all wildcard which isn't
+	// the last path segment.
+	ErrCatchAllPosition = errors.New("invalid catch-all position")
+
+	// ErrDuplicateParam is returned for a path using a wildcard name twice,
+	// unless AllowDuplicateParams is set. A *DuplicateParamError locating
+	// both is returned.
+	ErrDuplicateParam = errors.New("duplicate parameter name")
+
+	// ErrReservedParam is returned for a path declaring a wildcard with a
+	// name reserved for Params added by the router or a framework, see
+	// ReserveParam. A *PathError locating the wildcard is returned.
+	ErrReservedParam = errors.New("reserved parameter name")
+
+	// ErrMissingParam is returned by Params.Require for a parameter which is
+	// not present.
+	ErrMissingParam = errors.New("missing parameter")
+
+	// ErrSealed is returned for a route registered after the first lookup,
+	// unless ConcurrentRegistration is set.
+	ErrSealed = errors.New("registered after the first lookup, set ConcurrentRegistration to register routes while serving")
+)
+
+// ConflictError describes a route which can't be registered since it
+// conflicts with a route registered before. It matches ErrConflict.
+type ConflictError struct {
+	// the path of the new route
+	Path string
+
+	// the path and method of an existing route the new route conflicts
+	// with. If the conflicting node of the tree holds no *Route, e.g. in
+	// trees not built by a Router, Existing is the path prefix of the node and
+	// Method is empty.
+	Existing string
+	Method   string
+
+	// Others lists further existing routes below the conflicting node of the
+	// tree, as "METHOD path", if the new route would shadow or be shadowed by
+	// a whole subtree, e.g. a catch-all registered above static routes. At
+	// most maxConflictRoutes routes are reported including Existing, More
+	// counts the omitted ones.
+	Others []string
+	More   int
+
+	// the sources of the new and the existing route, see Route.Source, if
+	// known
+	Source         string
+	ExistingSource string
+
+	msg string
+}
+
+func (e *ConflictError) Error() string {
+	if e.Method == "" {
+		return e.msg
+	}
+	var msg string
+	if len(e.Others) == 0 {
+		msg = fmt.Sprintf("%s: '%s' conflicts with existing route '%s' registered for %s", e.msg, e.Path, e.Existing, e.Method)
+	} else {
+		msg = fmt.Sprintf("%s: '%s' conflicts with existing routes '%s %s'", e.msg, e.Path, e.Method, e.Existing)
+	}
+	if e.ExistingSource != "" {
+		msg += " by " + e.ExistingSource
+	}
+	for _, other := range e.Others {
+		msg += ", '" + other + "'"
+	}
+	if e.More > 0 {
+		msg += fmt.Sprintf(", +%d more", e.More)
+	}
+	if e.Source != "" {
+		msg += ", new route registered by " + e.Source
+	}
+	return msg
+}
+
+// Is reports whether target is ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// newConflictError returns a ConflictError for the new route of path, naming
+// the first route found in the subtree of the conflicting node n, or prefix
+// if there is none.
+func newConflictError(path string, n *node, prefix, format string, args ...interface{}) error {
+	e := &ConflictError{
+		Path:     path,
+		Existing: prefix,
+		msg:      fmt.Sprintf(format, args...),
+	}
+	if rt := n.firstRoute(); rt != nil {
+		e.Existing, e.Method, e.ExistingSource = rt.Path, rt.Method, rt.Source
+	}
+	return e
+}
+
+// maxConflictRoutes is the number of existing routes a ConflictError lists at
+// most.
+const maxConflictRoutes = 5
+
+// newSubtreeConflictError is like newConflictError, but lists all routes in
+// the subtree of n, up to maxConflictRoutes.
+func newSubtreeConflictError(path string, n *node, prefix, format string, args ...interface{}) error {
+	e := newConflictError(path, n, prefix, format, args...).(*ConflictError)
+	if e.Method == "" {
+		return e
+	}
+	first := true
+	n.walk(func(n *node) {
+		rt, ok := n.data.(*Route)
+		switch {
+		case !ok:
+		case first:
+			// reported as Existing
+			first = false
+		case len(e.Others) < maxConflictRoutes-1:
+			e.Others = append(e.Others, rt.Method+" "+rt.Path)
+		default:
+			e.More++
+		}
+	})
+	return e
+}
+
+// DuplicateParamError describes a path using a wildcard name twice. It matches
+// ErrDuplicateParam.
+type DuplicateParamError struct {
+	Path string
+	Name string
+
+	// byte offsets of both wildcards in Path
+	First  int
+	Second int
+}
+
+func (e *DuplicateParamError) Error() string {
+	return fmt.Sprintf("%v: '%s' at offset %d repeats the wildcard at offset %d in path '%s'",
+		ErrDuplicateParam, e.Name, e.Second, e.First, e.Path)
+}
+
+// Is reports whether target is ErrDuplicateParam.
+func (e *DuplicateParamError) Is(target error) bool {
+	return target == ErrDuplicateParam
+}
+
+// checkParamNames verifies that the wildcards of path have distinct names.
+// The wildcards must be well-formed, see checkPath.
+func checkParamNames(path string) error {
+	type wildcard struct {
+		name   string
+		offset int
+	}
+	var seen []wildcard
+	for i := 0; i < len(path); i++ {
+		if path[i] != ':' && path[i] != '*' {
+			continue
+		}
+		end := i + 1
+		for end < len(path) && path[end] != '/' {
+			end++
+		}
+		name := path[i+1 : end]
+		for _, w := range seen {
+			if w.name == name {
+				return &DuplicateParamError{Path: path, Name: name, First: w.offset, Second: i}
+			}
+		}
+		seen = append(seen, wildcard{name, i})
+		i = end
+	}
+	return nil
+}
+
+// RouteError describes the failure to register one of several routes, see
+// MultiError.
+type RouteError struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+func (e *RouteError) Error() string {
+	return fmt.Sprintf("route '%s %s': %v", e.Method, e.Path, e.Err)
+}
+
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError is returned by functions registering several routes at once,
+// like NewFrom, holding a *RouteError for each route which failed instead
+// of only the first failure. errors.Is and errors.As check each of them.
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d routes failed: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// add records the failure of the route of method and path.
+func (e *MultiError) add(method, path string, err error) {
+	e.Errors = append(e.Errors, &RouteError{Method: method, Path: path, Err: err})
+}
+
+// err returns e if it holds any failure, else nil.
+func (e *MultiError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// PathError describes a malformed path, locating the offending wildcard or
+// character. It wraps ErrInvalidWildcard, ErrCatchAllPosition, ErrInvalidPath
+// or ErrReservedParam.
+type PathError struct {
+	Path string
+
+	// index of the offending segment, the first segment behind the leading
+	// '/' has index 0
+	Segment int
+
+	// byte offset of the offending wildcard or character in Path
+	Offset int
+
+	// the part of Path following a catch-all which is not at the end
+	Remainder string
+
+	// the reason, ErrInvalidWildcard, ErrCatchAllPosition, ErrInvalidPath or
+	// ErrReservedParam
+	Err error
+
+	msg string
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%s, at segment %d, offset %d in path '%s'", e.msg, e.Segment, e.Offset, e.Path)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// checkPath verifies the wildcards of path: each must have a name, a segment
+// may contain only one, and a catch-all must form the last segment. A
+// catch-all followed by a trailing slash is rejected as well, as the slash
+// would be part of the value.
+func checkPath(path string) error {
+	fail := func(segment, offset int, err error, msg string) *PathError {
+		return &PathError{Path: path, Segment: segment, Offset: offset, Err: err, msg: msg}
+	}
+	segment := 0
+	for start := 1; start <= len(path); segment++ {
+		end := start
+		for end < len(path) && path[end] != '/' {
+			end++
+		}
+
+		wildcard := -1
+		for i := start; i < end; i++ {
+			if path[i] != ':' && path[i] != '*' {
+				continue
+			}
+			if wildcard >= 0 {
+				return fail(segment, i, ErrInvalidWildcard, fmt.Sprintf("only one wildcard per path segment is allowed, has: '%s'", path[wildcard:end]))
+			}
+			wildcard = i
+		}
+		if wildcard >= 0 {
+			if wildcard+1 == end {
+				return fail(segment, wildcard, ErrInvalidWildcard, fmt.Sprintf("wildcards must be named with a non-empty name, has: '%s'", path[wildcard:end]))
+			}
+			if path[wildcard] == '*' {
+				if wildcard != start {
+					return fail(segment, wildcard, ErrCatchAllPosition, "no / before catch-all")
+				}
+				if end != len(path) {
+					msg := fmt.Sprintf("catch-all routes are only allowed at the end of the path, followed by '%s'", path[end:])
+					if path[end:] == "/" {
+						msg = "catch-all routes must not have a trailing slash, which the catch-all value holds"
+					}
+					err := fail(segment, wildcard, ErrCatchAllPosition, msg)
+					err.Remainder = path[end:]
+					return err
+				}
+			}
+		}
+		start = end + 1
+	}
+	return nil
+}
+
+// checkPathChars verifies that path has no whitespace, control characters,
+// empty segments besides a trailing one, or invalid UTF-8, which are mistakes
+// rather than routes requests are meant for.
+func checkPathChars(path string) error {
+	fail := func(offset int, msg string) error {
+		segment := strings.Count(path[:offset], "/") - 1
+		if segment < 0 {
+			segment = 0
+		}
+		return &PathError{Path: path, Segment: segment, Offset: offset, Err: ErrInvalidPath, msg: msg}
+	}
+	for i := 0; i < len(path); {
+		c := path[i]
+		switch {
+		case c == ' ' || c == '\t':
+			return fail(i, "whitespace is not allowed")
+		case c < 0x20 || c == 0x7f:
+			return fail(i, fmt.Sprintf("control character %q is not allowed", c))
+		case c == '/' && i > 0 && path[i-1] == '/':
+			return fail(i, "empty path segments are not allowed")
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(path[i:])
+			if r == utf8.RuneError && size == 1 {
+				return fail(i, "invalid UTF-8")
+			}
+			i += size
+			continue
+		}
+		i++
+	}
+	return nil
+}
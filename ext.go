@@ -0,0 +1,30 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"path"
+)
+
+// ByExtension returns a Handle dispatching to the handle of handles whose key
+// is the extension of the last parameter value, which is the value of the
+// catch-all for routes ending in one, e.g. to serve "/*filepath" with one
+// handle for ".html" and another for ".css". Extensions include the dot, see
+// path.Ext; the key "" matches values without extension. Requests with other
+// extensions are answered with 404 Not Found.
+func ByExtension(handles map[string]Handle) Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps Params) {
+		var value string
+		if len(ps) > 0 {
+			value = ps[len(ps)-1].Value
+		}
+		if h, ok := handles[path.Ext(value)]; ok {
+			h(w, req, ps)
+			return
+		}
+		http.NotFound(w, req)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestByExtension(t *testing.T) {
+	handle := func(kind string) Handle {
+		return func(w http.ResponseWriter, _ *http.Request, ps Params) {
+			fmt.Fprintf(w, "%s %s", kind, ps.ByName("path"))
+		}
+	}
+	router := New()
+	router.GET("/site/*path", ByExtension(map[string]Handle{
+		".html": handle("template"),
+		".css":  handle("asset"),
+		"":      handle("dir"),
+	}))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/site/index.html", http.StatusOK, "template /index.html"},
+		{"/site/css/main.css", http.StatusOK, "asset /css/main.css"},
+		{"/site/docs/", http.StatusOK, "dir /docs/"},
+		{"/site/archive.tar.gz", http.StatusNotFound, ""},
+		{"/site/main.CSS", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.code || (test.body != "" && w.Body.String() != test.body) {
+			t.Errorf("wrong response for %s: %d %q", test.path, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,512 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+// Route tables used by the benchmarks, modeled on the fixtures of the
+// go-http-routing-benchmark suite.
+
+type benchRoute struct {
+	method string
+	path   string
+}
+
+// githubAPI is the route table of the GitHub API v3.
+var githubAPI = []benchRoute{
+	{"GET", "/authorizations"},
+	{"GET", "/authorizations/:id"},
+	{"POST", "/authorizations"},
+	{"DELETE", "/authorizations/:id"},
+	{"GET", "/applications/:client_id/tokens/:access_token"},
+	{"DELETE", "/applications/:client_id/tokens"},
+	{"DELETE", "/applications/:client_id/tokens/:access_token"},
+	{"GET", "/events"},
+	{"GET", "/repos/:owner/:repo/events"},
+	{"GET", "/networks/:owner/:repo/events"},
+	{"GET", "/orgs/:org/events"},
+	{"GET", "/users/:user/received_events"},
+	{"GET", "/users/:user/received_events/public"},
+	{"GET", "/users/:user/events"},
+	{"GET", "/users/:user/events/public"},
+	{"GET", "/users/:user/events/orgs/:org"},
+	{"GET", "/feeds"},
+	{"GET", "/notifications"},
+	{"GET", "/repos/:owner/:repo/notifications"},
+	{"PUT", "/notifications"},
+	{"PUT", "/repos/:owner/:repo/notifications"},
+	{"GET", "/notifications/threads/:id"},
+	{"GET", "/notifications/threads/:id/subscription"},
+	{"PUT", "/notifications/threads/:id/subscription"},
+	{"DELETE", "/notifications/threads/:id/subscription"},
+	{"GET", "/repos/:owner/:repo/stargazers"},
+	{"GET", "/users/:user/starred"},
+	{"GET", "/user/starred"},
+	{"GET", "/user/starred/:owner/:repo"},
+	{"PUT", "/user/starred/:owner/:repo"},
+	{"DELETE", "/user/starred/:owner/:repo"},
+	{"GET", "/repos/:owner/:repo/subscribers"},
+	{"GET", "/users/:user/subscriptions"},
+	{"GET", "/user/subscriptions"},
+	{"GET", "/repos/:owner/:repo/subscription"},
+	{"PUT", "/repos/:owner/:repo/subscription"},
+	{"DELETE", "/repos/:owner/:repo/subscription"},
+	{"GET", "/user/subscriptions/:owner/:repo"},
+	{"PUT", "/user/subscriptions/:owner/:repo"},
+	{"DELETE", "/user/subscriptions/:owner/:repo"},
+	{"GET", "/users/:user/gists"},
+	{"GET", "/gists"},
+	{"GET", "/gists/:id"},
+	{"POST", "/gists"},
+	{"PUT", "/gists/:id/star"},
+	{"DELETE", "/gists/:id/star"},
+	{"GET", "/gists/:id/star"},
+	{"POST", "/gists/:id/forks"},
+	{"DELETE", "/gists/:id"},
+	{"GET", "/repos/:owner/:repo/git/blobs/:sha"},
+	{"POST", "/repos/:owner/:repo/git/blobs"},
+	{"GET", "/repos/:owner/:repo/git/commits/:sha"},
+	{"POST", "/repos/:owner/:repo/git/commits"},
+	{"GET", "/repos/:owner/:repo/git/refs"},
+	{"POST", "/repos/:owner/:repo/git/refs"},
+	{"GET", "/repos/:owner/:repo/git/tags/:sha"},
+	{"POST", "/repos/:owner/:repo/git/tags"},
+	{"GET", "/repos/:owner/:repo/git/trees/:sha"},
+	{"POST", "/repos/:owner/:repo/git/trees"},
+	{"GET", "/issues"},
+	{"GET", "/user/issues"},
+	{"GET", "/orgs/:org/issues"},
+	{"GET", "/repos/:owner/:repo/issues"},
+	{"GET", "/repos/:owner/:repo/issues/:number"},
+	{"POST", "/repos/:owner/:repo/issues"},
+	{"GET", "/repos/:owner/:repo/assignees"},
+	{"GET", "/repos/:owner/:repo/assignees/:assignee"},
+	{"GET", "/repos/:owner/:repo/issues/:number/comments"},
+	{"POST", "/repos/:owner/:repo/issues/:number/comments"},
+	{"GET", "/repos/:owner/:repo/issues/:number/events"},
+	{"GET", "/repos/:owner/:repo/labels"},
+	{"GET", "/repos/:owner/:repo/labels/:name"},
+	{"POST", "/repos/:owner/:repo/labels"},
+	{"DELETE", "/repos/:owner/:repo/labels/:name"},
+	{"GET", "/repos/:owner/:repo/issues/:number/labels"},
+	{"POST", "/repos/:owner/:repo/issues/:number/labels"},
+	{"DELETE", "/repos/:owner/:repo/issues/:number/labels/:name"},
+	{"PUT", "/repos/:owner/:repo/issues/:number/labels"},
+	{"DELETE", "/repos/:owner/:repo/issues/:number/labels"},
+	{"GET", "/repos/:owner/:repo/milestones/:number/labels"},
+	{"GET", "/repos/:owner/:repo/milestones"},
+	{"GET", "/repos/:owner/:repo/milestones/:number"},
+	{"POST", "/repos/:owner/:repo/milestones"},
+	{"DELETE", "/repos/:owner/:repo/milestones/:number"},
+	{"GET", "/emojis"},
+	{"GET", "/gitignore/templates"},
+	{"GET", "/gitignore/templates/:name"},
+	{"POST", "/markdown"},
+	{"POST", "/markdown/raw"},
+	{"GET", "/meta"},
+	{"GET", "/rate_limit"},
+	{"GET", "/users/:user/orgs"},
+	{"GET", "/user/orgs"},
+	{"GET", "/orgs/:org"},
+	{"GET", "/orgs/:org/members"},
+	{"GET", "/orgs/:org/members/:user"},
+	{"DELETE", "/orgs/:org/members/:user"},
+	{"GET", "/orgs/:org/public_members"},
+	{"GET", "/orgs/:org/public_members/:user"},
+	{"PUT", "/orgs/:org/public_members/:user"},
+	{"DELETE", "/orgs/:org/public_members/:user"},
+	{"GET", "/orgs/:org/teams"},
+	{"GET", "/teams/:id"},
+	{"POST", "/orgs/:org/teams"},
+	{"DELETE", "/teams/:id"},
+	{"GET", "/teams/:id/members"},
+	{"GET", "/teams/:id/members/:user"},
+	{"PUT", "/teams/:id/members/:user"},
+	{"DELETE", "/teams/:id/members/:user"},
+	{"GET", "/teams/:id/repos"},
+	{"GET", "/teams/:id/repos/:owner/:repo"},
+	{"PUT", "/teams/:id/repos/:owner/:repo"},
+	{"DELETE", "/teams/:id/repos/:owner/:repo"},
+	{"GET", "/user/teams"},
+	{"GET", "/repos/:owner/:repo/pulls"},
+	{"GET", "/repos/:owner/:repo/pulls/:number"},
+	{"POST", "/repos/:owner/:repo/pulls"},
+	{"GET", "/repos/:owner/:repo/pulls/:number/commits"},
+	{"GET", "/repos/:owner/:repo/pulls/:number/files"},
+	{"GET", "/repos/:owner/:repo/pulls/:number/merge"},
+	{"PUT", "/repos/:owner/:repo/pulls/:number/merge"},
+	{"GET", "/repos/:owner/:repo/pulls/:number/comments"},
+	{"PUT", "/repos/:owner/:repo/pulls/:number/comments"},
+	{"GET", "/user/repos"},
+	{"GET", "/users/:user/repos"},
+	{"GET", "/orgs/:org/repos"},
+	{"GET", "/repositories"},
+	{"POST", "/user/repos"},
+	{"POST", "/orgs/:org/repos"},
+	{"GET", "/repos/:owner/:repo"},
+	{"DELETE", "/repos/:owner/:repo"},
+	{"GET", "/repos/:owner/:repo/contributors"},
+	{"GET", "/repos/:owner/:repo/languages"},
+	{"GET", "/repos/:owner/:repo/teams"},
+	{"GET", "/repos/:owner/:repo/tags"},
+	{"GET", "/repos/:owner/:repo/branches"},
+	{"GET", "/repos/:owner/:repo/branches/:branch"},
+	{"GET", "/repos/:owner/:repo/collaborators"},
+	{"GET", "/repos/:owner/:repo/collaborators/:user"},
+	{"PUT", "/repos/:owner/:repo/collaborators/:user"},
+	{"DELETE", "/repos/:owner/:repo/collaborators/:user"},
+	{"GET", "/repos/:owner/:repo/comments"},
+	{"GET", "/repos/:owner/:repo/commits/:sha/comments"},
+	{"POST", "/repos/:owner/:repo/commits/:sha/comments"},
+	{"GET", "/repos/:owner/:repo/comments/:id"},
+	{"DELETE", "/repos/:owner/:repo/comments/:id"},
+	{"GET", "/repos/:owner/:repo/commits"},
+	{"GET", "/repos/:owner/:repo/commits/:sha"},
+	{"GET", "/repos/:owner/:repo/readme"},
+	{"GET", "/repos/:owner/:repo/keys"},
+	{"GET", "/repos/:owner/:repo/keys/:id"},
+	{"POST", "/repos/:owner/:repo/keys"},
+	{"DELETE", "/repos/:owner/:repo/keys/:id"},
+	{"GET", "/repos/:owner/:repo/downloads"},
+	{"GET", "/repos/:owner/:repo/downloads/:id"},
+	{"DELETE", "/repos/:owner/:repo/downloads/:id"},
+	{"GET", "/repos/:owner/:repo/forks"},
+	{"POST", "/repos/:owner/:repo/forks"},
+	{"GET", "/repos/:owner/:repo/hooks"},
+	{"GET", "/repos/:owner/:repo/hooks/:id"},
+	{"POST", "/repos/:owner/:repo/hooks"},
+	{"POST", "/repos/:owner/:repo/hooks/:id/tests"},
+	{"DELETE", "/repos/:owner/:repo/hooks/:id"},
+	{"POST", "/repos/:owner/:repo/merges"},
+	{"GET", "/repos/:owner/:repo/releases"},
+	{"GET", "/repos/:owner/:repo/releases/:id"},
+	{"POST", "/repos/:owner/:repo/releases"},
+	{"DELETE", "/repos/:owner/:repo/releases/:id"},
+	{"GET", "/repos/:owner/:repo/releases/:id/assets"},
+	{"GET", "/repos/:owner/:repo/stats/contributors"},
+	{"GET", "/repos/:owner/:repo/stats/commit_activity"},
+	{"GET", "/repos/:owner/:repo/stats/code_frequency"},
+	{"GET", "/repos/:owner/:repo/stats/participation"},
+	{"GET", "/repos/:owner/:repo/stats/punch_card"},
+	{"GET", "/repos/:owner/:repo/statuses/:ref"},
+	{"POST", "/repos/:owner/:repo/statuses/:ref"},
+	{"GET", "/search/repositories"},
+	{"GET", "/search/code"},
+	{"GET", "/search/issues"},
+	{"GET", "/search/users"},
+	{"GET", "/legacy/issues/search/:owner/:repository/:state/:keyword"},
+	{"GET", "/legacy/repos/search/:keyword"},
+	{"GET", "/legacy/user/search/:keyword"},
+	{"GET", "/legacy/user/email/:email"},
+	{"GET", "/users/:user"},
+	{"GET", "/user"},
+	{"GET", "/users"},
+	{"GET", "/user/emails"},
+	{"POST", "/user/emails"},
+	{"DELETE", "/user/emails"},
+	{"GET", "/users/:user/followers"},
+	{"GET", "/user/followers"},
+	{"GET", "/users/:user/following"},
+	{"GET", "/user/following"},
+	{"GET", "/user/following/:user"},
+	{"GET", "/users/:user/following/:target_user"},
+	{"PUT", "/user/following/:user"},
+	{"DELETE", "/user/following/:user"},
+	{"GET", "/users/:user/keys"},
+	{"GET", "/user/keys"},
+	{"GET", "/user/keys/:id"},
+	{"POST", "/user/keys"},
+	{"DELETE", "/user/keys/:id"},
+}
+
+// staticRoutes is a static-heavy route table: the files of the Go website
+// plus the documentation pages of the standard library packages.
+var staticRoutes = []benchRoute{
+	{"GET", "/"},
+	{"GET", "/cmd.html"},
+	{"GET", "/code.html"},
+	{"GET", "/contrib.html"},
+	{"GET", "/contribute.html"},
+	{"GET", "/debugging_with_gdb.html"},
+	{"GET", "/docs.html"},
+	{"GET", "/effective_go.html"},
+	{"GET", "/files.log"},
+	{"GET", "/gccgo_contribute.html"},
+	{"GET", "/gccgo_install.html"},
+	{"GET", "/go-logo-black.png"},
+	{"GET", "/go-logo-blue.png"},
+	{"GET", "/go-logo-white.png"},
+	{"GET", "/go1.1.html"},
+	{"GET", "/go1.2.html"},
+	{"GET", "/go1.html"},
+	{"GET", "/go1compat.html"},
+	{"GET", "/go_faq.html"},
+	{"GET", "/go_mem.html"},
+	{"GET", "/go_spec.html"},
+	{"GET", "/help.html"},
+	{"GET", "/ie.css"},
+	{"GET", "/install-source.html"},
+	{"GET", "/install.html"},
+	{"GET", "/logo-153x55.png"},
+	{"GET", "/Makefile"},
+	{"GET", "/root.html"},
+	{"GET", "/share.png"},
+	{"GET", "/sieve.gif"},
+	{"GET", "/tos.html"},
+	{"GET", "/articles/"},
+	{"GET", "/articles/go_command.html"},
+	{"GET", "/articles/index.html"},
+	{"GET", "/articles/wiki/"},
+	{"GET", "/articles/wiki/edit.html"},
+	{"GET", "/articles/wiki/final-noclosure.go"},
+	{"GET", "/articles/wiki/final-noerror.go"},
+	{"GET", "/articles/wiki/final-parsetemplate.go"},
+	{"GET", "/articles/wiki/final-template.go"},
+	{"GET", "/articles/wiki/final.go"},
+	{"GET", "/articles/wiki/get.go"},
+	{"GET", "/articles/wiki/http-sample.go"},
+	{"GET", "/articles/wiki/index.html"},
+	{"GET", "/articles/wiki/Makefile"},
+	{"GET", "/articles/wiki/notemplate.go"},
+	{"GET", "/articles/wiki/part1-noerror.go"},
+	{"GET", "/articles/wiki/part1.go"},
+	{"GET", "/articles/wiki/part2.go"},
+	{"GET", "/articles/wiki/part3-errorhandling.go"},
+	{"GET", "/articles/wiki/part3.go"},
+	{"GET", "/articles/wiki/test.bash"},
+	{"GET", "/articles/wiki/test_edit.good"},
+	{"GET", "/articles/wiki/test_Test.txt.good"},
+	{"GET", "/articles/wiki/test_view.good"},
+	{"GET", "/articles/wiki/view.html"},
+	{"GET", "/codewalk/"},
+	{"GET", "/codewalk/codewalk.css"},
+	{"GET", "/codewalk/codewalk.js"},
+	{"GET", "/codewalk/codewalk.xml"},
+	{"GET", "/codewalk/functions.xml"},
+	{"GET", "/codewalk/markov.go"},
+	{"GET", "/codewalk/markov.xml"},
+	{"GET", "/codewalk/pig.go"},
+	{"GET", "/codewalk/popout.png"},
+	{"GET", "/codewalk/run"},
+	{"GET", "/codewalk/sharemem.xml"},
+	{"GET", "/codewalk/urlpoll.go"},
+	{"GET", "/devel/"},
+	{"GET", "/devel/release.html"},
+	{"GET", "/devel/weekly.html"},
+	{"GET", "/gopher/"},
+	{"GET", "/gopher/appenginegopher.jpg"},
+	{"GET", "/gopher/appenginegophercolor.jpg"},
+	{"GET", "/gopher/appenginelogo.gif"},
+	{"GET", "/gopher/bumper.png"},
+	{"GET", "/gopher/bumper192x108.png"},
+	{"GET", "/gopher/bumper320x180.png"},
+	{"GET", "/gopher/bumper480x270.png"},
+	{"GET", "/gopher/bumper640x360.png"},
+	{"GET", "/gopher/doc.png"},
+	{"GET", "/gopher/frontpage.png"},
+	{"GET", "/gopher/gopherbw.png"},
+	{"GET", "/gopher/gophercolor.png"},
+	{"GET", "/gopher/gophercolor16x16.png"},
+	{"GET", "/gopher/help.png"},
+	{"GET", "/gopher/pkg.png"},
+	{"GET", "/gopher/project.png"},
+	{"GET", "/gopher/ref.png"},
+	{"GET", "/gopher/run.png"},
+	{"GET", "/gopher/talks.png"},
+	{"GET", "/gopher/pencil/"},
+	{"GET", "/gopher/pencil/gopherhat.jpg"},
+	{"GET", "/gopher/pencil/gopherhelmet.jpg"},
+	{"GET", "/gopher/pencil/gophermega.jpg"},
+	{"GET", "/gopher/pencil/gopherrunning.jpg"},
+	{"GET", "/gopher/pencil/gopherswim.jpg"},
+	{"GET", "/gopher/pencil/gopherswrench.jpg"},
+	{"GET", "/play/"},
+	{"GET", "/play/fib.go"},
+	{"GET", "/play/hello.go"},
+	{"GET", "/play/life.go"},
+	{"GET", "/play/peano.go"},
+	{"GET", "/play/pi.go"},
+	{"GET", "/play/sieve.go"},
+	{"GET", "/play/solitaire.go"},
+	{"GET", "/play/tree.go"},
+	{"GET", "/progs/"},
+	{"GET", "/progs/cgo1.go"},
+	{"GET", "/progs/cgo2.go"},
+	{"GET", "/progs/cgo3.go"},
+	{"GET", "/progs/cgo4.go"},
+	{"GET", "/progs/defer.go"},
+	{"GET", "/progs/defer.out"},
+	{"GET", "/progs/defer2.go"},
+	{"GET", "/progs/defer2.out"},
+	{"GET", "/progs/eff_bytesize.go"},
+	{"GET", "/progs/eff_bytesize.out"},
+	{"GET", "/progs/eff_qr.go"},
+	{"GET", "/progs/eff_sequence.go"},
+	{"GET", "/progs/eff_sequence.out"},
+	{"GET", "/progs/error.go"},
+	{"GET", "/progs/error2.go"},
+	{"GET", "/progs/error3.go"},
+	{"GET", "/progs/error4.go"},
+	{"GET", "/progs/go1.go"},
+	{"GET", "/progs/gobs1.go"},
+	{"GET", "/progs/gobs2.go"},
+	{"GET", "/progs/image_draw.go"},
+	{"GET", "/progs/image_package1.go"},
+	{"GET", "/progs/image_package1.out"},
+	{"GET", "/pkg/archive/tar/"},
+	{"GET", "/pkg/archive/zip/"},
+	{"GET", "/pkg/bufio/"},
+	{"GET", "/pkg/bytes/"},
+	{"GET", "/pkg/cmp/"},
+	{"GET", "/pkg/compress/bzip2/"},
+	{"GET", "/pkg/compress/flate/"},
+	{"GET", "/pkg/compress/gzip/"},
+	{"GET", "/pkg/compress/lzw/"},
+	{"GET", "/pkg/compress/zlib/"},
+	{"GET", "/pkg/container/heap/"},
+	{"GET", "/pkg/container/list/"},
+	{"GET", "/pkg/container/ring/"},
+	{"GET", "/pkg/context/"},
+	{"GET", "/pkg/crypto/"},
+	{"GET", "/pkg/crypto/aes/"},
+	{"GET", "/pkg/crypto/cipher/"},
+	{"GET", "/pkg/crypto/des/"},
+	{"GET", "/pkg/crypto/dsa/"},
+	{"GET", "/pkg/crypto/ecdh/"},
+	{"GET", "/pkg/crypto/ecdsa/"},
+	{"GET", "/pkg/crypto/ed25519/"},
+	{"GET", "/pkg/crypto/elliptic/"},
+	{"GET", "/pkg/crypto/fips140/"},
+	{"GET", "/pkg/crypto/hkdf/"},
+	{"GET", "/pkg/crypto/hmac/"},
+	{"GET", "/pkg/crypto/hpke/"},
+	{"GET", "/pkg/crypto/md5/"},
+	{"GET", "/pkg/crypto/mldsa/"},
+	{"GET", "/pkg/crypto/mlkem/"},
+	{"GET", "/pkg/crypto/mlkem/mlkemtest/"},
+	{"GET", "/pkg/crypto/pbkdf2/"},
+	{"GET", "/pkg/crypto/rand/"},
+	{"GET", "/pkg/crypto/rc4/"},
+	{"GET", "/pkg/crypto/rsa/"},
+	{"GET", "/pkg/crypto/sha1/"},
+	{"GET", "/pkg/crypto/sha256/"},
+	{"GET", "/pkg/crypto/sha3/"},
+	{"GET", "/pkg/crypto/sha512/"},
+	{"GET", "/pkg/crypto/subtle/"},
+	{"GET", "/pkg/crypto/tls/"},
+	{"GET", "/pkg/crypto/x509/"},
+	{"GET", "/pkg/crypto/x509/pkix/"},
+	{"GET", "/pkg/database/sql/"},
+	{"GET", "/pkg/database/sql/driver/"},
+	{"GET", "/pkg/debug/buildinfo/"},
+	{"GET", "/pkg/debug/dwarf/"},
+	{"GET", "/pkg/debug/elf/"},
+	{"GET", "/pkg/debug/gosym/"},
+	{"GET", "/pkg/debug/macho/"},
+	{"GET", "/pkg/debug/pe/"},
+	{"GET", "/pkg/debug/plan9obj/"},
+	{"GET", "/pkg/embed/"},
+	{"GET", "/pkg/encoding/"},
+	{"GET", "/pkg/encoding/ascii85/"},
+	{"GET", "/pkg/encoding/asn1/"},
+	{"GET", "/pkg/encoding/base32/"},
+	{"GET", "/pkg/encoding/base64/"},
+	{"GET", "/pkg/encoding/binary/"},
+	{"GET", "/pkg/encoding/csv/"},
+	{"GET", "/pkg/encoding/gob/"},
+	{"GET", "/pkg/encoding/hex/"},
+	{"GET", "/pkg/encoding/json/"},
+	{"GET", "/pkg/encoding/json/jsontext/"},
+	{"GET", "/pkg/encoding/json/v2/"},
+	{"GET", "/pkg/encoding/pem/"},
+	{"GET", "/pkg/encoding/xml/"},
+	{"GET", "/pkg/errors/"},
+	{"GET", "/pkg/expvar/"},
+	{"GET", "/pkg/flag/"},
+	{"GET", "/pkg/fmt/"},
+	{"GET", "/pkg/go/ast/"},
+	{"GET", "/pkg/go/build/"},
+	{"GET", "/pkg/go/build/constraint/"},
+	{"GET", "/pkg/go/constant/"},
+	{"GET", "/pkg/go/doc/"},
+	{"GET", "/pkg/go/doc/comment/"},
+	{"GET", "/pkg/go/format/"},
+	{"GET", "/pkg/go/importer/"},
+	{"GET", "/pkg/go/parser/"},
+	{"GET", "/pkg/go/printer/"},
+	{"GET", "/pkg/go/scanner/"},
+	{"GET", "/pkg/go/token/"},
+	{"GET", "/pkg/go/types/"},
+	{"GET", "/pkg/go/version/"},
+	{"GET", "/pkg/hash/"},
+	{"GET", "/pkg/hash/adler32/"},
+	{"GET", "/pkg/hash/crc32/"},
+	{"GET", "/pkg/hash/crc64/"},
+	{"GET", "/pkg/hash/fnv/"},
+	{"GET", "/pkg/hash/maphash/"},
+	{"GET", "/pkg/html/"},
+	{"GET", "/pkg/html/template/"},
+	{"GET", "/pkg/image/"},
+	{"GET", "/pkg/image/color/"},
+	{"GET", "/pkg/image/color/palette/"},
+	{"GET", "/pkg/image/draw/"},
+	{"GET", "/pkg/image/gif/"},
+	{"GET", "/pkg/image/jpeg/"},
+	{"GET", "/pkg/image/png/"},
+	{"GET", "/pkg/index/suffixarray/"},
+	{"GET", "/pkg/io/"},
+	{"GET", "/pkg/io/fs/"},
+	{"GET", "/pkg/io/ioutil/"},
+	{"GET", "/pkg/iter/"},
+	{"GET", "/pkg/log/"},
+	{"GET", "/pkg/log/slog/"},
+	{"GET", "/pkg/log/syslog/"},
+	{"GET", "/pkg/maps/"},
+	{"GET", "/pkg/math/"},
+	{"GET", "/pkg/math/big/"},
+	{"GET", "/pkg/math/bits/"},
+	{"GET", "/pkg/math/cmplx/"},
+	{"GET", "/pkg/math/rand/"},
+	{"GET", "/pkg/math/rand/v2/"},
+	{"GET", "/pkg/mime/"},
+	{"GET", "/pkg/mime/multipart/"},
+	{"GET", "/pkg/mime/quotedprintable/"},
+	{"GET", "/pkg/net/"},
+	{"GET", "/pkg/net/http/"},
+	{"GET", "/pkg/net/http/cgi/"},
+	{"GET", "/pkg/net/http/cookiejar/"},
+	{"GET", "/pkg/net/http/fcgi/"},
+	{"GET", "/pkg/net/http/httptest/"},
+	{"GET", "/pkg/net/http/httptrace/"},
+	{"GET", "/pkg/net/http/httputil/"},
+	{"GET", "/pkg/net/http/pprof/"},
+	{"GET", "/pkg/net/mail/"},
+	{"GET", "/pkg/net/netip/"},
+	{"GET", "/pkg/net/rpc/"},
+}
+
+// deepRoutes is a deeply parameterized route table.
+var deepRoutes = []benchRoute{
+	{"GET", "/api/:version/orgs/:org"},
+	{"GET", "/api/:version/orgs/:org/projects/:project"},
+	{"PUT", "/api/:version/orgs/:org/projects/:project"},
+	{"DELETE", "/api/:version/orgs/:org/projects/:project"},
+	{"GET", "/api/:version/orgs/:org/projects/:project/members/:member"},
+	{"GET", "/api/:version/orgs/:org/projects/:project/files/*path"},
+	{"GET", "/api/:version/orgs/:org/projects/:project/envs/:env"},
+	{"GET", "/api/:version/orgs/:org/projects/:project/envs/:env/services/:service"},
+	{"POST", "/api/:version/orgs/:org/projects/:project/envs/:env/services/:service"},
+	{"GET", "/api/:version/orgs/:org/projects/:project/envs/:env/services/:service/deployments/:deployment"},
+	{"GET", "/api/:version/orgs/:org/projects/:project/envs/:env/services/:service/deployments/:deployment/steps/:step"},
+	{"GET", "/api/:version/orgs/:org/projects/:project/envs/:env/services/:service/instances/:instance"},
+	{"DELETE", "/api/:version/orgs/:org/projects/:project/envs/:env/services/:service/instances/:instance"},
+	{"GET", "/api/:version/orgs/:org/projects/:project/envs/:env/services/:service/instances/:instance/logs/:stream"},
+	{"GET", "/api/:version/orgs/:org/projects/:project/envs/:env/services/:service/instances/:instance/metrics/:metric"},
+	{"GET", "/api/:version/orgs/:org/teams/:team"},
+	{"GET", "/api/:version/orgs/:org/teams/:team/members/:member"},
+	{"PUT", "/api/:version/orgs/:org/teams/:team/members/:member/roles/:role"},
+	{"GET", "/api/:version/users/:user"},
+	{"GET", "/api/:version/users/:user/keys/:key"},
+	{"GET", "/api/:version/users/:user/orgs/:org/permissions/:permission"},
+	{"GET", "/blobs/:bucket/*object"},
+}
@@ -0,0 +1,114 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format configures the route table returned by Router.FormatRoutes.
+type Format struct {
+	// Markdown renders the table in Markdown instead of aligned plain text.
+	Markdown bool
+
+	// MaxWidth is the number of characters a cell may have at most, e.g. to
+	// keep long catch-all paths from widening the columns. Longer cells are
+	// shortened to MaxWidth characters ending with an ellipsis.
+	// If zero, cells are never shortened.
+	MaxWidth int
+}
+
+// routeColumns are the columns of the table returned by FormatRoutes.
+var routeColumns = []string{"METHOD", "PATH", "HANDLER", "NAME"}
+
+// FormatRoutes returns a table of the registered routes with the columns
+// METHOD, PATH, HANDLER and NAME, e.g. to print the routes at startup. The
+// handler is the name of the function of a func handle, else its type, and
+// the name is that of the route options. The routes are sorted by path and
+// then method, so the table is the same for equal routes.
+func (r *Router) FormatRoutes(style Format) string {
+	routes := r.routes()
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	rows := make([][]string, len(routes))
+	for i, rt := range routes {
+		rows[i] = []string{rt.Method, rt.Path, handleName(rt.Handle), rt.Options.Name}
+		for j, cell := range rows[i] {
+			rows[i][j] = truncateCell(cell, style.MaxWidth)
+		}
+	}
+
+	var b strings.Builder
+	if style.Markdown {
+		writeMarkdownRow(&b, routeColumns)
+		b.WriteString("|" + strings.Repeat(" --- |", len(routeColumns)) + "\n")
+		for _, row := range rows {
+			writeMarkdownRow(&b, row)
+		}
+		return b.String()
+	}
+	table := append([][]string{routeColumns}, rows...)
+	widths := make([]int, len(routeColumns))
+	for _, row := range table {
+		for j, cell := range row {
+			widths[j] = max(widths[j], utf8.RuneCountInString(cell))
+		}
+	}
+	for _, row := range table {
+		var line strings.Builder
+		for j, cell := range row {
+			line.WriteString(cell)
+			line.WriteString(strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell)+2))
+		}
+		b.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	return b.String()
+}
+
+// writeMarkdownRow writes the cells as row of a Markdown table, escaping
+// the pipes within.
+func writeMarkdownRow(b *strings.Builder, cells []string) {
+	b.WriteString("|")
+	for _, cell := range cells {
+		b.WriteString(" " + strings.ReplaceAll(cell, "|", `\|`) + " |")
+	}
+	b.WriteString("\n")
+}
+
+// truncateCell shortens cell to width characters ending with an ellipsis if
+// it is longer.
+func truncateCell(cell string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(cell) <= width {
+		return cell
+	}
+	runes := []rune(cell)
+	return string(runes[:width-1]) + "…"
+}
+
+// handleName returns the name of the function of handle without its package
+// path, e.g. "api.showUser", or the type of handle if it is no func.
+func handleName(handle interface{}) string {
+	v := reflect.ValueOf(handle)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Sprintf("%T", handle)
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return fmt.Sprintf("%T", handle)
+	}
+	// method values are named like "pkg.T.m-fm"
+	name := strings.TrimSuffix(fn.Name(), "-fm")
+	return name[strings.LastIndexByte(name, '/')+1:]
+}
@@ -0,0 +1,75 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"testing"
+)
+
+var updateRouteTables = flag.Bool("update-route-tables", false, "regenerate the route tables in testdata")
+
+func listUsers(w http.ResponseWriter, req *http.Request, _ Params) {}
+
+type usersAPI struct{}
+
+func (usersAPI) show(w http.ResponseWriter, req *http.Request, _ Params) {}
+
+func TestRouterFormatRoutes(t *testing.T) {
+	router := New()
+	router.HandleOptions("GET", "/users", listUsers, RouteOptions{Name: "users.list"})
+	router.HandleOptions("POST", "/users", http.HandlerFunc(http.NotFound), RouteOptions{Name: "users.create"})
+	router.HandleOptions("GET", "/users/:id", usersAPI{}.show, RouteOptions{Name: "users.show"})
+	router.GET("/", "index")
+	router.GET("/pipe|d", "pipe")
+	router.GET("/static/*averyveryverylongcatchallname", http.FileServer(http.Dir(".")))
+	router.Handle("PURGE", "/static/*averyveryverylongcatchallname", "purge")
+
+	for _, test := range []struct {
+		golden string
+		style  Format
+	}{
+		{"testdata/routes.txt", Format{}},
+		{"testdata/routes_truncated.txt", Format{MaxWidth: 20}},
+		{"testdata/routes.md", Format{Markdown: true}},
+	} {
+		got := router.FormatRoutes(test.style)
+		if *updateRouteTables {
+			if err := os.WriteFile(test.golden, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(test.golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			t.Errorf("table differs from %s, run the tests with -update-route-tables if intended:\n%s", test.golden, got)
+		}
+		if again := router.FormatRoutes(test.style); again != got {
+			t.Errorf("%s: output differs between calls", test.golden)
+		}
+	}
+}
+
+func TestTruncateCell(t *testing.T) {
+	for _, test := range []struct {
+		cell  string
+		width int
+		want  string
+	}{
+		{"/static/*filepath", 0, "/static/*filepath"},
+		{"/static/*filepath", 17, "/static/*filepath"},
+		{"/static/*filepath", 10, "/static/*…"},
+		{"/grüße/straße", 8, "/grüße/…"},
+		{"/x", 1, "…"},
+	} {
+		if got := truncateCell(test.cell, test.width); got != test.want {
+			t.Errorf("truncateCell(%q, %d) = %q, want %q", test.cell, test.width, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrFrozen is returned when registering or changing routes of a frozen
+// router.
+var ErrFrozen = errors.New("router is frozen")
+
+// FrozenRouter is a read-only snapshot of a Router, see Router.Freeze.
+type FrozenRouter struct {
+	r *Router
+}
+
+// Freeze marks the router immutable: registering or changing routes after
+// Freeze returns ErrFrozen, and methods without error changing the routes,
+// like Compact, do nothing. Lookups of the router then take no lock.
+// Freeze also returns a read-only snapshot of the routes, optimized further
+// for lookups: the trees are compacted like by Compact, with the children of
+// each node sorted for binary search.
+// Settings like EnableLookupCache may still be changed while no requests are
+// served; they are not reflected in the snapshot.
+func (r *Router) Freeze() *FrozenRouter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.immutable.Store(true)
+	return r.snapshot()
+}
+
+// snapshot returns a FrozenRouter holding the current routes of r.
+func (r *Router) snapshot() *FrozenRouter {
+	frozen := &Router{
+		frozen:                 true,
+		StrictParamCase:        r.StrictParamCase,
+		CaseSensitiveMethods:   r.CaseSensitiveMethods,
+		MethodAliases:          r.MethodAliases,
+		KeyFunc:                r.KeyFunc,
+		impl:                   r.impl,
+		MaxParams:              r.MaxParams,
+		MaxSegments:            r.MaxSegments,
+		MaxPathLength:          r.MaxPathLength,
+		RedirectTrailingSlash:  r.RedirectTrailingSlash,
+		AutoHead:               r.AutoHead,
+		HandleMethodNotAllowed: r.HandleMethodNotAllowed,
+		NotFound:               r.NotFound,
+		NotFoundParams:         r.NotFoundParams,
+		CountLookups:           r.CountLookups,
+		SuggestOnMiss:          r.SuggestOnMiss,
+		MethodNotAllowed:       r.MethodNotAllowed,
+		MiddlewareOnMiss:       r.MiddlewareOnMiss,
+		DecodeParams:           r.DecodeParams,
+		MalformedParamHandler:  r.MalformedParamHandler,
+		StrictCatchAll:         r.StrictCatchAll,
+		SchemeHeader:           r.SchemeHeader,
+		handleType:             r.handleType,
+		SchemeMismatch:         r.SchemeMismatch,
+		DrainingHandler:        r.DrainingHandler,
+		defaults:               append([]*Route(nil), r.defaults...),
+		middleware:             append([]Middleware(nil), r.middleware...),
+		ErrorHandler:           r.ErrorHandler,
+		UnknownHandleType:      r.UnknownHandleType,
+		Observe:                r.Observe,
+		onLookup:               r.onLookup,
+		onServe:                r.onServe,
+		errorMappers:           append([]func(error) error(nil), r.errorMappers...),
+	}
+	r.eachTree(func(method string, t *methodTree) {
+		routes := make(map[string]*Route, len(t.static.routes))
+		for path, rt := range t.static.routes {
+			routes[path] = rt
+		}
+		ct := newCompactTree(t.root)
+		ct.sortChildren()
+
+		// the mutable tree is not needed for lookups
+		*frozen.addTree(method) = methodTree{
+			static:      staticTable{routes: routes, lengths: t.static.lengths},
+			compact:     ct,
+			strictSlash: t.strictSlash,
+			catchAlls:   t.catchAlls,
+		}
+	})
+	// the map is never modified
+	frozen.queries.Store(r.queries.Load())
+	if r.tables != nil {
+		frozen.tables = make(map[string]*Router, len(r.tables))
+		for key, table := range r.tables {
+			frozen.tables[key] = table
+		}
+	}
+	// the set is never modified either
+	frozen.locales.Store(r.locales.Load())
+	return &FrozenRouter{r: frozen}
+}
+
+// Handle always returns ErrFrozen, routes can't be added to a frozen router.
+func (f *FrozenRouter) Handle(method, path string, handle interface{}) error {
+	return ErrFrozen
+}
+
+// Lookup is like Router.Lookup.
+func (f *FrozenRouter) Lookup(method, path string) (interface{}, Params, bool) {
+	return f.r.Lookup(method, path)
+}
+
+// LookupRoute is like Router.LookupRoute.
+func (f *FrozenRouter) LookupRoute(method, path string) (*Route, Params, bool) {
+	return f.r.LookupRoute(method, path)
+}
+
+// LookupNoTSR is like Router.LookupNoTSR.
+func (f *FrozenRouter) LookupNoTSR(method, path string) (interface{}, Params) {
+	return f.r.LookupNoTSR(method, path)
+}
+
+// LookupFunc is like Router.LookupFunc.
+func (f *FrozenRouter) LookupFunc(method, path string, visit func(key, value string)) (interface{}, bool) {
+	return f.r.LookupFunc(method, path, visit)
+}
+
+// LookupBytes is like Router.LookupBytes.
+func (f *FrozenRouter) LookupBytes(method string, path []byte) (interface{}, Params, bool) {
+	return f.r.LookupBytes(method, path)
+}
+
+// ServeHTTP makes the frozen router implement the http.Handler interface,
+// serving requests like Router.ServeHTTP.
+func (f *FrozenRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.r.ServeHTTP(w, req)
+}
+
+// HitCounts returns the number of requests matching each route, see
+// Router.HitCounts.
+func (f *FrozenRouter) HitCounts() map[string]uint64 {
+	return f.r.HitCounts()
+}
+
+// SetDraining sets whether the frozen router rejects new requests, see
+// Router.SetDraining. The router frozen is not affected.
+func (f *FrozenRouter) SetDraining(draining bool) {
+	f.r.SetDraining(draining)
+}
+
+// Counters returns the number of lookups matching each route, see
+// Router.Counters. The counts are shared with the router frozen.
+func (f *FrozenRouter) Counters() map[string]map[string]uint64 {
+	return f.r.Counters()
+}
@@ -0,0 +1,59 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import "testing"
+
+func TestFrozenRouter(t *testing.T) {
+	router := New()
+	router.GET("/user/:name", "user")
+	router.GET("/static", "static")
+	router.LocalePrefix([]string{"en", "zh"})
+	frozen := router.Freeze()
+
+	if err := frozen.Handle("GET", "/about", "about"); err != ErrFrozen {
+		t.Errorf("wrong error registering on frozen router: want %v, got %v", ErrFrozen, err)
+	}
+
+	// the router is frozen as well
+	if err := router.GET("/about", "about"); err != ErrFrozen {
+		t.Errorf("wrong error registering on frozen router: want %v, got %v", ErrFrozen, err)
+	}
+	if err := router.Replace("GET", "/static", "replaced"); err != ErrFrozen {
+		t.Errorf("wrong error replacing on frozen router: want %v, got %v", ErrFrozen, err)
+	}
+	if err := router.Remove("GET", "/user/:name"); err != ErrFrozen {
+		t.Errorf("wrong error removing from frozen router: want %v, got %v", ErrFrozen, err)
+	}
+	if err := router.SubtreeDefault("/", "default"); err != ErrFrozen {
+		t.Errorf("wrong error adding default to frozen router: want %v, got %v", ErrFrozen, err)
+	}
+	router.Compact()
+	if router.tree("GET").compact != nil {
+		t.Error("frozen router compacted")
+	}
+
+	if err := router.LocalePrefix(nil); err != ErrFrozen {
+		t.Errorf("wrong error changing the locales of a frozen router: want %v, got %v", ErrFrozen, err)
+	}
+
+	tests := []struct {
+		path   string
+		handle interface{}
+	}{
+		{"/zh/user/gopher", "user"},
+		{"/static", "static"},
+		{"/en/static", "static"},
+		{"/about", nil},
+	}
+	for _, test := range tests {
+		if handle, _, _ := frozen.Lookup("GET", test.path); handle != test.handle {
+			t.Errorf("wrong handle for %s: want %v, got %v", test.path, test.handle, handle)
+		}
+	}
+	if handle, _, _ := router.Lookup("GET", "/user/gopher"); handle != "user" {
+		t.Errorf("wrong handle of frozen router: %v", handle)
+	}
+}
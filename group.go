@@ -0,0 +1,149 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Group registers routes below a common path prefix, wrapped by the
+// middleware of the group, see RouteOptions.Middleware. Routes registered
+// through nested groups are wrapped by the middleware of the router, added by
+// Router.Use, then by the middleware of the outer groups, of the inner groups
+// and finally of the route itself.
+type Group struct {
+	router     *Router
+	parent     *Group
+	prefix     string
+	middleware []Middleware
+
+	// whether routes were registered through the group or a nested one
+	used bool
+}
+
+// Group returns a group registering routes with the router below prefix,
+// e.g. "/admin". A trailing slash of the prefix is dropped.
+func (r *Router) Group(prefix string) *Group {
+	return &Group{router: r, prefix: strings.TrimSuffix(prefix, "/")}
+}
+
+// Group returns a group nested in g, registering routes below the prefix of g
+// followed by prefix, wrapped by the middleware of g first.
+func (g *Group) Group(prefix string) *Group {
+	return &Group{
+		router: g.router,
+		parent: g,
+		prefix: g.prefix + strings.TrimSuffix(prefix, "/"),
+	}
+}
+
+// Use adds middleware applied around the handles of the routes registered
+// through the group and the groups nested in it. Middleware added by earlier
+// calls runs first; within a call, the first middleware is the outermost.
+// Since the middleware of a route is composed at registration, Use returns an
+// error once routes were registered through the group or a nested group,
+// instead of leaving them unwrapped.
+func (g *Group) Use(mw ...Middleware) error {
+	if g.used {
+		return fmt.Errorf("middleware added to group '%s' after routes were registered through it", g.prefix)
+	}
+	g.middleware = append(g.middleware, mw...)
+	return nil
+}
+
+// chain returns the middleware of g and the groups it is nested in,
+// outermost first.
+func (g *Group) chain() []Middleware {
+	if g.parent == nil {
+		return g.middleware[:len(g.middleware):len(g.middleware)]
+	}
+	return append(g.parent.chain(), g.middleware...)
+}
+
+// GET is a shortcut for group.Handle("GET", path, handle)
+func (g *Group) GET(path string, handle interface{}) error {
+	return g.Handle("GET", path, handle)
+}
+
+// HEAD is a shortcut for group.Handle("HEAD", path, handle)
+func (g *Group) HEAD(path string, handle interface{}) error {
+	return g.Handle("HEAD", path, handle)
+}
+
+// OPTIONS is a shortcut for group.Handle("OPTIONS", path, handle)
+func (g *Group) OPTIONS(path string, handle interface{}) error {
+	return g.Handle("OPTIONS", path, handle)
+}
+
+// POST is a shortcut for group.Handle("POST", path, handle)
+func (g *Group) POST(path string, handle interface{}) error {
+	return g.Handle("POST", path, handle)
+}
+
+// PUT is a shortcut for group.Handle("PUT", path, handle)
+func (g *Group) PUT(path string, handle interface{}) error {
+	return g.Handle("PUT", path, handle)
+}
+
+// PATCH is a shortcut for group.Handle("PATCH", path, handle)
+func (g *Group) PATCH(path string, handle interface{}) error {
+	return g.Handle("PATCH", path, handle)
+}
+
+// DELETE is a shortcut for group.Handle("DELETE", path, handle)
+func (g *Group) DELETE(path string, handle interface{}) error {
+	return g.Handle("DELETE", path, handle)
+}
+
+// Handle registers a new request handle with the given method and path below
+// the prefix of the group, see Router.Handle.
+func (g *Group) Handle(method, path string, handle interface{}) error {
+	return g.HandleOptions(method, path, handle, RouteOptions{})
+}
+
+// HandleOptions registers a new request handle with the given method, path
+// below the prefix of the group and per-route options, see
+// Router.HandleOptions. The middleware of the options is applied inside the
+// middleware of the group.
+func (g *Group) HandleOptions(method, path string, handle interface{}, opts RouteOptions) error {
+	if path == "" || path[0] != '/' {
+		return fmt.Errorf("path must begin with '/' in path '%s': %w", path, ErrInvalidPath)
+	}
+	if chain := g.chain(); len(chain) > 0 {
+		opts.Middleware = append(chain, opts.Middleware...)
+	}
+	if err := g.router.HandleOptions(method, g.prefix+path, handle, opts); err != nil {
+		return err
+	}
+	for ; g != nil; g = g.parent {
+		g.used = true
+	}
+	return nil
+}
+
+type versionKey struct{}
+
+// Version returns a group registering routes below the prefix "/" + v, e.g.
+// "/v1", whose handles can read v from the request context, see
+// VersionFromContext.
+func (r *Router) Version(v string) *Group {
+	g := r.Group("/" + v)
+	g.Use(func(next Handle) Handle {
+		return func(w http.ResponseWriter, req *http.Request, ps Params) {
+			next(w, req.WithContext(context.WithValue(req.Context(), versionKey{}, v)), ps)
+		}
+	})
+	return g
+}
+
+// VersionFromContext returns the API version of the route serving the request
+// of ctx, if registered through Router.Version, or "" otherwise.
+func VersionFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(versionKey{}).(string)
+	return v
+}
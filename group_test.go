@@ -0,0 +1,120 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRouterGroup(t *testing.T) {
+	router := New()
+	var calls []string
+	mark := func(name string) Middleware {
+		return func(next Handle) Handle {
+			return func(w http.ResponseWriter, req *http.Request, ps Params) {
+				calls = append(calls, name)
+				next(w, req, ps)
+			}
+		}
+	}
+	admin := router.Group("/admin/")
+	admin.Use(mark("admin"))
+	admin.GET("/users/:id", func(w http.ResponseWriter, _ *http.Request, ps Params) {
+		io.WriteString(w, "user "+ps.ByName("id"))
+	})
+	if err := admin.GET("users", "h"); err == nil {
+		t.Error("relative path accepted")
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/users/1", nil))
+	if w.Body.String() != "user 1" || len(calls) != 1 || calls[0] != "admin" {
+		t.Errorf("wrong result: %q, %v", w.Body.String(), calls)
+	}
+
+	// lookups return the handle, not the middleware chain
+	if handle, _, _ := router.Lookup("GET", "/admin/users/1"); handle == nil {
+		t.Error("route not found")
+	} else if _, ok := handle.(func(http.ResponseWriter, *http.Request, Params)); !ok {
+		t.Errorf("wrong handle type: %T", handle)
+	}
+}
+
+func TestRouterVersion(t *testing.T) {
+	router := New()
+	for _, v := range []string{"v1", "v2"} {
+		v := v
+		router.Version(v).GET("/users", func(w http.ResponseWriter, req *http.Request, _ Params) {
+			io.WriteString(w, v+" "+VersionFromContext(req.Context()))
+		})
+	}
+	router.GET("/users", func(w http.ResponseWriter, req *http.Request, _ Params) {
+		io.WriteString(w, "unversioned "+VersionFromContext(req.Context()))
+	})
+
+	for path, want := range map[string]string{
+		"/v1/users": "v1 v1",
+		"/v2/users": "v2 v2",
+		"/users":    "unversioned ",
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Body.String() != want {
+			t.Errorf("wrong response for %s: %q, want %q", path, w.Body.String(), want)
+		}
+	}
+}
+
+func TestGroupNestedMiddleware(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next Handle) Handle {
+			return func(w http.ResponseWriter, req *http.Request, ps Params) {
+				calls = append(calls, name)
+				next(w, req, ps)
+			}
+		}
+	}
+	router := New()
+	router.Use(mw("router"))
+	api := router.Group("/api")
+	v1 := api.Group("/v1")
+	admin := v1.Group("/admin")
+	// middleware of outer groups added after nesting applies as well
+	api.Use(mw("api"))
+	v1.Use(mw("v1"))
+	admin.Use(mw("admin 1"), mw("admin 2"))
+	admin.HandleOptions("GET", "/users", func(http.ResponseWriter, *http.Request, Params) {
+		calls = append(calls, "handle")
+	}, RouteOptions{Middleware: []Middleware{mw("route")}})
+	v1.GET("/status", func(http.ResponseWriter, *http.Request, Params) {
+		calls = append(calls, "status")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/admin/users", nil))
+	want := []string{"router", "api", "v1", "admin 1", "admin 2", "route", "handle"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("wrong order:\ngot  %v\nwant %v", calls, want)
+	}
+	calls = nil
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/status", nil))
+	if want := []string{"router", "api", "v1", "status"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("wrong order:\ngot  %v\nwant %v", calls, want)
+	}
+
+	// middleware can't be added once routes are registered through a group
+	for _, g := range []*Group{api, v1, admin} {
+		if err := g.Use(mw("late")); err == nil {
+			t.Errorf("no error adding middleware to %s", g.prefix)
+		}
+	}
+	if err := admin.Group("/x").Use(mw("new")); err != nil {
+		t.Errorf("error for a new nested group: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// HeadWriter is a http.ResponseWriter answering a HEAD request with the
+// response of a handle written for GET: it discards the body, but counts its
+// bytes to send an accurate Content-Length. Since the header can only be sent
+// once the whole body is known, it is held back until Finish is called.
+type HeadWriter struct {
+	w        http.ResponseWriter
+	code     int
+	n        int64
+	finished bool
+}
+
+// HeadFromGet returns a HeadWriter writing the response to w. Finish must be
+// called after the handle returned, e.g.
+//
+//	hw := xrouter.HeadFromGet(w)
+//	getHandler.ServeHTTP(hw, req)
+//	hw.Finish()
+func HeadFromGet(w http.ResponseWriter) *HeadWriter {
+	return &HeadWriter{w: w}
+}
+
+// Header returns the header of the response, see http.ResponseWriter.
+func (h *HeadWriter) Header() http.Header {
+	return h.w.Header()
+}
+
+// WriteHeader records the status code of the response, sent by Finish.
+func (h *HeadWriter) WriteHeader(code int) {
+	if h.code == 0 {
+		h.code = code
+	}
+}
+
+// Write counts the bytes of b and discards them.
+func (h *HeadWriter) Write(b []byte) (int, error) {
+	h.discard(len(b))
+	return len(b), nil
+}
+
+// WriteString counts the bytes of s and discards them.
+func (h *HeadWriter) WriteString(s string) (int, error) {
+	h.discard(len(s))
+	return len(s), nil
+}
+
+// discard counts n bytes of the body, implying 200 OK if no status code was
+// written.
+func (h *HeadWriter) discard(n int) {
+	if h.code == 0 {
+		h.code = http.StatusOK
+	}
+	h.n += int64(n)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for http.ResponseController.
+func (h *HeadWriter) Unwrap() http.ResponseWriter {
+	return h.w
+}
+
+// Finish sends the header of the response with the status code written by
+// the handle, 200 OK if none, and a Content-Length of the discarded body unless
+// the handle set one or the status code forbids a body. Calls after the first
+// do nothing.
+func (h *HeadWriter) Finish() {
+	if h.finished {
+		return
+	}
+	h.finished = true
+	code := h.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	header := h.w.Header()
+	if header.Get("Content-Length") == "" && header.Get("Transfer-Encoding") == "" && bodyAllowed(code) {
+		header.Set("Content-Length", strconv.FormatInt(h.n, 10))
+	}
+	h.w.WriteHeader(code)
+}
+
+// bodyAllowed reports whether a response with the status code may have a
+// body, see RFC 9110, section 6.4.1.
+func bodyAllowed(code int) bool {
+	return code >= 200 && code != http.StatusNoContent && code != http.StatusNotModified
+}
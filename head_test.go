@@ -0,0 +1,85 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHeadFromGet(t *testing.T) {
+	get := func(w http.ResponseWriter, _ *http.Request, _ Params) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, strings.Repeat("x", 60))
+		w.Write([]byte(strings.Repeat("y", 40)))
+	}
+	router := New()
+	router.AutoHead = true
+	router.GET("/file", get)
+	router.GET("/empty", func(w http.ResponseWriter, _ *http.Request, _ Params) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	router.HEAD("/own", func(w http.ResponseWriter, _ *http.Request, _ Params) {
+		w.Header().Set("X-Own", "1")
+	})
+	router.GET("/own", get)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/file", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Length") != "100" || w.Body.Len() != 0 ||
+		w.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("wrong response: %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/empty", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Content-Length") != "" {
+		t.Errorf("wrong response for 204: %d %v", w.Code, w.Header())
+	}
+
+	// HEAD routes take precedence
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/own", nil))
+	if w.Header().Get("X-Own") != "1" {
+		t.Errorf("HEAD route not used: %v", w.Header())
+	}
+
+	// GET is served as usual
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/file", nil))
+	if w.Body.Len() != 100 {
+		t.Errorf("wrong GET body length: %d", w.Body.Len())
+	}
+
+	router.AutoHead = false
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/file", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("wrong code without AutoHead: %d", w.Code)
+	}
+
+	// used directly, a Content-Length set by the handler is kept
+	w = httptest.NewRecorder()
+	hw := HeadFromGet(w)
+	hw.Header().Set("Content-Length", "7")
+	hw.Write([]byte("abc"))
+	hw.Finish()
+	hw.Finish()
+	if w.Header().Get("Content-Length") != "7" || w.Body.Len() != 0 {
+		t.Errorf("wrong response: %v %q", w.Header(), w.Body.String())
+	}
+}
+
+func TestHeadWriterWriteAllocs(t *testing.T) {
+	h := HeadFromGet(httptest.NewRecorder())
+	body := make([]byte, 64<<10)
+	if allocs := testing.AllocsPerRun(100, func() { h.Write(body) }); allocs != 0 {
+		t.Errorf("Write allocates %v times", allocs)
+	}
+	h.Finish()
+}
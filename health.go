@@ -0,0 +1,31 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import "net/http"
+
+// healthHandler answers health checks, see Router.Health.
+type healthHandler struct{}
+
+func (healthHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(`{"status":"ok"}` + "\n"))
+}
+
+// Health registers a GET route for path answering health and readiness
+// checks with 200 OK and the JSON body {"status":"ok"}. Requests of the route
+// are not passed to Observe or OnServe, nor counted by HitCounts, so frequent
+// probes don't distort the metrics of the other routes; middleware added by
+// Use still applies.
+func (r *Router) Health(path string) error {
+	return r.GET(path, healthHandler{})
+}
+
+// isHealth reports whether rt answers health checks, see Health.
+func isHealth(rt *Route) bool {
+	_, ok := rt.Handle.(healthHandler)
+	return ok
+}
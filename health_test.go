@@ -0,0 +1,52 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRouterHealth(t *testing.T) {
+	var observed, served []string
+	router := New()
+	router.OnServe(func(e ServeEvent) {
+		served = append(served, e.Pattern)
+	})
+	router.Observe = func(req *http.Request, rt *Route, elapsed time.Duration) {
+		observed = append(observed, rt.Path)
+		if elapsed < 0 {
+			t.Errorf("negative duration for %s: %v", rt.Path, elapsed)
+		}
+	}
+	if err := router.Health("/healthz"); err != nil {
+		t.Fatal(err)
+	}
+	router.GET("/user/:name", http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	forBoth(t, router, func(t *testing.T, r lookupRouter) {
+		observed, served = nil, nil
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+		if w.Code != http.StatusOK || w.Body.String() != `{"status":"ok"}`+"\n" || w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("wrong health response: %d %q %v", w.Code, w.Body, w.Header())
+		}
+
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/gopher", nil))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+		if want := []string{"/user/:name"}; !reflect.DeepEqual(observed, want) {
+			t.Errorf("wrong observed routes: got %v, want %v", observed, want)
+		}
+		if want := []string{"/user/:name"}; !reflect.DeepEqual(served, want) {
+			t.Errorf("wrong served routes: got %v, want %v", served, want)
+		}
+	})
+	if counts := router.HitCounts(); counts["/user/:name"] == 0 || len(counts) != 1 {
+		t.Errorf("wrong hit counts: %v", counts)
+	}
+}
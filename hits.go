@@ -0,0 +1,50 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+// HitCounts returns the number of requests served by ServeHTTP which matched
+// each route, keyed by the path the route was registered with, e.g.
+// "/user/:name". The counts of routes of different methods registered with the
+// same path add up. Requests rejected after the match, e.g. for invalid
+// parameter values, are counted as well, requests matching no route are not.
+// The counts are incremented atomically without locking and are kept when the
+// handle of a route is replaced. Health check routes are left out, see Health.
+func (r *Router) HitCounts() map[string]uint64 {
+	routes := r.routes()
+	counts := make(map[string]uint64, len(routes))
+	for _, rt := range routes {
+		if isHealth(rt) {
+			continue
+		}
+		counts[rt.Path] += rt.hits.Load()
+	}
+	return counts
+}
+
+// Counters returns the number of lookups which matched each route since it
+// was registered or ResetCounters was called, by method and registered path,
+// if CountLookups is set. Routes which were never matched are included with a
+// count of 0, e.g. to find unused routes. Lookups of ServeHTTP are counted
+// like the ones of Lookup. The counts are read atomically one by one, so
+// lookups running concurrently may be counted for some routes only.
+func (r *Router) Counters() map[string]map[string]uint64 {
+	counters := make(map[string]map[string]uint64)
+	for _, rt := range r.routes() {
+		counts := counters[rt.Method]
+		if counts == nil {
+			counts = make(map[string]uint64)
+			counters[rt.Method] = counts
+		}
+		counts[rt.Path] = rt.lookups.Load()
+	}
+	return counters
+}
+
+// ResetCounters sets the lookup counts of all routes to 0, see Counters.
+func (r *Router) ResetCounters() {
+	for _, rt := range r.routes() {
+		rt.lookups.Store(0)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRouterHitCounts(t *testing.T) {
+	router := New()
+	router.GET("/user/:name", http.NotFoundHandler())
+	router.POST("/user/:name", http.NotFoundHandler())
+	router.GET("/static", http.NotFoundHandler())
+	router.GET("/unused", http.NotFoundHandler())
+
+	goroutines, rounds := 16, 100
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/gopher", nil))
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/user/gopher", nil))
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/static", nil))
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+			}
+		}()
+	}
+	wg.Wait()
+
+	n := uint64(goroutines * rounds)
+	want := map[string]uint64{"/user/:name": 2 * n, "/static": n, "/unused": 0}
+	if got := router.HitCounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong counts: got %v, want %v", got, want)
+	}
+
+	// replacing the handle keeps the count
+	if err := router.Replace("GET", "/static", http.NotFoundHandler()); err != nil {
+		t.Fatal(err)
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/static", nil))
+	if got := router.HitCounts()["/static"]; got != n+1 {
+		t.Errorf("wrong count after Replace: %d, want %d", got, n+1)
+	}
+}
+
+func TestRouterCounters(t *testing.T) {
+	router := New()
+	router.CountLookups = true
+	router.GET("/user/:name", "user")
+	router.GET("/static", "static")
+	router.POST("/static", "post")
+	router.GET("/unused", "unused")
+
+	goroutines, rounds := 16, 200
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				router.Lookup("GET", "/user/gopher")
+				router.Lookup("GET", "/static")
+				router.Lookup("GET", "/missing")
+			}
+		}()
+	}
+	// snapshots while counting
+	for i := 0; i < 10; i++ {
+		router.Counters()
+	}
+	wg.Wait()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/static", nil))
+
+	n := uint64(goroutines * rounds)
+	want := map[string]map[string]uint64{
+		"GET":  {"/user/:name": n, "/static": n, "/unused": 0},
+		"POST": {"/static": 1},
+	}
+	if got := router.Counters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong counters: got %v, want %v", got, want)
+	}
+	frozen := router.Freeze()
+	frozen.Lookup("GET", "/unused")
+	if got := frozen.Counters()["GET"]["/unused"]; got != 1 {
+		t.Errorf("wrong count of the frozen router: %d", got)
+	}
+	if got := frozen.HitCounts()["/static"]; got != 1 {
+		t.Errorf("wrong hit count of the frozen router: %d", got)
+	}
+
+	router.ResetCounters()
+	want = map[string]map[string]uint64{
+		"GET":  {"/user/:name": 0, "/static": 0, "/unused": 0},
+		"POST": {"/static": 0},
+	}
+	if got := router.Counters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong counters after reset: got %v, want %v", got, want)
+	}
+
+	// off by default
+	router = New()
+	router.GET("/static", "static")
+	router.Lookup("GET", "/static")
+	if got := router.Counters()["GET"]["/static"]; got != 0 {
+		t.Errorf("counted without CountLookups: %d", got)
+	}
+}
+
+func TestRouterCountersLookupCache(t *testing.T) {
+	router := New()
+	router.CountLookups = true
+	router.EnableLookupCache(16)
+	router.GET("/user/:name", "user")
+	router.GET("/static", "static")
+	if err := router.SubtreeDefault("/docs", "docs"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		router.Lookup("GET", "/user/gopher")
+		router.Lookup("GET", "/static")
+		router.Lookup("GET", "/docs/intro")
+	}
+	want := map[string]map[string]uint64{
+		"GET": {"/user/:name": 10, "/static": 10},
+	}
+	if got := router.Counters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong counters of cached lookups: got %v, want %v", got, want)
+	}
+}
+
+func TestRouterCountersMethodNotAllowed(t *testing.T) {
+	router := New()
+	router.CountLookups = true
+	router.HandleMethodNotAllowed = true
+	router.EnableHitSampling(1)
+	router.GET("/x", "x")
+	router.POST("/y", "y")
+	router.POST("/user/:name", "user")
+
+	for _, path := range []string{"/y", "/user/gopher"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("PUT", path, nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("wrong status code for PUT %s: %d", path, w.Code)
+		}
+	}
+	want := map[string]map[string]uint64{
+		"GET":  {"/x": 0},
+		"POST": {"/y": 0, "/user/:name": 0},
+	}
+	if got := router.Counters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("405 responses counted the routes of other methods: got %v, want %v", got, want)
+	}
+	if hits := router.sampler.count(router.matchRoute("POST", "/y")); hits != 0 {
+		t.Errorf("405 response sampled the route of another method %d times", hits)
+	}
+}
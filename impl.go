@@ -0,0 +1,76 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+// Impl selects how lookups of a router find the child of a node matching the
+// next byte of the path, see NewWithImpl.
+type Impl int
+
+const (
+	// ImplIndexed scans the children of each node of the mutable trees in
+	// the order of the number of routes below them, so children holding many
+	// routes are found first. It is the implementation used by New.
+	ImplIndexed Impl = iota
+
+	// ImplBinarySearch looks up routes in a compact form of the trees, see
+	// Compact, with the children of each node sorted for binary search. The
+	// compact trees are built by the first lookup; registering routes
+	// afterwards, see ConcurrentRegistration, rebuilds the whole tree of the
+	// method.
+	ImplBinarySearch
+)
+
+// String returns the name of the implementation.
+func (impl Impl) String() string {
+	switch impl {
+	case ImplIndexed:
+		return "indexed"
+	case ImplBinarySearch:
+		return "binary search"
+	}
+	return "invalid"
+}
+
+// NewWithImpl returns a new initialized Router like New, whose lookups use the
+// given implementation. The behavior of the router is the same for all
+// implementations, only their performance differs.
+func NewWithImpl(impl Impl) *Router {
+	r := New()
+	r.impl = impl
+	return r
+}
+
+// seal marks the router as looked up, see ConcurrentRegistration, building
+// the trees used by the implementation for lookups.
+func (r *Router) seal() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sealed.Load() {
+		return
+	}
+	r.sealed.Store(true)
+	r.eachTree(func(_ string, t *methodTree) {
+		r.relayout(t)
+	})
+}
+
+// relayout updates the compact form of t after its routes changed. It is
+// rebuilt if set, and always once the router is sealed for ImplBinarySearch.
+func (r *Router) relayout(t *methodTree) {
+	if t.compact != nil || (r.impl == ImplBinarySearch && r.sealed.Load()) {
+		t.compact = r.compactTree(t.root)
+	}
+}
+
+// compactTree returns the compact form of the tree below root for the
+// implementation of the router.
+func (r *Router) compactTree(root *node) *compactTree {
+	ct := newCompactTree(root)
+	if r.impl == ImplBinarySearch {
+		ct.sortChildren()
+	}
+	return ct
+}
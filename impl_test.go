@@ -0,0 +1,55 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewWithImpl(t *testing.T) {
+	reference := loadRoutes(githubAPI)
+	for _, impl := range []Impl{ImplIndexed, ImplBinarySearch} {
+		t.Run(impl.String(), func(t *testing.T) {
+			router := NewWithImpl(impl)
+			router.ConcurrentRegistration = true
+			for _, route := range githubAPI[1:] {
+				if err := router.Handle(route.method, route.path, route.path); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// the first lookup builds the trees, registering afterwards
+			// rebuilds them
+			router.Lookup("GET", "/")
+			if sorted := router.tree("GET").compact != nil && router.tree("GET").compact.sorted; sorted != (impl == ImplBinarySearch) {
+				t.Errorf("sorted compact tree: %t", sorted)
+			}
+			route := githubAPI[0]
+			if err := router.Handle(route.method, route.path, route.path); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, route := range githubAPI {
+				for _, path := range []string{requestPath(route.path), requestPath(route.path) + "/", requestPath(route.path) + "/x"} {
+					handle, ps, tsr := router.Lookup(route.method, path)
+					wantHandle, wantPs, wantTSR := reference.Lookup(route.method, path)
+					if handle != wantHandle || !reflect.DeepEqual(ps, wantPs) || tsr != wantTSR {
+						t.Errorf("wrong result for %s %s: got %v, %v, %t, want %v, %v, %t",
+							route.method, path, handle, ps, tsr, wantHandle, wantPs, wantTSR)
+					}
+				}
+			}
+
+			path := "/repos/:owner/:repo/stargazers"
+			if err := router.Remove("GET", path); err != nil {
+				t.Fatal(err)
+			}
+			if handle, _, _ := router.Lookup("GET", requestPath(path)); handle != nil {
+				t.Errorf("removed route matched: %v", handle)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Default limits set by New, see Router.MaxSegments and Router.MaxPathLength.
+const (
+	DefaultMaxSegments   = 128
+	DefaultMaxPathLength = 8 << 10
+)
+
+// checkLimits verifies that the path of a new route is within the limits of
+// the router.
+func (r *Router) checkLimits(path string) error {
+	if r.MaxPathLength > 0 && len(path) > r.MaxPathLength {
+		return fmt.Errorf("path of %d bytes exceeds MaxPathLength of %d: %w", len(path), r.MaxPathLength, ErrInvalidPath)
+	}
+	if r.MaxSegments > 0 {
+		if n := strings.Count(path, "/"); n > r.MaxSegments {
+			return fmt.Errorf("path of %d segments exceeds MaxSegments of %d: %w", n, r.MaxSegments, ErrInvalidPath)
+		}
+	}
+	return nil
+}
+
+// exceedsLimits reports whether path is too long to be looked up in t. The
+// segments are not limited for trees with catch-all routes, whose values may
+// hold any number of them.
+func (r *Router) exceedsLimits(t *methodTree, path string) bool {
+	if r.MaxPathLength > 0 && len(path) > r.MaxPathLength {
+		return true
+	}
+	// a path has at most one segment per byte
+	return r.MaxSegments > 0 && len(path) > r.MaxSegments && t.catchAlls == 0 &&
+		strings.Count(path, "/") > r.MaxSegments
+}
@@ -0,0 +1,78 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRouterLimits(t *testing.T) {
+	router := New()
+	router.MaxSegments = 4
+	router.MaxPathLength = 64
+
+	// registration
+	for _, path := range []string{"/a/b/c/d/e", "/" + strings.Repeat("a", 64)} {
+		if err := router.GET(path, "h"); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("wrong error for %s: %v", path, err)
+		}
+	}
+	for _, path := range []string{"/a/b/c/:d", "/" + strings.Repeat("a", 63)} {
+		if err := router.GET(path, path); err != nil {
+			t.Errorf("path within the limits rejected: %v", err)
+		}
+	}
+	if err := router.GET("/p/:y/:z", "params"); err != nil {
+		t.Fatal(err)
+	}
+
+	forBoth(t, router, func(t *testing.T, r lookupRouter) {
+		tests := []struct {
+			path   string
+			handle interface{}
+		}{
+			{"/a/b/c/d", "/a/b/c/:d"},
+			{"/p/b/c", "params"},
+			{"/a/b/c/d/", nil}, // 5 segments, no redirect
+			{"/a/b/c/d/e/f", nil},
+			{"/" + strings.Repeat("a", 63), "/" + strings.Repeat("a", 63)},
+			{"/" + strings.Repeat("a", 64), nil},
+		}
+		for _, test := range tests {
+			handle, _, tsr := r.Lookup("GET", test.path)
+			if handle != test.handle || (test.handle == nil && tsr) {
+				t.Errorf("wrong result for %s: %v, %t", test.path, handle, tsr)
+			}
+		}
+	})
+
+	// catch-all values may have any number of segments
+	router = New()
+	router.MaxPathLength = 0
+	router.GET("/files/*filepath", "files")
+	long := "/files" + strings.Repeat("/dir", 500) + "/file.txt"
+	if handle, ps, _ := router.Lookup("GET", long); handle != "files" || len(ps.ByName("filepath")) != len(long)-len("/files") {
+		t.Errorf("long catch-all value rejected: %v", handle)
+	}
+
+	// no limits
+	router = New()
+	router.MaxSegments, router.MaxPathLength = 0, 0
+	deep := strings.Repeat("/a", 200)
+	if err := router.GET(deep, "deep"); err != nil {
+		t.Fatal(err)
+	}
+	if handle, _, _ := router.Lookup("GET", deep); handle != "deep" {
+		t.Errorf("deep path not found without limits: %v", handle)
+	}
+
+	// defaults
+	router = New()
+	if err := router.GET(strings.Repeat("/a", DefaultMaxSegments+1), "h"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("default segment limit not enforced: %v", err)
+	}
+}
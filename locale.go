@@ -0,0 +1,91 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LocaleParam is the key of the Param holding the locale recognized by a
+// router configured with LocalePrefix.
+const LocaleParam = "lang"
+
+// LocalePrefix makes the router recognize a leading path segment naming one of
+// the given locale codes, e.g. "/en/users/1". The segment is stripped before
+// the remainder of the path is matched against the registered routes, so
+// routes are registered without the prefix, and the locale is stored as the
+// first Param under the key LocaleParam. Routes declaring a wildcard named
+// LocaleParam are rejected then with ErrReservedParam, so LocalePrefix should
+// be called before registering routes; the values of such routes registered
+// before are dropped from the Params.
+// Paths not starting with one of the codes are matched unchanged, with the
+// first code as the default locale.
+// Calling LocalePrefix without codes disables the prefix handling.
+// Like Handle, LocalePrefix returns ErrSealed after the first lookup unless
+// ConcurrentRegistration is set.
+func (r *Router) LocalePrefix(codes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.immutable.Load() {
+		return ErrFrozen
+	}
+	if r.sealed.Load() && !r.ConcurrentRegistration {
+		return fmt.Errorf("locale prefix: %w", ErrSealed)
+	}
+
+	r.invalidateCache()
+	if len(codes) == 0 {
+		r.locales.Store(nil)
+		return nil
+	}
+	l := &localeSet{codes: make(map[string]bool, len(codes)), fallback: codes[0]}
+	for _, code := range codes {
+		l.codes[code] = true
+	}
+	r.locales.Store(l)
+	return nil
+}
+
+// localeSet holds the locale codes of LocalePrefix. It is never modified once
+// stored in Router.locales.
+type localeSet struct {
+	codes map[string]bool
+
+	// the locale of paths starting with none of the codes
+	fallback string
+}
+
+// lookupLocale implements LookupRoute for a router with locale prefixes.
+func (r *Router) lookupLocale(l *localeSet, method, path string, noTSR bool) (*Route, Params, bool) {
+	locale, path := l.split(path)
+	rt, ps, tsr := r.lookup(method, path, noTSR)
+	if rt == nil {
+		return nil, nil, tsr
+	}
+	p := make(Params, 1, len(ps)+1)
+	p[0] = Param{Key: LocaleParam, Value: locale}
+	for _, param := range ps {
+		if param.Key != LocaleParam {
+			p = append(p, param)
+		}
+	}
+	return rt, p, tsr
+}
+
+// split returns the locale of path and the path to match against the routes.
+func (l *localeSet) split(path string) (locale, rest string) {
+	if len(path) > 1 {
+		seg, rest := path[1:], "/"
+		if i := strings.IndexByte(seg, '/'); i >= 0 {
+			seg, rest = seg[:i], seg[i:]
+		}
+		if l.codes[seg] {
+			return seg, rest
+		}
+	}
+	return l.fallback, path
+}
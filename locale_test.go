@@ -0,0 +1,69 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRouterLocalePrefix(t *testing.T) {
+	var got Params
+	router := New()
+	router.LocalePrefix([]string{"en", "zh"})
+	router.GET("/", Handle(func(_ http.ResponseWriter, _ *http.Request, ps Params) {
+		got = ps
+	}))
+	router.GET("/users/:id", Handle(func(_ http.ResponseWriter, _ *http.Request, ps Params) {
+		got = ps
+	}))
+
+	tests := []struct {
+		path string
+		ps   Params
+	}{
+		{"/en/users/1", Params{{LocaleParam, "en"}, {"id", "1"}}},
+		{"/zh/users/1", Params{{LocaleParam, "zh"}, {"id", "1"}}},
+		{"/users/2", Params{{LocaleParam, "en"}, {"id", "2"}}},
+		{"/zh/", Params{{LocaleParam, "zh"}}},
+		{"/zh", Params{{LocaleParam, "zh"}}},
+		{"/", Params{{LocaleParam, "en"}}},
+	}
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		for _, test := range tests {
+			got = nil
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("wrong status code for %s: %d", test.path, w.Code)
+			}
+			if !reflect.DeepEqual(got, test.ps) {
+				t.Errorf("wrong params for %s: want %v, got %v", test.path, test.ps, got)
+			}
+		}
+
+		// unknown locales are matched as part of the path
+		if handle, _, _ := router.Lookup("GET", "/fr/users/1"); handle != nil {
+			t.Errorf("got handle for unknown locale: %v", handle)
+		}
+		if _, _, tsr := router.Lookup("GET", "/en/users/1/"); !tsr {
+			t.Error("expected TSR recommendation behind locale prefix")
+		}
+	})
+
+	if err := router.LocalePrefix(nil); !errors.Is(err, ErrSealed) {
+		t.Errorf("wrong error disabling the locale prefix after the first lookup: %v", err)
+	}
+	router.ConcurrentRegistration = true
+	if err := router.LocalePrefix(nil); err != nil {
+		t.Fatal(err)
+	}
+	if handle, ps, _ := router.Lookup("GET", "/users/1"); handle == nil || len(ps) != 1 {
+		t.Errorf("wrong result with disabled locale prefix: %v, %v", handle, ps)
+	}
+}
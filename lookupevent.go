@@ -0,0 +1,74 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import "time"
+
+// LookupOutcome is the result of a lookup, see LookupEvent.
+type LookupOutcome int
+
+const (
+	// LookupMatched is the outcome of lookups matching a route.
+	LookupMatched LookupOutcome = iota
+
+	// LookupMissed is the outcome of lookups matching no route, without a
+	// trailing slash recommendation.
+	LookupMissed
+
+	// LookupTSR is the outcome of lookups matching no route, but
+	// recommending a redirect to the path with or without a trailing slash.
+	LookupTSR
+)
+
+// String returns the name of the outcome.
+func (o LookupOutcome) String() string {
+	switch o {
+	case LookupMatched:
+		return "matched"
+	case LookupMissed:
+		return "missed"
+	case LookupTSR:
+		return "tsr"
+	}
+	return "invalid"
+}
+
+// LookupEvent describes a lookup of a router, see OnLookup.
+type LookupEvent struct {
+	Method string
+	Path   string
+
+	// Pattern is the path the matched route was registered with, or empty
+	// if no route matched.
+	Pattern string
+
+	// Duration is the time the lookup took, including the lookup cache.
+	Duration time.Duration
+
+	Outcome LookupOutcome
+}
+
+// OnLookup sets a function called after every lookup of the router, by the
+// Lookup methods as well as ServeHTTP, e.g. to record metrics of the routing
+// decisions. The function is called synchronously and must not block.
+// Without it, lookups take no timestamps.
+// OnLookup must not be called while the router serves requests.
+func (r *Router) OnLookup(fn func(LookupEvent)) {
+	r.onLookup = fn
+}
+
+func newLookupEvent(method, path string, rt *Route, tsr bool, start time.Time) LookupEvent {
+	e := LookupEvent{Method: method, Path: path, Duration: time.Since(start)}
+	switch {
+	case rt != nil:
+		e.Pattern = rt.Path
+		e.Outcome = LookupMatched
+	case tsr:
+		e.Outcome = LookupTSR
+	default:
+		e.Outcome = LookupMissed
+	}
+	return e
+}
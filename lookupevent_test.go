@@ -0,0 +1,79 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRouterOnLookup(t *testing.T) {
+	router := New()
+	router.GET("/user/:name", http.NotFoundHandler())
+	router.GET("/src/", http.NotFoundHandler())
+
+	var events []LookupEvent
+	router.OnLookup(func(e LookupEvent) {
+		if e.Duration < 0 {
+			t.Errorf("negative duration: %v", e.Duration)
+		}
+		e.Duration = 0
+		events = append(events, e)
+	})
+
+	router.Lookup("GET", "/user/gopher")
+	router.Lookup("GET", "/nope")
+	router.Lookup("GET", "/src")
+	router.LookupFunc("POST", "/user/gopher", func(string, string) {})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/gopher", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/src", nil))
+
+	want := []LookupEvent{
+		{Method: "GET", Path: "/user/gopher", Pattern: "/user/:name", Outcome: LookupMatched},
+		{Method: "GET", Path: "/nope", Outcome: LookupMissed},
+		{Method: "GET", Path: "/src", Outcome: LookupTSR},
+		{Method: "POST", Path: "/user/gopher", Outcome: LookupMissed},
+		{Method: "GET", Path: "/user/gopher", Pattern: "/user/:name", Outcome: LookupMatched},
+		{Method: "GET", Path: "/src", Outcome: LookupTSR},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("wrong events:\ngot  %v\nwant %v", events, want)
+	}
+	if s := LookupTSR.String(); s != "tsr" {
+		t.Errorf("wrong name: %q", s)
+	}
+}
+
+func TestRouterOnLookupFallbacks(t *testing.T) {
+	router := New()
+	router.AutoHead = true
+	router.GET("/page", http.NotFoundHandler())
+	router.Handle(MethodAny, "/health", http.NotFoundHandler())
+
+	var events []LookupEvent
+	router.OnLookup(func(e LookupEvent) {
+		e.Duration = 0
+		events = append(events, e)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("HEAD", "/page", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/health", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/nope", nil))
+	router.LookupAny("PUT", "/health")
+	router.LookupAny("PUT", "/nope")
+
+	want := []LookupEvent{
+		{Method: "HEAD", Path: "/page", Pattern: "/page", Outcome: LookupMatched},
+		{Method: "POST", Path: "/health", Pattern: "/health", Outcome: LookupMatched},
+		{Method: "POST", Path: "/nope", Outcome: LookupMissed},
+		{Method: "PUT", Path: "/health", Pattern: "/health", Outcome: LookupMatched},
+		{Method: "PUT", Path: "/nope", Outcome: LookupMissed},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("wrong events:\ngot  %v\nwant %v", events, want)
+	}
+}
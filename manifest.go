@@ -0,0 +1,83 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+)
+
+// GenerateManifest returns formatted Go source of package pkg declaring
+//
+//	func RegisterRoutes(r *xrouter.Router) error
+//
+// which registers the given routes on r. The Handle of each route must be a
+// string holding the Go expression of the handle, e.g. "showUser", so typos
+// in handle names fail the build. The expressions are evaluated in package pkg,
+// which has only xrouter imported. The routes are registered on a scratch
+// router first, so conflicting or malformed paths are reported by
+// GenerateManifest already, all of them at once in a *MultiError. Route
+// options can't be generated, routes with any of them set are reported.
+//
+// GenerateManifest is meant to be called from a small program run by
+// go:generate, keeping the route list as the single source of truth.
+func GenerateManifest(routes []Route, pkg string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name '%s'", pkg)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by xrouter.GenerateManifest. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintf(&buf, "import \"github.com/zhaojkun/xrouter\"\n\n")
+	fmt.Fprintf(&buf, "// RegisterRoutes registers the routes of the manifest on r.\n")
+	fmt.Fprintf(&buf, "func RegisterRoutes(r *xrouter.Router) error {\n")
+
+	scratch := New()
+	scratch.DisableCallerSource = true
+	var failed MultiError
+	for _, rt := range routes {
+		expr, ok := rt.Handle.(string)
+		if !ok {
+			failed.add(rt.Method, rt.Path, fmt.Errorf("handle must be a Go expression, got %T", rt.Handle))
+			continue
+		}
+		if _, err := parser.ParseExpr(expr); err != nil {
+			failed.add(rt.Method, rt.Path, fmt.Errorf("invalid handle '%s': %w", expr, err))
+			continue
+		}
+		if rt.Method == "" {
+			failed.add(rt.Method, rt.Path, errors.New("missing method"))
+			continue
+		}
+		if !reflect.ValueOf(rt.Options).IsZero() {
+			failed.add(rt.Method, rt.Path, errors.New("options can't be generated"))
+			continue
+		}
+		if err := scratch.Handle(rt.Method, rt.Path, expr); err != nil {
+			failed.add(rt.Method, rt.Path, err)
+			continue
+		}
+
+		fmt.Fprintf(&buf, "\tif err := r.Handle(%s, %s, %s); err != nil {\n\t\treturn err\n\t}\n",
+			strconv.Quote(rt.Method), strconv.Quote(rt.Path), expr)
+	}
+	if err := failed.err(); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&buf, "\treturn nil\n}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting manifest: %w", err)
+	}
+	return src, nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zhaojkun/xrouter"
+)
+
+var updateManifest = flag.Bool("update-manifest", false, "regenerate "+manifestFile)
+
+// manifestFile is generated from manifestRoutes, run the tests with
+// -update-manifest after changing them.
+const manifestFile = "manifest_gen_test.go"
+
+var manifestRoutes = []xrouter.Route{
+	{Method: "GET", Path: "/", Handle: "index"},
+	{Method: "GET", Path: "/users/:name", Handle: "showUser"},
+	{Method: "POST", Path: "/users", Handle: "xrouter.Handle(createUser)"},
+	{Method: "GET", Path: "/files/*filepath", Handle: "files"},
+}
+
+func index(_ http.ResponseWriter, _ *http.Request, _ xrouter.Params)      {}
+func showUser(_ http.ResponseWriter, _ *http.Request, _ xrouter.Params)   {}
+func createUser(_ http.ResponseWriter, _ *http.Request, _ xrouter.Params) {}
+
+var files = http.NotFoundHandler()
+
+func TestGenerateManifest(t *testing.T) {
+	src, err := xrouter.GenerateManifest(manifestRoutes, "xrouter_test")
+	if err != nil {
+		t.Fatalf("generating manifest failed: %v", err)
+	}
+	if *updateManifest {
+		if err := os.WriteFile(manifestFile, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the generated file is part of this test package, so it compiles
+	old, err := os.ReadFile(manifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, old) {
+		t.Errorf("%s is out of date, run the tests with -update-manifest", manifestFile)
+	}
+
+	router := xrouter.New()
+	if err := RegisterRoutes(router); err != nil {
+		t.Fatalf("registering manifest routes failed: %v", err)
+	}
+	for _, route := range manifestRoutes {
+		rt, _, _ := router.LookupRoute(route.Method, route.Path)
+		if rt == nil || rt.Path != route.Path {
+			t.Errorf("route %s %s not registered: %+v", route.Method, route.Path, rt)
+		}
+	}
+}
+
+func TestGenerateManifestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []xrouter.Route
+		pkg    string
+	}{
+		{"package", manifestRoutes, "main pkg"},
+		{"handle type", []xrouter.Route{{Method: "GET", Path: "/", Handle: index}}, "main"},
+		{"handle expression", []xrouter.Route{{Method: "GET", Path: "/", Handle: "index("}}, "main"},
+		{"method", []xrouter.Route{{Path: "/", Handle: "index"}}, "main"},
+		{"path", []xrouter.Route{{Method: "GET", Path: "users", Handle: "index"}}, "main"},
+		{"conflict", []xrouter.Route{
+			{Method: "GET", Path: "/users/:name", Handle: "showUser"},
+			{Method: "GET", Path: "/users/:id", Handle: "showUser"},
+		}, "main"},
+		{"options", []xrouter.Route{{Method: "GET", Path: "/users/:name", Handle: "showUser", Options: xrouter.RouteOptions{
+			Validate: map[string]func(string) error{"name": nil},
+		}}}, "main"},
+		{"name option", []xrouter.Route{{Method: "GET", Path: "/users/:name", Handle: "showUser", Options: xrouter.RouteOptions{
+			Name: "user",
+		}}}, "main"},
+		{"catch-all option", []xrouter.Route{{Method: "GET", Path: "/files/*path", Handle: "files", Options: xrouter.RouteOptions{
+			EmptyCatchAll: true,
+		}}}, "main"},
+	}
+	for _, test := range tests {
+		if _, err := xrouter.GenerateManifest(test.routes, test.pkg); err == nil {
+			t.Errorf("no error for invalid %s", test.name)
+		}
+	}
+}
+
+func TestGenerateManifestMultiError(t *testing.T) {
+	routes := []xrouter.Route{
+		{Method: "GET", Path: "/users/:name", Handle: "showUser"},
+		{Method: "GET", Path: "/users/:id", Handle: "showUser"},
+		{Method: "GET", Path: "users", Handle: "index"},
+		{Method: "GET", Path: "/files/*", Handle: "files"},
+		{Method: "POST", Path: "/users", Handle: "xrouter.Handle(createUser)"},
+	}
+	_, err := xrouter.GenerateManifest(routes, "main")
+	var multi *xrouter.MultiError
+	if !errors.As(err, &multi) || len(multi.Errors) != 3 {
+		t.Fatalf("expected three failures, got %v", err)
+	}
+	for _, kind := range []error{xrouter.ErrConflict, xrouter.ErrInvalidPath, xrouter.ErrInvalidWildcard} {
+		if !errors.Is(err, kind) {
+			t.Errorf("%v not reported: %v", kind, err)
+		}
+	}
+	for _, route := range []string{"'GET /users/:id'", "'GET users'", "'GET /files/*'"} {
+		if !strings.Contains(err.Error(), route) {
+			t.Errorf("%s not named: %v", route, err)
+		}
+	}
+	if strings.Contains(err.Error(), "POST") {
+		t.Errorf("valid route reported: %v", err)
+	}
+	var rtErr *xrouter.RouteError
+	if !errors.As(multi.Errors[1], &rtErr) || rtErr.Method != "GET" || rtErr.Path != "users" {
+		t.Errorf("wrong route error: %#v", multi.Errors[1])
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NewFrom returns a new initialized Router holding the routes of all the
+// given routers, with their options, e.g. to assemble a router from route
+// tables built in parallel. The given routers are not modified, so NewFrom
+// returns equal routers when called repeatedly. Settings of the routers, like
+// subtree defaults, are not taken over.
+// If routes of different routers conflict, NewFrom returns a *MultiError
+// listing all conflicting routes along with the index of their router.
+func NewFrom(routers ...*Router) (*Router, error) {
+	merged := New()
+	// the paths have been accepted by the given routers
+	merged.PermissivePaths = true
+	merged.AllowDuplicateParams = true
+	var conflicts MultiError
+	for i, r := range routers {
+		for _, rt := range r.routes() {
+			opts := rt.Options
+			opts.Source = rt.Source
+			if err := merged.HandleOptions(rt.Method, rt.Path, rt.Handle, opts); err != nil {
+				conflicts.add(rt.Method, rt.Path, fmt.Errorf("router %d: %w", i, err))
+			}
+		}
+	}
+	if err := conflicts.err(); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// Routes returns the routes registered with the router, ordered by method and
+// path. The handles are the registered ones, not wrapped by the middleware of
+// the routes.
+func (r *Router) Routes() []*Route {
+	return r.routes()
+}
+
+// routes returns the routes registered with the router, ordered by method
+// and path.
+func (r *Router) routes() []*Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var routes []*Route
+	r.eachTree(func(_ string, t *methodTree) {
+		if t.root == nil {
+			// frozen trees only keep the compact form
+			for _, data := range t.compact.data {
+				if rt, ok := data.(*Route); ok {
+					routes = append(routes, rt)
+				}
+			}
+			return
+		}
+		t.root.walk(func(n *node) {
+			if rt, ok := n.data.(*Route); ok {
+				routes = append(routes, rt)
+			}
+		})
+	})
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Method != routes[j].Method {
+			return routes[i].Method < routes[j].Method
+		}
+		return routes[i].Path < routes[j].Path
+	})
+	return routes
+}
@@ -0,0 +1,79 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewFrom(t *testing.T) {
+	users := New()
+	users.GET("/users/:id", "user")
+	users.HandleOptions("PUT", "/users/:id", "update", RouteOptions{Tags: []string{"admin"}})
+	repos := New()
+	repos.GET("/repos/:owner/:repo", "repo")
+	repos.Handle("PURGE", "/repos/:owner/:repo", "purge")
+
+	for i := 0; i < 2; i++ {
+		merged, err := NewFrom(users, repos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			method, path string
+			handle       interface{}
+			ps           Params
+		}{
+			{"GET", "/users/1", "user", Params{{"id", "1"}}},
+			{"PUT", "/users/1", "update", Params{{"id", "1"}}},
+			{"GET", "/repos/gopher/xrouter", "repo", Params{{"owner", "gopher"}, {"repo", "xrouter"}}},
+			{"PURGE", "/repos/gopher/xrouter", "purge", Params{{"owner", "gopher"}, {"repo", "xrouter"}}},
+		}
+		for _, test := range tests {
+			rt, ps, _ := merged.LookupRoute(test.method, test.path)
+			if rt == nil || rt.Handle != test.handle || !reflect.DeepEqual(ps, test.ps) {
+				t.Errorf("wrong result for %s %s: got %+v, %v", test.method, test.path, rt, ps)
+			}
+		}
+		if rt, _, _ := merged.LookupRoute("PUT", "/users/1"); rt == nil || len(rt.Options.Tags) != 1 {
+			t.Errorf("options not merged: %+v", rt)
+		}
+	}
+
+	// the pieces are left alone
+	if handle, _, _ := users.Lookup("GET", "/repos/gopher/xrouter"); handle != nil {
+		t.Errorf("piece modified by merging: %v", handle)
+	}
+}
+
+func TestNewFromConflict(t *testing.T) {
+	a := New()
+	a.GET("/users/:id", "a")
+	a.GET("/static", "a")
+	b := New()
+	b.GET("/users/:name", "b")
+	b.GET("/static", "b")
+	b.POST("/static", "b")
+
+	merged, err := NewFrom(a, b)
+	if err == nil {
+		t.Fatalf("expected error, got router %v", merged)
+	}
+	var multi *MultiError
+	if !errors.As(err, &multi) || len(multi.Errors) != 2 || !errors.Is(err, ErrConflict) {
+		t.Errorf("wrong error: %#v", err)
+	}
+	for _, want := range []string{"'GET /static': router 1", "'GET /users/:name': router 1"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not name %s", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "POST") || strings.Contains(err.Error(), "router 0") {
+		t.Errorf("error names routes without conflict: %q", err)
+	}
+}
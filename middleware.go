@@ -0,0 +1,200 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps a Handle, e.g. to run code before and after it.
+type Middleware func(next Handle) Handle
+
+// Use adds middleware applied by ServeHTTP around the handle of every matched
+// route. Middleware added by earlier calls runs first; within a call, the
+// first middleware is the outermost. The matched route is available to the
+// middleware through RouteFromContext. With MiddlewareOnMiss, the middleware
+// also wraps the NotFound and MethodNotAllowed handlers.
+// The chain is composed per request, so it also applies to routes registered
+// before, at the cost of an allocation per middleware and request.
+// Use must not be called while the router serves requests.
+func (r *Router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// scopedMiddleware is middleware applying to the routes whose path matches
+// glob, see UseFor.
+type scopedMiddleware struct {
+	glob string
+	mw   []Middleware
+}
+
+// UseFor adds middleware to the chain of every route whose registered path
+// matches patternGlob, e.g. "/admin/**" for the admin routes "/admin/users"
+// and "/admin/users/:id", but not "/admin-public/x". A "*" segment of the
+// glob matches any single segment of the path, including wildcards like
+// ":id", and a "**" segment any number of segments, so "/a/**/b" matches
+// "/a/b", but "/admin/**" doesn't match "/admin". Other segments must equal
+// the segments of the path.
+// The glob is matched and the chain composed at registration, like the
+// middleware of RouteOptions.Middleware, which runs inside. Routes
+// registered before are composed anew. Middleware added by earlier calls runs
+// first; within a call, the first middleware is the outermost. Middleware
+// added by Use runs outside.
+// UseFor must not be called while the router serves requests.
+func (r *Router) UseFor(patternGlob string, mw ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.immutable.Load() {
+		return
+	}
+
+	r.scoped = append(r.scoped, scopedMiddleware{glob: patternGlob, mw: mw})
+	r.eachTree(func(_ string, t *methodTree) {
+		changed := false
+		t.root.walk(func(n *node) {
+			old, ok := n.data.(*Route)
+			if !ok || !globMatch(patternGlob, old.Path) {
+				return
+			}
+			// routes are never modified after registration, replace it by a copy
+			rt := *old
+			rt.compose(r)
+			n.data = &rt
+			if countParams(rt.Path) == 0 {
+				t.static.add(&rt)
+			}
+			changed = true
+		})
+		if changed {
+			r.relayout(t)
+		}
+	})
+	r.invalidateCache()
+}
+
+// globMatch reports whether path matches glob, see UseFor.
+func globMatch(glob, path string) bool {
+	for {
+		if glob == "**" || strings.HasPrefix(glob, "**/") {
+			rest := strings.TrimPrefix(glob[2:], "/")
+			for {
+				if globMatch(rest, path) {
+					return true
+				}
+				i := strings.IndexByte(path, '/')
+				if i < 0 {
+					// "**" matching the remaining segment
+					return rest == ""
+				}
+				path = path[i+1:]
+			}
+		}
+		gseg, grest, gmore := strings.Cut(glob, "/")
+		pseg, prest, pmore := strings.Cut(path, "/")
+		if gseg != "*" && gseg != pseg {
+			return false
+		}
+		if !gmore || !pmore {
+			return gmore == pmore
+		}
+		glob, path = grest, prest
+	}
+}
+
+// HandleWith registers a new request handle with the given path and method,
+// wrapped by the given middleware, see RouteOptions.Middleware. The first
+// middleware is the outermost. The chain is composed once at registration;
+// lookups and Routes return the handle itself.
+func (r *Router) HandleWith(method, path string, handle interface{}, mw ...Middleware) error {
+	return r.HandleOptions(method, path, handle, RouteOptions{Middleware: mw})
+}
+
+// GETWith is a shortcut for router.HandleWith("GET", path, handle, mw...)
+func (r *Router) GETWith(path string, handle interface{}, mw ...Middleware) error {
+	return r.HandleWith("GET", path, handle, mw...)
+}
+
+// HEADWith is a shortcut for router.HandleWith("HEAD", path, handle, mw...)
+func (r *Router) HEADWith(path string, handle interface{}, mw ...Middleware) error {
+	return r.HandleWith("HEAD", path, handle, mw...)
+}
+
+// OPTIONSWith is a shortcut for router.HandleWith("OPTIONS", path, handle, mw...)
+func (r *Router) OPTIONSWith(path string, handle interface{}, mw ...Middleware) error {
+	return r.HandleWith("OPTIONS", path, handle, mw...)
+}
+
+// POSTWith is a shortcut for router.HandleWith("POST", path, handle, mw...)
+func (r *Router) POSTWith(path string, handle interface{}, mw ...Middleware) error {
+	return r.HandleWith("POST", path, handle, mw...)
+}
+
+// PUTWith is a shortcut for router.HandleWith("PUT", path, handle, mw...)
+func (r *Router) PUTWith(path string, handle interface{}, mw ...Middleware) error {
+	return r.HandleWith("PUT", path, handle, mw...)
+}
+
+// PATCHWith is a shortcut for router.HandleWith("PATCH", path, handle, mw...)
+func (r *Router) PATCHWith(path string, handle interface{}, mw ...Middleware) error {
+	return r.HandleWith("PATCH", path, handle, mw...)
+}
+
+// DELETEWith is a shortcut for router.HandleWith("DELETE", path, handle, mw...)
+func (r *Router) DELETEWith(path string, handle interface{}, mw ...Middleware) error {
+	return r.HandleWith("DELETE", path, handle, mw...)
+}
+
+// UseError adds mappers applied by ServeHTTP to the errors returned by handles
+// of type HandleErr before they are passed to the ErrorHandler, e.g. to map
+// domain errors to errors carrying a status. The mappers run in the order
+// they were added, each receiving the error returned by the previous one. A
+// mapper returning nil drops the error, which is not rendered then.
+// UseError must not be called while the router serves requests.
+func (r *Router) UseError(mappers ...func(error) error) {
+	r.errorMappers = append(r.errorMappers, mappers...)
+}
+
+// handleErr adapts h to a Handle passing its errors through the error
+// mappers to the ErrorHandler.
+func (r *Router) handleErr(h HandleErr) Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps Params) {
+		err := h(w, req, ps)
+		for _, mapErr := range r.errorMappers {
+			if err == nil {
+				return
+			}
+			err = mapErr(err)
+		}
+		if err == nil {
+			return
+		}
+		if r.ErrorHandler != nil {
+			r.ErrorHandler(w, req, err)
+			return
+		}
+		defaultErrorHandler(w, req, err)
+	}
+}
+
+// defaultErrorHandler answers a request failing with err with 500 Internal
+// Server Error, not exposing the error to the client.
+func defaultErrorHandler(w http.ResponseWriter, _ *http.Request, _ error) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// toHandle adapts a handle of any supported type to a Handle.
+func (r *Router) toHandle(handle interface{}) Handle {
+	switch h := handle.(type) {
+	case Handle:
+		return h
+	case func(http.ResponseWriter, *http.Request, Params):
+		return h
+	}
+	return func(w http.ResponseWriter, req *http.Request, ps Params) {
+		r.serve(handle, w, req, ps)
+	}
+}
@@ -0,0 +1,367 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRouterUse(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next Handle) Handle {
+			return func(w http.ResponseWriter, req *http.Request, ps Params) {
+				calls = append(calls, name)
+				next(w, req, ps)
+			}
+		}
+	}
+
+	router := New()
+	router.GET("/user/:name", http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "handler "+ParamsFromContext(req.Context()).ByName("name"))
+	}))
+	router.Use(mw("first"), mw("second"))
+	router.Use(mw("third"))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/gopher", nil))
+	want := []string{"first", "second", "third", "handler gopher"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("wrong invocation order: want %v, got %v", want, calls)
+	}
+
+	calls = nil
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+	if len(calls) != 0 {
+		t.Errorf("middleware invoked for unmatched request: %v", calls)
+	}
+}
+
+func TestRouterUseOnMiss(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next Handle) Handle {
+			return func(w http.ResponseWriter, req *http.Request, ps Params) {
+				calls = append(calls, name)
+				next(w, req, ps)
+			}
+		}
+	}
+	router := New()
+	router.HandleMethodNotAllowed = true
+	router.GET("/user/:name", func(http.ResponseWriter, *http.Request, Params) { calls = append(calls, "handle") })
+	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls = append(calls, "not found")
+		w.WriteHeader(http.StatusNotFound)
+	})
+	// added after the routes
+	router.Use(mw("first"))
+	router.Use(mw("second"))
+
+	tests := []struct {
+		method, path string
+		onMiss       bool
+		code         int
+		want         []string
+	}{
+		{"GET", "/user/gopher", false, http.StatusOK, []string{"first", "second", "handle"}},
+		{"GET", "/missing", false, http.StatusNotFound, []string{"not found"}},
+		{"POST", "/user/gopher", false, http.StatusMethodNotAllowed, nil},
+		{"GET", "/missing", true, http.StatusNotFound, []string{"first", "second", "not found"}},
+		{"POST", "/user/gopher", true, http.StatusMethodNotAllowed, []string{"first", "second"}},
+	}
+	for _, test := range tests {
+		calls = nil
+		router.MiddlewareOnMiss = test.onMiss
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.code || !reflect.DeepEqual(calls, test.want) {
+			t.Errorf("%s %s, on miss %v: got %d %v, want %d %v", test.method, test.path, test.onMiss, w.Code, calls, test.code, test.want)
+		}
+	}
+}
+
+func TestRouterHandleWith(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next Handle) Handle {
+			return func(w http.ResponseWriter, req *http.Request, ps Params) {
+				calls = append(calls, name)
+				next(w, req, ps)
+			}
+		}
+	}
+	auth := func(next Handle) Handle {
+		return func(w http.ResponseWriter, req *http.Request, ps Params) {
+			if req.Header.Get("Authorization") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next(w, req, ps)
+		}
+	}
+	// hides the internal tenant param from the handle and adds a derived one
+	scope := func(next Handle) Handle {
+		return func(w http.ResponseWriter, req *http.Request, ps Params) {
+			scoped := Params{{"scope", "tenant-" + ps.ByName("tenant")}}
+			for _, p := range ps {
+				if p.Key != "tenant" {
+					scoped = append(scoped, p)
+				}
+			}
+			next(w, req, scoped)
+		}
+	}
+
+	router := New()
+	router.Use(mw("router"))
+	var got Params
+	handle := func(_ http.ResponseWriter, _ *http.Request, ps Params) {
+		calls = append(calls, "handle")
+		got = ps
+	}
+	router.GETWith("/admin/:tenant/users/:id", handle, mw("outer"), auth, scope, mw("inner"))
+	router.GET("/public", handle)
+
+	req := httptest.NewRequest("GET", "/admin/acme/users/1", nil)
+	req.Header.Set("Authorization", "token")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if want := []string{"router", "outer", "inner", "handle"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("wrong order: %v, want %v", calls, want)
+	}
+	if want := (Params{{"scope", "tenant-acme"}, {"id", "1"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong params downstream: %v, want %v", got, want)
+	}
+
+	// short-circuited
+	calls = nil
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/acme/users/1", nil))
+	if want := []string{"router", "outer"}; w.Code != http.StatusUnauthorized || !reflect.DeepEqual(calls, want) {
+		t.Errorf("not short-circuited: %d, %v", w.Code, calls)
+	}
+
+	// routes without middleware are unaffected
+	calls = nil
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/public", nil))
+	if want := []string{"router", "handle"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("wrong calls: %v", calls)
+	}
+
+	// listings and lookups report the handle itself
+	for _, rt := range router.Routes() {
+		if fmt.Sprintf("%p", rt.Handle) != fmt.Sprintf("%p", handle) {
+			t.Errorf("wrong handle listed for %s", rt.Path)
+		}
+	}
+	if h, _, _ := router.Lookup("GET", "/admin/acme/users/1"); fmt.Sprintf("%p", h) != fmt.Sprintf("%p", handle) {
+		t.Error("lookup returned the middleware chain")
+	}
+
+	// the chain is composed once, serving allocates as much as without
+	router = New()
+	noop := func(next Handle) Handle { return next }
+	router.GET("/plain/:id", handle)
+	router.GETWith("/wrapped/:id", handle, noop, noop)
+	w = httptest.NewRecorder()
+	plain := httptest.NewRequest("GET", "/plain/1", nil)
+	wrapped := httptest.NewRequest("GET", "/wrapped/1", nil)
+	allocs := testing.AllocsPerRun(100, func() { router.ServeHTTP(w, plain) })
+	if wrappedAllocs := testing.AllocsPerRun(100, func() { router.ServeHTTP(w, wrapped) }); wrappedAllocs != allocs {
+		t.Errorf("route middleware allocates: %v allocs, %v without", wrappedAllocs, allocs)
+	}
+}
+
+func TestRouterUseFor(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next Handle) Handle {
+			return func(w http.ResponseWriter, req *http.Request, ps Params) {
+				calls = append(calls, name)
+				next(w, req, ps)
+			}
+		}
+	}
+	handle := func(http.ResponseWriter, *http.Request, Params) {
+		calls = append(calls, "handle")
+	}
+
+	router := New()
+	router.GET("/admin/users/:id", handle)
+	router.UseFor("/admin/**", mw("auth"))
+	router.GETWith("/admin/stats", handle, mw("route"))
+	router.GET("/admin-public/x", handle)
+	router.GET("/admin", handle)
+	router.GET("/api/v1/items/:id/tags", handle)
+	router.UseFor("/api/*/items/**", mw("items"))
+
+	tests := []struct {
+		path  string
+		calls []string
+	}{
+		// registered before UseFor
+		{"/admin/users/1", []string{"auth", "handle"}},
+		{"/admin/stats", []string{"auth", "route", "handle"}},
+		{"/admin-public/x", []string{"handle"}},
+		{"/admin", []string{"handle"}},
+		{"/api/v1/items/1/tags", []string{"items", "handle"}},
+	}
+	for _, test := range tests {
+		calls = nil
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", test.path, nil))
+		if !reflect.DeepEqual(calls, test.calls) {
+			t.Errorf("wrong calls for %s: %v, want %v", test.path, calls, test.calls)
+		}
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		match      bool
+	}{
+		{"/admin/**", "/admin/users/:id", true},
+		{"/admin/**", "/admin/", true},
+		{"/admin/**", "/admin", false},
+		{"/admin/**", "/admin-public/x", false},
+		{"/admin/*", "/admin/users", true},
+		{"/admin/*", "/admin/users/:id", false},
+		{"/*/users", "/admin/users", true},
+		{"/a/**/b", "/a/b", true},
+		{"/a/**/b", "/a/x/y/b", true},
+		{"/a/**/b", "/a/x/y/c", false},
+		{"/**", "/src/*filepath", true},
+		{"/user/:id", "/user/:id", true},
+		{"/user/:id", "/user/:name", false},
+	}
+	for _, test := range tests {
+		if match := globMatch(test.glob, test.path); match != test.match {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", test.glob, test.path, match, test.match)
+		}
+	}
+}
+
+func TestRouteFromContext(t *testing.T) {
+	router := New()
+	router.GET("/public", Handle(func(_ http.ResponseWriter, _ *http.Request, _ Params) {}))
+	router.HandleOptions("GET", "/admin/:page", Handle(func(w http.ResponseWriter, _ *http.Request, ps Params) {
+		w.Write([]byte("admin " + ps.ByName("page")))
+	}), RouteOptions{Tags: []string{"scope:admin"}})
+
+	// middleware enforcing the scopes a route is tagged with
+	router.Use(func(next Handle) Handle {
+		return func(w http.ResponseWriter, req *http.Request, ps Params) {
+			rt := RouteFromContext(req.Context())
+			for _, tag := range rt.Options.Tags {
+				if tag == "scope:admin" && req.Header.Get("Scope") != "admin" {
+					http.Error(w, "forbidden", http.StatusForbidden)
+					return
+				}
+			}
+			next(w, req, ps)
+		}
+	})
+
+	tests := []struct {
+		path, scope string
+		code        int
+	}{
+		{"/admin/users", "", http.StatusForbidden},
+		{"/admin/users", "admin", http.StatusOK},
+		{"/public", "", http.StatusOK},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", test.path, nil)
+		req.Header.Set("Scope", test.scope)
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("wrong status code for %s with scope %q: want %d, got %d", test.path, test.scope, test.code, w.Code)
+		}
+	}
+
+	if rt := RouteFromContext(httptest.NewRequest("GET", "/", nil).Context()); rt != nil {
+		t.Errorf("got route from empty context: %+v", rt)
+	}
+}
+
+// statusError is an error rendered with its status.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e statusError) Error() string { return e.err.Error() }
+
+func TestRouterUseError(t *testing.T) {
+	errNotFound := errors.New("user not found")
+	var calls []string
+
+	router := New()
+	router.GET("/user/:name", HandleErr(func(_ http.ResponseWriter, _ *http.Request, ps Params) error {
+		if ps.ByName("name") == "gopher" {
+			return nil
+		}
+		return errNotFound
+	}))
+	router.UseError(func(err error) error {
+		calls = append(calls, "wrap")
+		return fmt.Errorf("lookup: %w", err)
+	})
+	router.UseError(func(err error) error {
+		calls = append(calls, "status")
+		if errors.Is(err, errNotFound) {
+			return statusError{http.StatusNotFound, err}
+		}
+		return err
+	})
+	router.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, err error) {
+		calls = append(calls, "render")
+		var se statusError
+		if !errors.As(err, &se) {
+			se.status = http.StatusInternalServerError
+		}
+		http.Error(w, err.Error(), se.status)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/user/nobody", nil))
+	if want := []string{"wrap", "status", "render"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("wrong pipeline: want %v, got %v", want, calls)
+	}
+	if w.Code != http.StatusNotFound || w.Body.String() != "lookup: user not found\n" {
+		t.Errorf("wrong response: %d %q", w.Code, w.Body)
+	}
+
+	// no error, nothing rendered
+	calls = nil
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/user/gopher", nil))
+	if len(calls) != 0 || w.Code != http.StatusOK {
+		t.Errorf("error pipeline invoked without error: %v, %d", calls, w.Code)
+	}
+
+	// a mapper dropping the error
+	router.UseError(func(error) error { return nil })
+	calls = nil
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/nobody", nil))
+	if want := []string{"wrap", "status"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("wrong pipeline: want %v, got %v", want, calls)
+	}
+
+	// default rendering
+	router = New()
+	router.GET("/fail", HandleErr(func(http.ResponseWriter, *http.Request, Params) error { return errNotFound }))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/fail", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("wrong default status: %d", w.Code)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// miss responds to a request matching no route, with 405 Method Not Allowed
+// if other methods are allowed for the path, else with 404 Not Found. ps are
+// the values captured by the failed lookup, passed on if NotFoundParams is set.
+func (r *Router) miss(w http.ResponseWriter, req *http.Request, method, path string, ps Params) {
+	h := r.NotFound
+	notFound := true
+	if r.HandleMethodNotAllowed {
+		if allow := r.allowed(method, path); allow != "" {
+			w.Header().Set("Allow", allow)
+			h = r.MethodNotAllowed
+			if h == nil {
+				h = http.HandlerFunc(methodNotAllowed)
+			}
+			notFound = false
+		}
+	}
+	if h == nil {
+		h = http.NotFoundHandler()
+	}
+	if !notFound || !r.NotFoundParams {
+		ps = nil
+	}
+	if len(ps) > 0 {
+		req = req.WithContext(context.WithValue(req.Context(), ParamsKey, ps))
+	}
+	if notFound && r.SuggestOnMiss > 0 {
+		if suggestions := r.Suggest(method, path, r.SuggestOnMiss); len(suggestions) > 0 {
+			req = req.WithContext(context.WithValue(req.Context(), suggestionsKey{}, suggestions))
+		}
+	}
+	if !r.MiddlewareOnMiss || len(r.middleware) == 0 {
+		h.ServeHTTP(w, req)
+		return
+	}
+	handle := r.toHandle(h)
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handle = r.middleware[i](handle)
+	}
+	handle(w, req, ps)
+}
+
+func methodNotAllowed(w http.ResponseWriter, _ *http.Request) {
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// allowed returns the methods other than method with a route matching path,
+// sorted and comma-separated for the Allow header, or "" if there are none.
+func (r *Router) allowed(method, path string) string {
+	r.mu.RLock()
+	var methods []string
+	r.eachTree(func(m string, _ *methodTree) {
+		// routes of MethodAny are served for all methods
+		if m != method && m != MethodAny {
+			methods = append(methods, m)
+		}
+	})
+	r.mu.RUnlock()
+
+	// the lookups aren't reported to OnLookup, nor counted
+	allowed := methods[:0]
+	var buf [paramsBufSize]Param
+	for _, m := range methods {
+		if rt, _, _ := r.findRoute(m, path, buf[:0], true, false); rt != nil {
+			allowed = append(allowed, m)
+		}
+	}
+	sort.Strings(allowed)
+	return strings.Join(allowed, ", ")
+}
@@ -0,0 +1,112 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// openAPIMethods maps the methods of the operations of an OpenAPI path item
+// to their field names.
+var openAPIMethods = map[string]string{
+	"GET":     "get",
+	"PUT":     "put",
+	"POST":    "post",
+	"DELETE":  "delete",
+	"OPTIONS": "options",
+	"HEAD":    "head",
+	"PATCH":   "patch",
+	"TRACE":   "trace",
+}
+
+type openAPIOperation struct {
+	Summary    string                     `json:"summary,omitempty"`
+	Tags       []string                   `json:"tags,omitempty"`
+	Parameters []openAPIParameter         `json:"parameters,omitempty"`
+	Responses  map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string        `json:"name"`
+	In          string        `json:"in"`
+	Description string        `json:"description,omitempty"`
+	Required    bool          `json:"required"`
+	Schema      openAPISchema `json:"schema"`
+	CatchAll    bool          `json:"x-catch-all,omitempty"`
+}
+
+type openAPISchema struct {
+	Type string `json:"type"`
+}
+
+type openAPIResponse struct {
+	Description string `json:"description"`
+}
+
+// OpenAPIPaths returns the registered routes as the JSON of the Paths Object
+// of an OpenAPI 3 document, e.g. to keep the paths of a hand-written document
+// in sync with the routes. Parameters like ":id" become templates like "{id}"
+// with a required path parameter of type string. A catch-all like "*filepath"
+// becomes "{filepath}" as well, whose parameter is marked with the extension
+// "x-catch-all": true, since path parameters can't span several segments in
+// OpenAPI; its value begins with '/'. Each route becomes the operation of its
+// method, with the Summary and Tags of its options and a default response.
+// Routes of methods without an operation in OpenAPI, e.g. CONNECT, and
+// placeholders are left out. The output is indented and sorted by path and
+// method, so it is the same for equal routes.
+func (r *Router) OpenAPIPaths() ([]byte, error) {
+	paths := make(map[string]map[string]openAPIOperation)
+	for _, rt := range r.routes() {
+		method, ok := openAPIMethods[rt.Method]
+		if !ok || rt.reserved() {
+			continue
+		}
+		template, params := openAPIPath(rt.Path)
+		item := paths[template]
+		if item == nil {
+			item = make(map[string]openAPIOperation)
+			paths[template] = item
+		}
+		item[method] = openAPIOperation{
+			Summary:    rt.Options.Summary,
+			Tags:       rt.Options.Tags,
+			Parameters: params,
+			Responses:  map[string]openAPIResponse{"default": {Description: "Default response"}},
+		}
+	}
+	b, err := json.MarshalIndent(paths, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// openAPIPath returns the OpenAPI path template of path and its parameters.
+func openAPIPath(path string) (string, []openAPIParameter) {
+	var b strings.Builder
+	var params []openAPIParameter
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c != ':' && c != '*' {
+			b.WriteByte(c)
+			continue
+		}
+		end := i + 1
+		for end < len(path) && path[end] != '/' {
+			end++
+		}
+		name := path[i+1 : end]
+		param := openAPIParameter{Name: name, In: "path", Required: true, Schema: openAPISchema{Type: "string"}}
+		if c == '*' {
+			param.Description = "The rest of the path, beginning with '/'."
+			param.CatchAll = true
+		}
+		params = append(params, param)
+		b.WriteString("{" + name + "}")
+		i = end - 1
+	}
+	return b.String(), params
+}
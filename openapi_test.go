@@ -0,0 +1,58 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+)
+
+var updateOpenAPI = flag.Bool("update-openapi", false, "regenerate "+openAPIGolden)
+
+// openAPIGolden holds the paths exported by TestRouterOpenAPIPaths, run the
+// tests with -update-openapi after changing them.
+const openAPIGolden = "testdata/openapi.json"
+
+func TestRouterOpenAPIPaths(t *testing.T) {
+	router := New()
+	router.HandleOptions("GET", "/users", "listUsers", RouteOptions{Summary: "List users", Tags: []string{"users"}})
+	router.HandleOptions("POST", "/users", "createUser", RouteOptions{Summary: "Create a user", Tags: []string{"users", "admin"}})
+	router.HandleOptions("GET", "/users/:id", "showUser", RouteOptions{Summary: "Show a user"})
+	router.DELETE("/users/:id", "deleteUser")
+	router.GET("/repos/:owner/:repo/issues/:number", "showIssue")
+	router.GET("/static/*filepath", "static")
+	router.GET("/", "index")
+	router.Handle("CONNECT", "/tunnel", "tunnel")
+	router.GET("/reserved", Placeholder{})
+
+	got, err := router.OpenAPIPaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(got) {
+		t.Fatalf("invalid JSON:\n%s", got)
+	}
+	if *updateOpenAPI {
+		if err := os.WriteFile(openAPIGolden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(openAPIGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("paths differ from %s, run the tests with -update-openapi if intended:\n%s", openAPIGolden, got)
+	}
+
+	// deterministic
+	again, _ := router.OpenAPIPaths()
+	if !bytes.Equal(got, again) {
+		t.Error("output differs between calls")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"context"
+	"time"
+)
+
+// MatchedPattern returns the path the route matched by ServeHTTP for the
+// request of the given context was registered with, e.g. "/user/:name", or ""
+// if there is none. It is available to the middleware added by Use and to the
+// handles, e.g. to label traces and metrics by route. Handlers wrapping the
+// router use CapturePattern instead.
+func MatchedPattern(ctx context.Context) string {
+	if rt := RouteFromContext(ctx); rt != nil {
+		return rt.Path
+	}
+	return ""
+}
+
+// PatternCapture receives the pattern of the route matched by ServeHTTP for
+// a handler wrapping the router, see CapturePattern.
+type PatternCapture struct {
+	pattern string
+}
+
+// Pattern returns the path the matched route was registered with, also if
+// the request was rejected afterwards, e.g. for invalid parameter values, or
+// "" if the request matched no route or wasn't served yet.
+func (c *PatternCapture) Pattern() string {
+	return c.pattern
+}
+
+type patternCaptureKey struct{}
+
+// CapturePattern returns a copy of ctx making ServeHTTP store the pattern of
+// the route matched for a request with the context in the returned
+// PatternCapture, for handlers wrapping the router which read it once the
+// router returned, e.g.
+//
+//	ctx, capture := xrouter.CapturePattern(req.Context())
+//	router.ServeHTTP(w, req.WithContext(ctx))
+//	requests.WithLabelValues(capture.Pattern()).Inc()
+func CapturePattern(ctx context.Context) (context.Context, *PatternCapture) {
+	c := new(PatternCapture)
+	return context.WithValue(ctx, patternCaptureKey{}, c), c
+}
+
+// capturePattern stores the pattern of rt in the PatternCapture of ctx, if
+// any.
+func capturePattern(ctx context.Context, rt *Route) {
+	if c, ok := ctx.Value(patternCaptureKey{}).(*PatternCapture); ok {
+		c.pattern = rt.Path
+	}
+}
+
+// PatternFor returns the path the route matching method and path was
+// registered with, like LookupRoute, but without allocating the Params. The
+// second return value is false if no route matches.
+func (r *Router) PatternFor(method, path string) (string, bool) {
+	var start time.Time
+	if r.onLookup != nil {
+		start = time.Now()
+	}
+	lookupPath := path
+	if l := r.locales.Load(); l != nil {
+		_, lookupPath = l.split(path)
+	}
+	var buf [paramsBufSize]Param
+	rt, _, tsr := r.find(method, lookupPath, buf[:0], false)
+	if r.onLookup != nil {
+		r.onLookup(newLookupEvent(method, path, rt, tsr, start))
+	}
+	if rt == nil {
+		return "", false
+	}
+	return rt.Path, true
+}
@@ -0,0 +1,65 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchedPattern(t *testing.T) {
+	router := New()
+	var inner, middleware string
+	router.GET("/user/:name", http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		inner = MatchedPattern(req.Context())
+	}))
+	router.Use(func(next Handle) Handle {
+		return func(w http.ResponseWriter, req *http.Request, ps Params) {
+			middleware = MatchedPattern(req.Context())
+			next(w, req, ps)
+		}
+	})
+
+	// wrapping the router
+	var outer string
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx, capture := CapturePattern(req.Context())
+		router.ServeHTTP(w, req.WithContext(ctx))
+		outer = capture.Pattern()
+	})
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/gopher", nil))
+	if inner != "/user/:name" || middleware != "/user/:name" || outer != "/user/:name" {
+		t.Errorf("wrong patterns: %q, %q, %q", inner, middleware, outer)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+	if outer != "" {
+		t.Errorf("pattern captured for a miss: %q", outer)
+	}
+	if p := MatchedPattern(context.Background()); p != "" {
+		t.Errorf("pattern without match: %q", p)
+	}
+}
+
+func TestRouterPatternFor(t *testing.T) {
+	router := New()
+	router.GET("/user/:name", "user")
+	router.GET("/src/*filepath", "src")
+
+	for path, want := range map[string]string{
+		"/user/gopher": "/user/:name",
+		"/src/a/b.go":  "/src/*filepath",
+		"/nope":        "",
+	} {
+		if pattern, ok := router.PatternFor("GET", path); pattern != want || ok != (want != "") {
+			t.Errorf("PatternFor(%s) = %q, %v, want %q", path, pattern, ok, want)
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() { router.PatternFor("GET", "/user/gopher") }); allocs != 0 {
+		t.Errorf("PatternFor allocates: %v", allocs)
+	}
+}
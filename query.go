@@ -0,0 +1,136 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// queryRoute is a handle of a route which is only served to requests with
+// the given query parameter value.
+type queryRoute struct {
+	key, value string
+	handle     interface{}
+}
+
+// queryPattern identifies a route by the method and path it was registered
+// with.
+type queryPattern struct {
+	method, path string
+}
+
+// queryRoutes maps routes to their query handles. A map is never modified
+// once stored in Router.queries.
+type queryRoutes map[queryPattern][]queryRoute
+
+// HandleQuery registers a handle for the route of the given method and path
+// which ServeHTTP invokes instead of the handle of the route if the first value
+// of the query parameter key of the request equals value, e.g. to serve
+// "/search?type=a" and "/search?type=b" by different handles. If several
+// handles of the route match, the first registered one is used. Requests
+// matching none of them are served by the handle of the route.
+// If no route is registered for the method and path yet, HandleQuery registers
+// one responding with 404 Not Found, whose handle can be set with Replace.
+// Lookups are unaffected, they return the handle of the route.
+func (r *Router) HandleQuery(method, path, key, value string, handle interface{}) error {
+	method = r.normalizeMethod(method)
+	if r.matchRoute(method, path) == nil {
+		// the route may have been registered concurrently in the meantime
+		err := r.Handle(method, path, http.NotFoundHandler())
+		if err != nil && !(errors.Is(err, ErrConflict) && r.matchRoute(method, path) != nil) {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.immutable.Load() {
+		return ErrFrozen
+	}
+
+	pattern := queryPattern{method, path}
+	old := r.queries.Load()
+	var routes []queryRoute
+	if old != nil {
+		routes = (*old)[pattern]
+	}
+	for _, qr := range routes {
+		if qr.key == key && qr.value == value {
+			return fmt.Errorf("a handle is already registered for query '%s=%s' of path '%s'", key, value, path)
+		}
+	}
+
+	queries := make(queryRoutes)
+	if old != nil {
+		for p, routes := range *old {
+			queries[p] = routes
+		}
+	}
+	queries[pattern] = append(routes[:len(routes):len(routes)], queryRoute{key: key, value: value, handle: handle})
+	r.queries.Store(&queries)
+	return nil
+}
+
+// matchRoute returns the route registered for exactly the given method and
+// path, or nil if there is none.
+func (r *Router) matchRoute(method, path string) *Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t := r.tree(method)
+	if t == nil || t.root == nil {
+		return nil
+	}
+	if n := t.root.findNode(path); n != nil {
+		return n.data.(*Route)
+	}
+	return nil
+}
+
+// removeQueries drops the query handles of the route of the given method and
+// path.
+func (r *Router) removeQueries(method, path string) {
+	old := r.queries.Load()
+	if old == nil {
+		return
+	}
+	pattern := queryPattern{method, path}
+	if _, ok := (*old)[pattern]; !ok {
+		return
+	}
+	queries := make(queryRoutes, len(*old))
+	for p, routes := range *old {
+		if p != pattern {
+			queries[p] = routes
+		}
+	}
+	r.queries.Store(&queries)
+}
+
+// queryHandle returns the handle serving req for the matched route rt,
+// wrapped by the middleware of the route.
+func (r *Router) queryHandle(rt *Route, req *http.Request) interface{} {
+	queries := r.queries.Load()
+	if queries == nil {
+		return rt.serveHandle()
+	}
+	routes := (*queries)[queryPattern{rt.Method, rt.Path}]
+	if len(routes) == 0 {
+		return rt.serveHandle()
+	}
+	query := req.URL.Query()
+	for _, qr := range routes {
+		if values, ok := query[qr.key]; ok && values[0] == qr.value {
+			if rt.chain != nil {
+				return rt.wrap(r, qr.handle)
+			}
+			return qr.handle
+		}
+	}
+	return rt.serveHandle()
+}
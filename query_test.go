@@ -0,0 +1,109 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestRouterHandleQuery(t *testing.T) {
+	respond := func(body string) Handle {
+		return func(w http.ResponseWriter, _ *http.Request, ps Params) {
+			io.WriteString(w, body+ps.ByName("lang"))
+		}
+	}
+
+	router := New()
+	router.GET("/search", respond("any"))
+	if err := router.HandleQuery("GET", "/search", "type", "a", respond("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.HandleQuery("GET", "/search", "type", "b", respond("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.HandleQuery("GET", "/search", "type", "b", respond("b2")); err == nil {
+		t.Error("expected error for duplicate query handle")
+	}
+	if err := router.HandleQuery("GET", "/search", "empty", "", respond("empty")); err != nil {
+		t.Fatal(err)
+	}
+
+	// no query-agnostic registration
+	if err := router.HandleQuery("GET", "/docs/:lang", "format", "pdf", respond("pdf ")); err != nil {
+		t.Fatal(err)
+	}
+
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		tests := []struct {
+			path string
+			code int
+			body string
+		}{
+			{"/search?type=a", http.StatusOK, "a"},
+			{"/search?q=go&type=b", http.StatusOK, "b"},
+			{"/search?type=b&type=a", http.StatusOK, "b"},
+			{"/search?type=c", http.StatusOK, "any"},
+			{"/search", http.StatusOK, "any"},
+			{"/search?empty=", http.StatusOK, "empty"},
+			{"/docs/en?format=pdf", http.StatusOK, "pdf en"},
+			{"/docs/en", http.StatusNotFound, "404 page not found\n"},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+			if w.Code != test.code || w.Body.String() != test.body {
+				t.Errorf("%s: got %d %q; want %d %q", test.path, w.Code, w.Body.String(), test.code, test.body)
+			}
+		}
+	})
+
+	// the handle of a route registered by HandleQuery can be set later
+	if err := router.Replace("GET", "/docs/:lang", respond("html ")); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/docs/de", nil))
+	if w.Body.String() != "html de" {
+		t.Errorf("wrong body after replacing the handle: %q", w.Body.String())
+	}
+
+	// removing the route drops its query handles
+	router.ConcurrentRegistration = true
+	router.Remove("GET", "/search")
+	router.GET("/search", respond("new"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/search?type=a", nil))
+	if w.Body.String() != "new" {
+		t.Errorf("query handle not removed: %q", w.Body.String())
+	}
+}
+
+func TestRouterHandleQueryConcurrent(t *testing.T) {
+	for round := 0; round < 100; round++ {
+		router := New()
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(value string) {
+				defer wg.Done()
+				<-start
+				if err := router.HandleQuery("GET", "/search", "type", value, "search "+value); err != nil {
+					t.Errorf("registering query handle %s failed: %v", value, err)
+				}
+			}(strconv.Itoa(i))
+		}
+		close(start)
+		wg.Wait()
+		if routes := (*router.queries.Load())[queryPattern{"GET", "/search"}]; len(routes) != 8 {
+			t.Fatalf("wrong number of query handles: %d", len(routes))
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+)
+
+// EnableHitSampling makes the router count the lookups matching each route
+// in the tree, sampling on average one in n lookups to keep the overhead low,
+// for use by Rebalance. The counts are kept across calls of Rebalance.
+// A rate of 0 disables the sampling and drops the counts.
+// EnableHitSampling must not be called while the router serves requests.
+func (r *Router) EnableHitSampling(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n <= 0 {
+		r.sampler = nil
+		return
+	}
+	r.sampler = &hitSampler{n: uint32(n)}
+}
+
+// Rebalance reorders the children of each node of the trees by the number of
+// sampled lookups passing through them, see EnableHitSampling, instead of
+// the number of routes registered below them. Lookups of the most requested
+// routes then check the fewest siblings on their way down the tree.
+// Registering routes afterwards moves the affected nodes back in the order of
+// their number of routes. Rebalance is safe to call while the router serves
+// requests, which are blocked until it returns. Without sampling it does
+// nothing, and for routers using ImplBinarySearch it doesn't affect lookups.
+func (r *Router) Rebalance() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.immutable.Load() {
+		return
+	}
+
+	s := r.sampler
+	if s == nil {
+		return
+	}
+	r.eachTree(func(_ string, t *methodTree) {
+		s.rebalance(t.root)
+		r.relayout(t)
+	})
+}
+
+// hitSampler counts the sampled lookups per route.
+type hitSampler struct {
+	n    uint32
+	hits sync.Map // *Route -> *atomic.Uint64
+}
+
+// sample counts a lookup matching rt with a probability of 1/n.
+func (s *hitSampler) sample(rt *Route) {
+	if s.n > 1 && rand.Uint32N(s.n) != 0 {
+		return
+	}
+	c, ok := s.hits.Load(rt)
+	if !ok {
+		c, _ = s.hits.LoadOrStore(rt, new(atomic.Uint64))
+	}
+	c.(*atomic.Uint64).Add(1)
+}
+
+func (s *hitSampler) count(data interface{}) uint64 {
+	if data == nil {
+		return 0
+	}
+	c, ok := s.hits.Load(data)
+	if !ok {
+		return 0
+	}
+	return c.(*atomic.Uint64).Load()
+}
+
+// rebalance orders the indexed children of each node below n by their hits
+// and returns the hits of all routes below n.
+func (s *hitSampler) rebalance(n *node) uint64 {
+	hits := s.count(n.data)
+	weights := make([]uint64, len(n.children))
+	for i, child := range n.children {
+		weights[i] = s.rebalance(child)
+		hits += weights[i]
+	}
+	if len(n.indices) < 2 {
+		return hits
+	}
+
+	// stable insertion sort, so children without hits keep their order
+	indices := []byte(n.indices)
+	for i := 1; i < len(indices); i++ {
+		for j := i; j > 0 && weights[j] > weights[j-1]; j-- {
+			weights[j], weights[j-1] = weights[j-1], weights[j]
+			indices[j], indices[j-1] = indices[j-1], indices[j]
+			n.children[j], n.children[j-1] = n.children[j-1], n.children[j]
+		}
+	}
+	n.indices = string(indices)
+	return hits
+}
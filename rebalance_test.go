@@ -0,0 +1,101 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRouterRebalance(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		router := newSkewedRouter()
+		reference := newSkewedRouter()
+		if compact {
+			router.Compact()
+		}
+
+		// without sampling there is nothing to rebalance by
+		router.Rebalance()
+		if root := router.tree("GET").root; root.indices[0] != 'a' {
+			t.Fatalf("rebalanced without sampling: %q", root.indices)
+		}
+
+		router.EnableHitSampling(1)
+		paths := skewedPaths()
+		for _, path := range paths {
+			router.Lookup("GET", path)
+		}
+		router.Rebalance()
+
+		root := router.tree("GET").root
+		if root.indices[0] != 't' {
+			t.Errorf("hottest child not first: %q", root.indices)
+		}
+		if got, want := siblingChecks(root, "/t/r0/42"), 1; got != want {
+			t.Errorf("wrong number of checks for the hottest path: got %d, want %d", got, want)
+		}
+		if compact && router.tree("GET").compact == nil {
+			t.Error("compact tree dropped")
+		}
+
+		paths = append(paths, "/a/r0/42/", "/t/r1/42", "/u/r0/42")
+		for _, path := range paths {
+			handle, ps, tsr := reference.Lookup("GET", path)
+			gotHandle, gotPs, gotTsr := router.Lookup("GET", path)
+			// the handles are all the same func, which isn't comparable
+			if (gotHandle == nil) != (handle == nil) || !reflect.DeepEqual(gotPs, ps) || gotTsr != tsr {
+				t.Errorf("wrong result for %s after rebalancing: got %v, %v; want %v, %v", path, gotPs, gotTsr, ps, tsr)
+			}
+		}
+	}
+}
+
+func TestRouterRebalanceStatic(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		router := New()
+		for c := 'a'; c <= 't'; c++ {
+			router.GET("/"+string(c)+"/static", benchHandle)
+		}
+		router.EnableHitSampling(1)
+		if cache {
+			router.EnableLookupCache(16)
+		}
+		for i := 0; i < 100; i++ {
+			router.Lookup("GET", "/t/static")
+		}
+		router.Lookup("GET", "/a/static")
+		router.Rebalance()
+
+		if root := router.tree("GET").root; root.indices[0] != 't' {
+			t.Errorf("hottest static route not first (cache %t): %q", cache, root.indices)
+		}
+	}
+}
+
+func TestRouterRebalanceConcurrent(t *testing.T) {
+	router := newSkewedRouter()
+	router.EnableHitSampling(2)
+	paths := skewedPaths()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, path := range paths {
+				if handle, _, _ := router.Lookup("GET", path); handle == nil {
+					t.Errorf("no handle for %s", path)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		router.Rebalance()
+	}
+	wg.Wait()
+}
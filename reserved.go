@@ -0,0 +1,77 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	reservedMu     sync.RWMutex
+	reservedParams = make(map[string]bool)
+)
+
+// ReserveParam reserves key for Params added by a framework built on the
+// router, so no route can declare a wildcard of that name and the values
+// can't be confused: registering such a route fails with ErrReservedParam.
+// The reservation applies to all routers. It should be made before routes
+// are registered, e.g. in an init function.
+func ReserveParam(key string) {
+	reservedMu.Lock()
+	defer reservedMu.Unlock()
+
+	reservedParams[key] = true
+}
+
+// ReservedParams returns the keys reserved by ReserveParam, sorted. Routers
+// configured with LocalePrefix reserve LocaleParam in addition.
+func ReservedParams() []string {
+	reservedMu.RLock()
+	defer reservedMu.RUnlock()
+
+	keys := make([]string, 0, len(reservedParams))
+	for key := range reservedParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isReserved reports whether routes of r may not declare a wildcard named
+// key.
+func (r *Router) isReserved(key string) bool {
+	if key == LocaleParam && r.locales.Load() != nil {
+		return true
+	}
+	reservedMu.RLock()
+	defer reservedMu.RUnlock()
+
+	return reservedParams[key]
+}
+
+// checkReserved verifies that path declares no reserved wildcard name. The
+// wildcards must be well-formed, see checkPath.
+func (r *Router) checkReserved(path string) error {
+	segment := -1
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '/':
+			segment++
+		case ':', '*':
+			end := i + 1
+			for end < len(path) && path[end] != '/' {
+				end++
+			}
+			if name := path[i+1 : end]; r.isReserved(name) {
+				return &PathError{Path: path, Segment: segment, Offset: i, Err: ErrReservedParam,
+					msg: fmt.Sprintf("parameter name '%s' is reserved", name)}
+			}
+			i = end - 1
+		}
+	}
+	return nil
+}
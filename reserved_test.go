@@ -0,0 +1,68 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReserveParam(t *testing.T) {
+	const key = "_matched"
+	ReserveParam(key)
+	defer func() {
+		reservedMu.Lock()
+		delete(reservedParams, key)
+		reservedMu.Unlock()
+	}()
+
+	found := false
+	for _, reserved := range ReservedParams() {
+		found = found || reserved == key
+	}
+	if !found {
+		t.Errorf("%s not listed in %v", key, ReservedParams())
+	}
+
+	router := New()
+	for _, path := range []string{"/users/:_matched", "/files/*_matched"} {
+		err := router.GET(path, "h")
+		var pathErr *PathError
+		if !errors.Is(err, ErrReservedParam) || !errors.As(err, &pathErr) || pathErr.Offset != 7 || pathErr.Segment != 1 {
+			t.Errorf("wrong error for %s: %v", path, err)
+		}
+	}
+	if err := router.GET("/users/:_matchedx", "h"); err != nil {
+		t.Errorf("unreserved name rejected: %v", err)
+	}
+}
+
+func TestRouterLocaleParamReserved(t *testing.T) {
+	router := New()
+	router.GET("/docs/:lang", "before")
+	router.LocalePrefix([]string{"en", "de"})
+	if err := router.GET("/pages/:lang", "h"); !errors.Is(err, ErrReservedParam) {
+		t.Errorf("wrong error for %s: %v", LocaleParam, err)
+	}
+
+	// only the locale is returned under the key
+	_, ps, _ := router.Lookup("GET", "/de/docs/fr")
+	if len(ps) != 1 || ps[0] != (Param{LocaleParam, "de"}) {
+		t.Errorf("wrong params: %v", ps)
+	}
+	var visited Params
+	router.LookupFunc("GET", "/de/docs/fr", func(key, value string) {
+		visited = append(visited, Param{key, value})
+	})
+	if !reflect.DeepEqual(visited, ps) {
+		t.Errorf("wrong params visited: got %v, want %v", visited, ps)
+	}
+
+	// without locales the name is free
+	if err := New().GET("/pages/:lang", "h"); err != nil {
+		t.Error(err)
+	}
+}
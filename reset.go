@@ -0,0 +1,42 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+// Reset removes all routes of the router along with what was registered for
+// them: subtree defaults, query handles, StrictSlash settings, the lookup and
+// hit counts, the sampled hits and the cached lookups. The route tables, see
+// Table, are kept but reset as well. The router is then as
+// if none were registered yet, e.g. to load a changed route configuration in
+// a long-lived process, and accepts registrations again even if it was looked
+// up before, see ConcurrentRegistration.
+// Settings are kept: the exported fields, the middleware added by Use and
+// UseFor, which applies to the routes registered afterwards, and those made
+// by methods like OnLookup, EnableLookupCache and EnableHitSampling.
+// Reset is safe to call while the router serves requests, which miss once it
+// returns. It does nothing for frozen routers.
+func (r *Router) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.immutable.Load() {
+		return
+	}
+
+	r.trees = [len(standardMethods)]*methodTree{}
+	r.otherTrees = nil
+	r.paramNames = nil
+	r.paths = nil
+	r.internedBytes = 0
+	r.defaults = nil
+	r.queries.Store(nil)
+	if r.sampler != nil {
+		r.sampler = &hitSampler{n: r.sampler.n}
+	}
+	r.sealed.Store(false)
+	r.invalidateCache()
+	for _, table := range r.tables {
+		table.Reset()
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterReset(t *testing.T) {
+	router := New()
+	router.EnableLookupCache(16)
+	router.GET("/", "index")
+	router.GET("/user/:name", "user")
+	router.Handle("PURGE", "/cache/*key", "purge")
+	router.SubtreeDefault("/docs/", "docs")
+	router.HandleQuery("GET", "/search", "type", "a", "searchA")
+	router.StrictSlash("GET", true)
+	var wrapped bool
+	router.Use(func(next Handle) Handle {
+		return func(w http.ResponseWriter, req *http.Request, ps Params) {
+			wrapped = true
+			next(w, req, ps)
+		}
+	})
+	// seal the router and fill the cache
+	router.Lookup("GET", "/user/gopher")
+
+	router.Reset()
+
+	if routes := router.Routes(); len(routes) != 0 {
+		t.Errorf("routes left after Reset: %v", routes)
+	}
+	if stats := router.Stats(); stats.InternedBytes != 0 {
+		t.Errorf("interned paths left after Reset: %d bytes", stats.InternedBytes)
+	}
+
+	// the router accepts routes again, including ones conflicting with the
+	// removed routes
+	if err := router.GET("/user/:id", "user2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.GET("/about/", "about"); err != nil {
+		t.Fatal(err)
+	}
+	router.GET("/hello", func(w http.ResponseWriter, req *http.Request, _ Params) {})
+	for _, route := range []struct{ method, path string }{
+		{"GET", "/"},
+		{"PURGE", "/cache/x"},
+		{"GET", "/docs/intro"},
+		{"GET", "/search"},
+	} {
+		if handle, _, tsr := router.Lookup(route.method, route.path); handle != nil || tsr {
+			t.Errorf("%s %s still matches after Reset: %v, %v", route.method, route.path, handle, tsr)
+		}
+	}
+	handle, ps, _ := router.Lookup("GET", "/user/gopher")
+	if handle != "user2" || ps.ByName("id") != "gopher" {
+		t.Errorf("wrong result for a route registered after Reset: %v, %v", handle, ps)
+	}
+	// trailing slashes are no longer strict
+	if _, _, tsr := router.Lookup("GET", "/about"); !tsr {
+		t.Error("no trailing slash redirect after Reset")
+	}
+
+	// middleware applies to the new routes
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/hello", nil))
+	if !wrapped {
+		t.Error("middleware not applied after Reset")
+	}
+}
+
+func TestRouterResetFrozen(t *testing.T) {
+	router := New()
+	router.GET("/", "index")
+	router.Freeze()
+	router.Reset()
+	if handle, _, _ := router.Lookup("GET", "/"); handle != "index" {
+		t.Errorf("Reset modified a frozen router: %v", handle)
+	}
+}
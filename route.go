@@ -0,0 +1,426 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// Handle is a function that can be registered to a route to handle HTTP
+// requests. Like http.HandlerFunc, but has a third parameter for the values of
+// wildcards (variables).
+type Handle func(http.ResponseWriter, *http.Request, Params)
+
+// HandleErr is a Handle which can fail. ServeHTTP passes the returned error
+// through the mappers added by UseError to the ErrorHandler of the router.
+type HandleErr func(http.ResponseWriter, *http.Request, Params) error
+
+// Placeholder can be registered instead of a handle to reserve a path: routes
+// conflicting with it can't be registered, but lookups of the path miss as if
+// no route was registered. Replace sets the handle later.
+type Placeholder struct{}
+
+// RouteOptions holds optional per-route settings, see Router.HandleOptions.
+type RouteOptions struct {
+	// Validate maps parameter names to validation functions. The validators
+	// run after the route has been matched, so a failing validation does not
+	// fall through to other routes: ServeHTTP responds with 400 Bad Request
+	// and the validator's error message instead of invoking the handle.
+	Validate map[string]func(string) error
+
+	// Match maps parameter names to predicates the values must satisfy for
+	// the route to match, e.g. a checksum test of an id. Unlike Validate,
+	// they are checked by lookups: if a predicate rejects its value, the
+	// lookup misses as if the route was not registered, without trying other
+	// routes. The predicates receive the values before decoding, see
+	// Router.DecodeParams.
+	Match map[string]func(string) bool
+
+	// Tags hold arbitrary metadata of the route, e.g. the scopes a request
+	// needs to be authorized for. Middleware can read them from the route
+	// returned by RouteFromContext.
+	Tags []string
+
+	// Name identifies the route for people, e.g. "user.show", see
+	// Router.FormatRoutes.
+	Name string
+
+	// Summary describes the route in a few words, e.g. "Show a user", see
+	// Router.OpenAPIPaths.
+	Summary string
+
+	// Source identifies the component registering the route, e.g. a plugin,
+	// see Route.Source.
+	Source string
+
+	// EmptyCatchAll makes the catch-all of the route also match if nothing
+	// follows the path before it, e.g. "/api/*rest" matches "/api" with an
+	// empty value of rest instead of redirecting to "/api/". The values for
+	// "/api/" and "/api/x" are "/" and "/x" as usual. A route registered for
+	// the path before the catch-all takes precedence.
+	EmptyCatchAll bool
+
+	// MinCatchAllSegments is the number of non-empty segments the value of
+	// the catch-all of the route must have at least for the route to match,
+	// e.g. with 1 "/assets/*path" matches "/assets/x" but not "/assets/".
+	// Like a rejecting predicate of Match, an unmet minimum makes the lookup
+	// miss, so ServeHTTP responds with Router.NotFound. It can only be set
+	// for routes ending with a catch-all.
+	MinCatchAllSegments int
+
+	// Schemes restricts the route to requests with one of the given
+	// schemes, e.g. "https", compared case-insensitively. The scheme of a
+	// request is determined by Router.SchemeHeader. ServeHTTP passes requests
+	// with other schemes to Router.SchemeMismatch. Lookups are unaffected.
+	Schemes []string
+
+	// Middleware is applied by ServeHTTP around the handle of the route,
+	// inside the middleware added by Router.Use and Router.UseFor. The first
+	// middleware is the outermost. The chain is composed at registration, see
+	// Group.
+	Middleware []Middleware
+
+	// Push lists resources ServeHTTP pushes to the client before invoking the
+	// handle, if the connection supports HTTP/2 server push, see http.Pusher.
+	// Each target must be an absolute path or URL as expected by Push.
+	Push []string
+
+	// ContextValues are added to the context of requests matching the route,
+	// e.g. the tenant scope or the permission the request requires, so any
+	// middleware can read them with the Value method of the context
+	// without knowing about routes. The keys must be comparable, see
+	// context.WithValue. The map must not be modified after registration.
+	ContextValues map[interface{}]interface{}
+}
+
+// Route is a registered route, holding the method and path it was registered
+// with, the handle and its options.
+// A Route must not be modified after registration.
+type Route struct {
+	Method  string
+	Path    string
+	Handle  interface{}
+	Options RouteOptions
+
+	// Source is where the route was registered: the Source option if set,
+	// else the function calling the router with its file and line, unless
+	// disabled by Router.DisableCallerSource. Errors for conflicting routes
+	// name the sources of both.
+	Source string
+
+	// Handle wrapped by the middleware of the options, see compose
+	chain Handle
+
+	// middleware of the router applying to the route followed by the
+	// middleware of the options, outermost first
+	middleware []Middleware
+
+	// requests matching the route, see HitCounts, and lookups matching it,
+	// see Counters; shared by the copies made by Replace
+	hits, lookups *atomic.Uint64
+}
+
+// compose wraps the handle of the route by the middleware added by UseFor
+// for its path and the middleware of its options.
+func (rt *Route) compose(r *Router) {
+	rt.chain = nil
+	rt.middleware = nil
+	for _, s := range r.scoped {
+		if globMatch(s.glob, rt.Path) {
+			rt.middleware = append(rt.middleware, s.mw...)
+		}
+	}
+	if rt.middleware == nil {
+		rt.middleware = rt.Options.Middleware
+	} else {
+		rt.middleware = append(rt.middleware, rt.Options.Middleware...)
+	}
+	if len(rt.middleware) > 0 {
+		rt.chain = rt.wrap(r, rt.Handle)
+	}
+}
+
+// wrap returns handle wrapped by the middleware of the route, see compose.
+func (rt *Route) wrap(r *Router, handle interface{}) Handle {
+	h := r.toHandle(handle)
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		h = rt.middleware[i](h)
+	}
+	return h
+}
+
+// Validate runs the validators of the route against the given parameter
+// values. It returns the first error in the order of the parameters.
+func (rt *Route) Validate(ps Params) error {
+	if len(rt.Options.Validate) == 0 {
+		return nil
+	}
+	for i := range ps {
+		if validate := rt.Options.Validate[ps[i].Key]; validate != nil {
+			if err := validate(ps[i].Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// serveHandle returns the handle of the route wrapped by its middleware.
+func (rt *Route) serveHandle() interface{} {
+	if rt.chain != nil {
+		return rt.chain
+	}
+	return rt.Handle
+}
+
+// reserved reports whether the route only reserves its path, see Placeholder.
+func (rt *Route) reserved() bool {
+	_, ok := rt.Handle.(Placeholder)
+	return ok
+}
+
+// push pushes the resources of the route to the client if w supports it.
+// Failing pushes are ignored, the client requests the resources itself then.
+func (rt *Route) push(w http.ResponseWriter) {
+	if len(rt.Options.Push) == 0 {
+		return
+	}
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return
+	}
+	for _, target := range rt.Options.Push {
+		if pusher.Push(target, nil) == http.ErrNotSupported {
+			return
+		}
+	}
+}
+
+// checkOptions verifies that the options only refer to parameters declared
+// in the path of the route.
+func (rt *Route) checkOptions() error {
+	for name := range rt.Options.Validate {
+		if !hasParam(rt.Path, name) {
+			return fmt.Errorf("validator for unknown parameter '%s' in path '%s'", name, rt.Path)
+		}
+	}
+	for name := range rt.Options.Match {
+		if !hasParam(rt.Path, name) {
+			return fmt.Errorf("predicate for unknown parameter '%s' in path '%s'", name, rt.Path)
+		}
+	}
+	if min := rt.Options.MinCatchAllSegments; min < 0 {
+		return fmt.Errorf("negative minimum of catch-all segments %d in path '%s'", min, rt.Path)
+	} else if min > 0 && !strings.Contains(rt.Path, "/*") {
+		return fmt.Errorf("minimum of catch-all segments for path '%s' without catch-all", rt.Path)
+	}
+	return nil
+}
+
+// matches reports whether the parameter values satisfy the predicates of the
+// route, see RouteOptions.Match, and the value of its catch-all has enough
+// segments, see RouteOptions.MinCatchAllSegments.
+func (rt *Route) matches(ps Params) bool {
+	if min := rt.Options.MinCatchAllSegments; min > 0 && len(ps) > 0 {
+		// the catch-all is always the last parameter
+		if segments(ps[len(ps)-1].Value) < min {
+			return false
+		}
+	}
+	if len(rt.Options.Match) == 0 {
+		return true
+	}
+	for i := range ps {
+		if match := rt.Options.Match[ps[i].Key]; match != nil && !match(ps[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// segments returns the number of non-empty segments of path.
+func segments(path string) int {
+	n := 0
+	for i := 0; i < len(path); i++ {
+		if path[i] != '/' && (i == 0 || path[i-1] == '/') {
+			n++
+		}
+	}
+	return n
+}
+
+// hasParam reports whether path declares a wildcard with the given name.
+func hasParam(path, name string) bool {
+	for _, param := range paramNames(path) {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
+
+// paramNames returns the names of the wildcards declared in path, in order.
+func paramNames(path string) []string {
+	var names []string
+	for i := 0; i < len(path); i++ {
+		if path[i] != ':' && path[i] != '*' {
+			continue
+		}
+		end := i + 1
+		for end < len(path) && path[end] != '/' {
+			end++
+		}
+		names = append(names, path[i+1:end])
+		i = end
+	}
+	return names
+}
+
+// Segment is a path segment matched by a route.
+type Segment struct {
+	// the segment of the route's path, e.g. "users", ":id" or "*filepath"
+	Pattern string
+
+	// the matched segment of the request path. For a catch-all it holds the
+	// remainder of the path, without the leading '/' its Param value has.
+	Value string
+}
+
+// Segments returns the breakdown of path into the segments matched by the
+// static parts and wildcards of the route's path, in order, e.g. for
+// diagnosing why a request matched the route. It returns nil if path doesn't
+// match the route's path. Segments is not used by lookups; it splits path on
+// each call.
+func (rt *Route) Segments(path string) []Segment {
+	pattern := rt.Path
+	var segs []Segment
+	for pattern != "" {
+		if path == "" && rt.Options.EmptyCatchAll && strings.HasPrefix(pattern, "/*") {
+			return append(segs, Segment{Pattern: pattern[1:]})
+		}
+		if pattern[0] != '/' || path == "" || path[0] != '/' {
+			return nil
+		}
+		pattern, path = pattern[1:], path[1:]
+
+		pseg := pattern
+		if i := strings.IndexByte(pattern, '/'); i >= 0 {
+			pseg = pattern[:i]
+		}
+		// a catch-all begins a segment, a param may follow a static prefix
+		// within it like in "/con:tact"
+		wild := strings.IndexAny(pseg, ":*")
+		if wild == 0 && pseg[0] == '*' {
+			return append(segs, Segment{Pattern: pseg, Value: path})
+		}
+		seg := path
+		if i := strings.IndexByte(path, '/'); i >= 0 {
+			seg = path[:i]
+		}
+		if wild < 0 && seg != pseg || wild >= 0 && !strings.HasPrefix(seg, pseg[:wild]) {
+			return nil
+		}
+		segs = append(segs, Segment{Pattern: pseg, Value: seg})
+		pattern, path = pattern[len(pseg):], path[len(seg):]
+	}
+	if path != "" {
+		return nil
+	}
+	return segs
+}
+
+type routeKey struct{}
+
+// RouteFromContext returns the Route matched by Router.ServeHTTP for the
+// request of the given context, or nil if there is none.
+func RouteFromContext(ctx context.Context) *Route {
+	rt, _ := ctx.Value(routeKey{}).(*Route)
+	return rt
+}
+
+// matchContext carries the matched route, the parameter values and the
+// context values of the route for a request, saving a context per value.
+type matchContext struct {
+	context.Context
+	route  *Route
+	params Params
+}
+
+func (c *matchContext) Value(key interface{}) interface{} {
+	switch key.(type) {
+	case routeKey:
+		return c.route
+	case paramsKey:
+		if c.params == nil {
+			break
+		}
+		return c.params
+	}
+	if v, ok := c.route.Options.ContextValues[key]; ok {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+// serve invokes the handle with the given request and parameter values.
+// Handles not taking Params receive the values through the request context.
+// Handles of other types are passed to UnknownHandleType.
+func (r *Router) serve(handle interface{}, w http.ResponseWriter, req *http.Request, ps Params) {
+	switch h := handle.(type) {
+	case Handle:
+		h(w, req, ps)
+	case func(http.ResponseWriter, *http.Request, Params):
+		h(w, req, ps)
+	case HandleErr:
+		r.handleErr(h)(w, req, ps)
+	case http.Handler:
+		h.ServeHTTP(w, withParams(req, ps))
+	case func(http.ResponseWriter, *http.Request):
+		h(w, withParams(req, ps))
+	default:
+		if r.UnknownHandleType != nil {
+			r.UnknownHandleType(w, req, handle)
+			return
+		}
+		defaultErrorHandler(w, req, fmt.Errorf("unsupported handle type %T", handle))
+	}
+}
+
+// withParams returns req with ps stored in its context, see
+// ParamsFromContext.
+func withParams(req *http.Request, ps Params) *http.Request {
+	// the values are in the context already if set by ServeHTTP
+	if _, ok := req.Context().(*matchContext); !ok && len(ps) > 0 {
+		req = req.WithContext(context.WithValue(req.Context(), ParamsKey, ps))
+	}
+	return req
+}
+
+// pkgPrefix prefixes the names of the functions of this package.
+var pkgPrefix = reflect.TypeOf(Route{}).PkgPath() + "."
+
+// callerSource returns the first function outside of this package on the
+// stack, with its file and line, as source of a route.
+func callerSource() string {
+	var pcs [16]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		// tests of the package register routes too
+		if !strings.HasPrefix(frame.Function, pkgPrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s (%s:%d)", frame.Function, filepath.Base(frame.File), frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,383 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func checkULID(s string) error {
+	if len(s) != 26 {
+		return errors.New("invalid ULID")
+	}
+	return nil
+}
+
+func TestRouteValidate(t *testing.T) {
+	routed := false
+	router := New()
+	err := router.HandleOptions("GET", "/user/:name/item/:id", Handle(func(_ http.ResponseWriter, _ *http.Request, _ Params) {
+		routed = true
+	}), RouteOptions{
+		Validate: map[string]func(string) error{"id": checkULID},
+	})
+	if err != nil {
+		t.Fatalf("registering route failed: %v", err)
+	}
+
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		routed = false
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/user/gopher/item/42", nil))
+		if routed {
+			t.Fatal("handle invoked despite failing validation")
+		}
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("wrong status code: want %d, got %d", http.StatusBadRequest, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "invalid ULID") {
+			t.Errorf("validator error missing from body: %q", w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/user/gopher/item/01ARZ3NDEKTSV4RRFFQ69G5FAV", nil))
+		if !routed {
+			t.Fatal("handle not invoked for valid parameters")
+		}
+		if w.Code != http.StatusOK {
+			t.Errorf("wrong status code: want %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+}
+
+func TestLookupRouteValidators(t *testing.T) {
+	router := New()
+	router.HandleOptions("GET", "/item/:id", "item", RouteOptions{
+		Validate: map[string]func(string) error{"id": checkULID},
+	})
+
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		rt, ps, _ := router.LookupRoute("GET", "/item/42")
+		if rt == nil {
+			t.Fatal("Got no route!")
+		}
+		if rt.Path != "/item/:id" || rt.Handle != "item" {
+			t.Errorf("wrong route: %+v", rt)
+		}
+		if err := rt.Validate(ps); err == nil {
+			t.Error("expected validation error")
+		}
+	})
+}
+
+func TestRouteMatch(t *testing.T) {
+	router := New()
+	evenLength := func(id string) bool { return len(id)%2 == 0 }
+	if err := router.HandleMatch("GET", "/order/:id", "order", map[string]func(string) bool{"id": evenLength}); err != nil {
+		t.Fatal(err)
+	}
+	router.GET("/order/:id/items", "items")
+	if err := router.HandleMatch("GET", "/user/:name", "user", map[string]func(string) bool{"id": evenLength}); err == nil {
+		t.Error("no error for predicate of undeclared parameter")
+	}
+
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		for path, want := range map[string]interface{}{
+			"/order/42":        "order",
+			"/order/abcd":      "order",
+			"/order/123":       nil,
+			"/order/123/items": "items",
+		} {
+			if handle, _, _ := router.Lookup("GET", path); handle != want {
+				t.Errorf("wrong handle for %s: %v, want %v", path, handle, want)
+			}
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/order/123", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("wrong status code for a rejected value: %d", w.Code)
+		}
+	})
+}
+
+func TestRouteValidateUnknownParam(t *testing.T) {
+	router := New()
+	err := router.HandleOptions("GET", "/item/:id", "item", RouteOptions{
+		Validate: map[string]func(string) error{"name": checkULID},
+	})
+	if err == nil {
+		t.Fatal("no error for validator of undeclared parameter")
+	}
+	if handle, _, _ := router.Lookup("GET", "/item/42"); handle != nil {
+		t.Error("route registered despite invalid options")
+	}
+}
+
+type fakePusher struct {
+	http.ResponseWriter
+	pushed []string
+	err    error
+}
+
+func (p *fakePusher) Push(target string, opts *http.PushOptions) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func TestRoutePush(t *testing.T) {
+	var pushedBefore int
+	var w *fakePusher
+	router := New()
+	router.HandleOptions("GET", "/", Handle(func(http.ResponseWriter, *http.Request, Params) {
+		if w != nil {
+			pushedBefore = len(w.pushed)
+		}
+	}), RouteOptions{
+		Push: []string{"/app.css", "/app.js"},
+	})
+
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		// no-op without HTTP/2 push support
+		rec := httptest.NewRecorder()
+		w = nil
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("wrong status code without pusher: %d", rec.Code)
+		}
+
+		w = &fakePusher{ResponseWriter: httptest.NewRecorder()}
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if want := []string{"/app.css", "/app.js"}; !reflect.DeepEqual(w.pushed, want) {
+			t.Errorf("wrong pushes: got %v, want %v", w.pushed, want)
+		}
+		if pushedBefore != 2 {
+			t.Errorf("handle invoked after %d pushes, want 2", pushedBefore)
+		}
+
+		// push disabled by the client
+		w = &fakePusher{ResponseWriter: httptest.NewRecorder(), err: http.ErrNotSupported}
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if len(w.pushed) != 0 {
+			t.Errorf("got pushes: %v", w.pushed)
+		}
+	})
+}
+
+type scopeKey struct{}
+
+func TestRouteContextValues(t *testing.T) {
+	var got []interface{}
+	handle := http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		got = append(got, req.Context().Value(scopeKey{}))
+	})
+	router := New()
+	router.HandleOptions("GET", "/admin", handle, RouteOptions{
+		ContextValues: map[interface{}]interface{}{scopeKey{}: "admin"},
+	})
+	router.HandleOptions("GET", "/billing/:id", handle, RouteOptions{
+		ContextValues: map[interface{}]interface{}{scopeKey{}: "billing"},
+	})
+	router.GET("/public", handle)
+
+	// the values don't leak to the request of handlers wrapping the router
+	outer := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		router.ServeHTTP(w, req)
+		got = append(got, req.Context().Value(scopeKey{}))
+	})
+	for _, path := range []string{"/admin", "/billing/1", "/public", "/admin"} {
+		outer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	want := []interface{}{"admin", nil, "billing", nil, nil, nil, "admin", nil}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong values: got %v, want %v", got, want)
+	}
+}
+
+func TestRouteSegments(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		segs          []Segment
+	}{
+		{"/", "/", []Segment{{"", ""}}},
+		{"/users/:id/posts", "/users/42/posts", []Segment{{"users", "users"}, {":id", "42"}, {"posts", "posts"}}},
+		{"/users/:id/", "/users/42/", []Segment{{"users", "users"}, {":id", "42"}, {"", ""}}},
+		{"/files/:dir/*filepath", "/files/js/inc/app.js", []Segment{{"files", "files"}, {":dir", "js"}, {"*filepath", "inc/app.js"}}},
+		{"/src/*filepath", "/src/", []Segment{{"src", "src"}, {"*filepath", ""}}},
+		{"/users/:id", "/users/42/posts", nil},
+		{"/users/:id", "/teams/42", nil},
+		{"/users/:id/", "/users/42", nil},
+		{"/con:tact", "/contact", []Segment{{"con:tact", "contact"}}},
+		{"/con:tact/info", "/conference/info", []Segment{{"con:tact", "conference"}, {"info", "info"}}},
+		{"/con:tact", "/about", nil},
+		{"/v:version/*rest", "/v2/users/42", []Segment{{"v:version", "v2"}, {"*rest", "users/42"}}},
+		{"/*filepath", "/", []Segment{{"*filepath", ""}}},
+		{"/*filepath", "/css/app.css", []Segment{{"*filepath", "css/app.css"}}},
+		{"/src/*filepath", "/src", nil},
+		{"/src/*filepath", "/lib/app.js", nil},
+	}
+	for _, test := range tests {
+		rt := &Route{Path: test.pattern}
+		if segs := rt.Segments(test.path); !reflect.DeepEqual(segs, test.segs) {
+			t.Errorf("wrong segments of %s for %s: got %q, want %q", test.path, test.pattern, segs, test.segs)
+		}
+	}
+
+	// the paths with segments are matched by the route
+	for _, test := range tests {
+		if test.segs == nil {
+			continue
+		}
+		router := New()
+		if err := router.GET(test.pattern, test.pattern); err != nil {
+			t.Fatal(err)
+		}
+		if rt, _, _ := router.LookupRoute("GET", test.path); rt == nil {
+			t.Errorf("%s not matched by %s", test.path, test.pattern)
+		}
+	}
+
+	// through the route of a request
+	var segs []Segment
+	router := New()
+	router.GET("/users/:id/posts", Handle(func(_ http.ResponseWriter, req *http.Request, _ Params) {
+		segs = RouteFromContext(req.Context()).Segments(req.URL.Path)
+	}))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42/posts", nil))
+	if want := []Segment{{"users", "users"}, {":id", "42"}, {"posts", "posts"}}; !reflect.DeepEqual(segs, want) {
+		t.Errorf("wrong segments from handle: got %q, want %q", segs, want)
+	}
+}
+
+func TestRouteEmptyCatchAll(t *testing.T) {
+	router := New()
+	router.HandleOptions("GET", "/api/*rest", "api", RouteOptions{EmptyCatchAll: true})
+	router.GET("/files/*filepath", "files")
+	router.GET("/v1/*rest", "v1 rest")
+	router.GET("/v1", "v1")
+
+	forBoth(t, router, func(t *testing.T, r lookupRouter) {
+		tests := []struct {
+			path   string
+			handle interface{}
+			ps     Params
+			tsr    bool
+		}{
+			{"/api", "api", Params{{"rest", ""}}, false},
+			{"/api/", "api", Params{{"rest", "/"}}, false},
+			{"/api/x", "api", Params{{"rest", "/x"}}, false},
+			{"/files", nil, nil, true},
+			{"/files/", "files", Params{{"filepath", "/"}}, false},
+			{"/v1", "v1", nil, false},
+		}
+		for _, test := range tests {
+			handle, ps, tsr := r.Lookup("GET", test.path)
+			if handle != test.handle || !reflect.DeepEqual(ps, test.ps) || tsr != test.tsr {
+				t.Errorf("wrong result for %s: got %v, %v, %t", test.path, handle, ps, tsr)
+			}
+		}
+		if handle, ps := r.LookupNoTSR("GET", "/api"); handle != "api" || ps.ByName("rest") != "" || len(ps) != 1 {
+			t.Errorf("wrong result without TSR: %v, %v", handle, ps)
+		}
+	})
+
+	// not redirected
+	served := New()
+	served.HandleOptions("GET", "/api/*rest", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), RouteOptions{EmptyCatchAll: true})
+	w := httptest.NewRecorder()
+	served.ServeHTTP(w, httptest.NewRequest("GET", "/api", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("wrong response for /api: %d %v", w.Code, w.Header())
+	}
+
+	rt, _, _ := router.LookupRoute("GET", "/api")
+	if segs, want := rt.Segments("/api"), []Segment{{"api", "api"}, {"*rest", ""}}; !reflect.DeepEqual(segs, want) {
+		t.Errorf("wrong segments: got %q, want %q", segs, want)
+	}
+}
+
+func registerUsersPlugin(router *Router) error {
+	return router.GET("/users/:id", "users")
+}
+
+func registerAdminPlugin(router *Router) error {
+	return router.GET("/users/:id", "admin")
+}
+
+func TestRouteSource(t *testing.T) {
+	router := New()
+	if err := registerUsersPlugin(router); err != nil {
+		t.Fatal(err)
+	}
+	err := registerAdminPlugin(router)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	for _, name := range []string{"registerUsersPlugin", "registerAdminPlugin"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error does not name %s: %v", name, err)
+		}
+	}
+	if !strings.Contains(conflict.ExistingSource, "registerUsersPlugin (route_test.go:") ||
+		!strings.Contains(conflict.Source, "registerAdminPlugin (route_test.go:") {
+		t.Errorf("wrong sources: %q, %q", conflict.ExistingSource, conflict.Source)
+	}
+
+	// explicit sources
+	router = New()
+	router.HandleOptions("GET", "/a", "a", RouteOptions{Source: "plugin a"})
+	err = router.HandleOptions("GET", "/a", "b", RouteOptions{Source: "plugin b"})
+	if !strings.HasSuffix(err.Error(), "registered for GET by plugin a, new route registered by plugin b") {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	// without capture
+	router = New()
+	router.DisableCallerSource = true
+	router.GET("/a", "a")
+	rt, _, _ := router.LookupRoute("GET", "/a")
+	if rt.Source != "" {
+		t.Errorf("source captured: %q", rt.Source)
+	}
+}
+
+func TestRouteMinCatchAllSegments(t *testing.T) {
+	router := New()
+	if err := router.HandleOptions("GET", "/assets/*path", "assets", RouteOptions{MinCatchAllSegments: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.HandleOptions("GET", "/user/:name", "user", RouteOptions{MinCatchAllSegments: 1}); err == nil {
+		t.Error("no error for minimum without catch-all")
+	}
+
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		for path, want := range map[string]interface{}{
+			"/assets/":    nil,
+			"/assets//":   nil,
+			"/assets/x":   "assets",
+			"/assets/x/":  "assets",
+			"/assets/x/y": "assets",
+		} {
+			if handle, _, _ := router.Lookup("GET", path); handle != want {
+				t.Errorf("wrong handle for %s: %v, want %v", path, handle, want)
+			}
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/assets/", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("wrong status code for /assets/: %d", w.Code)
+		}
+	})
+}
@@ -76,7 +76,17 @@
 //  thirdValue := ps[2].Value // the value of the 3rd parameter
 package xrouter
 
-import "github.com/pkg/errors"
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
 
 // Param is a single URL parameter, consisting of a key and a value.
 type Param struct {
@@ -87,10 +97,16 @@ type Param struct {
 // Params is a Param-slice, as returned by the router.
 // The slice is ordered, the first URL parameter is also the first slice value.
 // It is therefore safe to read values by the index.
+//
+// The values are substrings of the path passed to the lookup, so capturing
+// even a long catch-all value costs nothing. They thereby keep the whole path
+// alive; use Clone to retain values independently of it.
 type Params []Param
 
 // ByName returns the value of the first Param which key matches the given name.
 // If no matching Param is found, an empty string is returned.
+// ByName never allocates. It scans ps in order, so its cost grows with the
+// position of the Param, which is cheap for the few Params of a route.
 func (ps Params) ByName(name string) string {
 	for i := range ps {
 		if ps[i].Key == name {
@@ -100,16 +116,349 @@ func (ps Params) ByName(name string) string {
 	return ""
 }
 
+// Get returns the value of the first Param which key matches the given name,
+// and whether such a Param exists. Unlike ByName, it tells a parameter with an
+// empty value apart from a missing one.
+func (ps Params) Get(name string) (string, bool) {
+	for i := range ps {
+		if ps[i].Key == name {
+			return ps[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// Require returns an error wrapping ErrMissingParam and naming the first of
+// the given names without a Param in ps, or nil if all are present, e.g. to
+// catch routes which no longer declare a parameter their handle reads.
+func (ps Params) Require(names ...string) error {
+	for _, name := range names {
+		if _, ok := ps.Get(name); !ok {
+			return fmt.Errorf("parameter '%s': %w", name, ErrMissingParam)
+		}
+	}
+	return nil
+}
+
+// Clone returns a copy of ps whose values are copied into new memory, sharing
+// nothing with the path they were captured from.
+func (ps Params) Clone() Params {
+	if ps == nil {
+		return nil
+	}
+	c := make(Params, len(ps))
+	copy(c, ps)
+	c.copyValues()
+	return c
+}
+
+// copyValues replaces the values of ps with copies, stored in a single string.
+func (ps Params) copyValues() {
+	var size int
+	for i := range ps {
+		size += len(ps[i].Value)
+	}
+	var b strings.Builder
+	b.Grow(size)
+	for i := range ps {
+		b.WriteString(ps[i].Value)
+	}
+	values := b.String()
+	for i := range ps {
+		n := len(ps[i].Value)
+		ps[i].Value, values = values[:n], values[n:]
+	}
+}
+
+// Validate runs the validator of rules for each Param with the key it is
+// mapped to, in the order of ps. Keys not present in ps are skipped.
+// Unlike Route.Validate it runs all validators, and returns an error listing
+// each failed Param along with its validator's error, or nil if all passed.
+func (ps Params) Validate(rules map[string]func(string) error) error {
+	var failed []string
+	for i := range ps {
+		if validate := rules[ps[i].Key]; validate != nil {
+			if err := validate(ps[i].Value); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", ps[i].Key, err))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("invalid parameters: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+type paramsKey struct{}
+
+// ParamsKey is the request context key under which URL params are stored.
+var ParamsKey = paramsKey{}
+
+// ParamsFromContext pulls the URL parameters from a request context,
+// or returns nil if none are present.
+func ParamsFromContext(ctx context.Context) Params {
+	p, _ := ctx.Value(ParamsKey).(Params)
+	return p
+}
+
+// ByNameFold is like ByName, but matches the key case-insensitively, using
+// strings.EqualFold. Like ByName, it does not allocate.
+func (ps Params) ByNameFold(name string) string {
+	for i := range ps {
+		if strings.EqualFold(ps[i].Key, name) {
+			return ps[i].Value
+		}
+	}
+	return ""
+}
+
 // Router is a http.Handler which can be used to dispatch requests to different
 // handler functions via configurable routes
 type Router struct {
-	trees map[string]*node
+	// guards the trees and the registration state; lookups hold a read lock
+	mu sync.RWMutex
+
+	// trees of the standard methods at their methodIndex, saving the map
+	// lookup for almost all requests, and of all other methods
+	trees      [len(standardMethods)]*methodTree
+	otherTrees map[string]*methodTree
+
+	// param names registered per method, used by StrictParamCase
+	paramNames map[string][]string
+
+	// the paths of all registered routes, see intern
+	paths         map[string]internedPath
+	internedBytes int
+
+	// If enabled, registering a route is rejected if it declares a parameter
+	// whose name differs only by case from another parameter name of the same
+	// method, e.g. ':userId' and ':userid'.
+	StrictParamCase bool
+
+	// If enabled, methods are matched case-sensitively. By default methods are
+	// upper-cased when registering routes and looking them up, so routes
+	// registered for "get" are found for "GET" requests.
+	CaseSensitiveMethods bool
+
+	// Maps non-canonical methods of incoming requests to the methods routes
+	// are registered for, e.g. "DEL" to "DELETE", before ServeHTTP looks up
+	// the route. The method of the request passed to the handle is left as
+	// is. Registration doesn't use the aliases.
+	MethodAliases map[string]string
+
+	// Derives the key of a request selecting the route table ServeHTTP
+	// dispatches it to among those created by Table, e.g. the tier of the
+	// API key in a header. Requests whose key names no table are served by
+	// the routes of the router itself, as are all requests if it is nil.
+	KeyFunc func(*http.Request) string
+
+	// If enabled, routes may be registered while the router serves requests,
+	// which the lock makes safe. Otherwise all routes must be registered
+	// before the first lookup; registering a route after it fails with
+	// ErrSealed, catching accidental registrations at runtime.
+	ConcurrentRegistration bool
+
+	// If enabled, a path may use a wildcard name twice, like in
+	// "/orgs/:id/repos/:id", of which ByName returns the first value.
+	// Otherwise such paths are rejected with ErrDuplicateParam.
+	AllowDuplicateParams bool
+
+	// If enabled, paths with whitespace, control characters, empty segments
+	// like in "/a//b" or invalid UTF-8 can be registered. Otherwise they are
+	// rejected with ErrInvalidPath, as they are usually mistakes.
+	PermissivePaths bool
+
+	// If enabled, handles matching none of the adapters registered with
+	// RegisterAdapter are stored as is instead of being rejected.
+	PermissiveHandles bool
+
+	// If enabled, routes registered without the Source option have no
+	// Route.Source, sparing the cost of looking up the caller at each
+	// registration.
+	DisableCallerSource bool
+
+	// set by the first lookup, see ConcurrentRegistration
+	sealed atomic.Bool
+
+	// how lookups find the children of nodes, see NewWithImpl
+	impl Impl
+
+	// If positive, requests capturing more parameter values are treated as
+	// if no route matched, bounding the memory used by the values of a
+	// request. The default 0 means no limit.
+	MaxParams int
+
+	// If positive, routes with more path segments or longer paths are
+	// rejected with ErrInvalidPath, and requests with such paths are treated
+	// as if no route matched without walking the tree. Requests for methods
+	// with catch-all routes are not limited in segments, the catch-all may
+	// match any number. New sets DefaultMaxSegments and DefaultMaxPathLength;
+	// 0 means no limit.
+	MaxSegments   int
+	MaxPathLength int
+
+	// Enables automatic redirection if the current route can't be matched but a
+	// handler for the path with (without) the trailing slash exists.
+	// For example if /foo/ is requested but a route only exists for /foo, the
+	// client is redirected to /foo with http status code 301 for GET requests
+	// and 307 for all other request methods. The query is kept.
+	// Methods set to StrictSlash are never redirected.
+	RedirectTrailingSlash bool
+
+	// If enabled, ServeHTTP serves HEAD requests without a matching HEAD
+	// route by the GET route of the path, if any, discarding the body but
+	// sending its Content-Length, see HeadFromGet.
+	AutoHead bool
+
+	// If enabled, the router checks if another method is allowed for the
+	// current route, if the current request can not be routed.
+	// If this is the case, the request is answered with 'Method Not Allowed'
+	// and HTTP status code 405, listing the allowed methods in the Allow
+	// header. If no other method is allowed, the request is delegated to the
+	// NotFound handler.
+	HandleMethodNotAllowed bool
+
+	// Configurable http.Handler which is called when no matching route is
+	// found. If it is not set, http.NotFound is used.
+	NotFound http.Handler
+
+	// If enabled, the NotFound handler receives the Params captured before
+	// the lookup failed through the request context, see ParamsFromContext
+	// and Lookup, e.g. to respond that user 42 exists, but not the requested
+	// sub-resource.
+	NotFoundParams bool
+
+	// If greater than 0, the NotFound handler receives up to this many
+	// suggestions of the routes the request may have been meant for through
+	// the request context, see Suggest and SuggestionsFromContext, e.g. to
+	// answer with "did you mean /users/:id?" during development.
+	SuggestOnMiss int
+
+	// Configurable http.Handler which is called when a request cannot be
+	// routed and HandleMethodNotAllowed is true. If it is not set,
+	// http.Error with http.StatusMethodNotAllowed is used. The Allow header
+	// with the allowed methods is set before the handler is called.
+	MethodNotAllowed http.Handler
+
+	// If enabled, the middleware added by Use also wraps the NotFound and
+	// MethodNotAllowed handlers, e.g. to log or recover from panics of all
+	// requests. RouteFromContext returns nil for them.
+	MiddlewareOnMiss bool
+
+	// If enabled, ServeHTTP matches requests against the path as sent by the
+	// client, see url.URL.RawPath, so encoded slashes like in "/files/a%2Fb"
+	// don't separate segments, and decodes the parameter values. Values
+	// with malformed percent-encoding are passed to MalformedParamHandler.
+	DecodeParams bool
+
+	// Configurable http.Handler which is called for requests whose parameter
+	// values can't be decoded, see DecodeParams. If it is not set, the
+	// request is answered with 400 Bad Request.
+	MalformedParamHandler http.Handler
+
+	// the type of the accepted handles, see NewStrict
+	handleType reflect.Type
+
+	// If enabled, ServeHTTP responds with 400 Bad Request to requests whose
+	// catch-all value has a ".." segment, literal or percent-encoded, e.g.
+	// "/files/..%2f..%2fetc/passwd", instead of invoking the handle. Dots
+	// elsewhere, like in "/files/v1..2.tar.gz", are not affected.
+	StrictCatchAll bool
+
+	// SchemeHeader names a request header holding the scheme of the request
+	// as seen by the client, e.g. "X-Forwarded-Proto" behind a proxy
+	// terminating TLS, for routes restricted by RouteOptions.Schemes. The
+	// first of several comma-separated values is used. If not set or not sent,
+	// the scheme is "https" for requests received over TLS, else "http".
+	SchemeHeader string
+
+	// Configurable http.Handler which is called for requests whose scheme the
+	// matched route is not restricted to, e.g. to redirect to https. If it is
+	// not set, the request is answered with 404 Not Found.
+	SchemeMismatch http.Handler
+
+	// Configurable http.Handler which is called for the requests received
+	// while the router is draining, see SetDraining. If it is not set, the
+	// requests are answered with 503 Service Unavailable.
+	DrainingHandler http.Handler
+
+	// route tables selected by KeyFunc, see Table
+	tables map[string]*Router
+
+	// fallback routes of path prefixes, longest prefix first, see
+	// SubtreeDefault
+	defaults []*Route
+
+	// applied around the handles of matched routes, see Use
+	middleware []Middleware
+
+	// composed into the chains of routes matching their glob, see UseFor
+	scoped []scopedMiddleware
+
+	// called for every lookup, see OnLookup
+	onLookup func(LookupEvent)
+
+	// called for every request served by a handle, see OnServe
+	onServe func(ServeEvent)
+
+	// convert handles to a Handle at registration, see RegisterAdapter
+	adapters []func(interface{}) (Handle, bool)
+
+	// Function called by ServeHTTP after the handle of a matched route
+	// returned, with the time it took including middleware, e.g. to record
+	// request metrics per route. Requests which are not served by a handle
+	// and health checks, see Health, are not observed.
+	Observe func(req *http.Request, rt *Route, elapsed time.Duration)
+
+	// If enabled, lookups count the matches of each route, including those
+	// of ServeHTTP, at the cost of an atomic increment, see Counters.
+	CountLookups bool
+
+	// Function to render the errors returned by handles of type HandleErr,
+	// after the mappers added by UseError. If it is not set, the error is
+	// answered with 500 Internal Server Error.
+	ErrorHandler func(http.ResponseWriter, *http.Request, error)
+
+	// Function to serve requests matching a route whose handle is of none of
+	// the types ServeHTTP invokes: Handle, HandleErr, http.Handler and
+	// functions with the signature of Handle or http.HandlerFunc. If it is not
+	// set, the request is answered with 500 Internal Server Error.
+	UnknownHandleType func(w http.ResponseWriter, req *http.Request, handle interface{})
+
+	// applied to the errors of handles, see UseError
+	errorMappers []func(error) error
+
+	// recently looked up results, see EnableLookupCache
+	cache *lookupCache
+
+	// counts the lookups per route, see EnableHitSampling
+	sampler *hitSampler
+
+	// handles selected by a query parameter, see HandleQuery
+	queries atomic.Pointer[queryRoutes]
+
+	// locale codes recognized as leading path segment, see LocalePrefix
+	locales atomic.Pointer[localeSet]
+
+	// set for the snapshot held by a FrozenRouter, which is never modified,
+	// so lookups don't need the lock
+	frozen bool
+
+	// set by Freeze, after which the router is never modified either
+	immutable atomic.Bool
+
+	// set by SetDraining
+	draining atomic.Bool
 }
 
 // New returns a new initialized Router.
 // Path auto-correction, including trailing slashes, is enabled by default.
 func New() *Router {
-	return &Router{}
+	return &Router{
+		RedirectTrailingSlash: true,
+		MaxSegments:            DefaultMaxSegments,
+		MaxPathLength:          DefaultMaxPathLength,
+	}
 }
 
 // GET is a shortcut for router.Handle("GET", path, handle)
@@ -156,29 +505,847 @@ func (r *Router) DELETE(path string, handle interface{}) error {
 // frequently used, non-standardized or custom methods (e.g. for internal
 // communication with a proxy).
 func (r *Router) Handle(method, path string, handle interface{}) error {
-	if path[0] != '/' {
-		return errors.Errorf("path must begin with '/' in path '%s'", path)
+	return r.HandleOptions(method, path, handle, RouteOptions{})
+}
+
+// HandleOptions registers a new request handle with the given path, method and
+// per-route options.
+func (r *Router) HandleOptions(method, path string, handle interface{}, opts RouteOptions) error {
+	if path == "" || path[0] != '/' {
+		return fmt.Errorf("path must begin with '/' in path '%s': %w", path, ErrInvalidPath)
+	}
+	if isNil(handle) {
+		return fmt.Errorf("path '%s': %w", path, ErrNilHandle)
+	}
+	handle, err := r.adapt(path, handle)
+	if err != nil {
+		return err
+	}
+	if err := r.checkHandleType(path, handle); err != nil {
+		return err
+	}
+	if err := checkPath(path); err != nil {
+		return err
+	}
+	if !r.PermissivePaths {
+		if err := checkPathChars(path); err != nil {
+			return err
+		}
+	}
+	if err := r.checkLimits(path); err != nil {
+		return err
+	}
+	if !r.AllowDuplicateParams {
+		if err := checkParamNames(path); err != nil {
+			return err
+		}
+	}
+	if err := r.checkReserved(path); err != nil {
+		return err
+	}
+	if !validMethod(method) {
+		return fmt.Errorf("method '%s' of path '%s': %w", method, path, ErrInvalidMethod)
+	}
+	method = r.normalizeMethod(method)
+
+	rt := &Route{
+		Method:  method,
+		Path:    path,
+		Handle:  handle,
+		Options: opts,
+		Source:  opts.Source,
+		hits:    new(atomic.Uint64),
+		lookups: new(atomic.Uint64),
+	}
+	if err := rt.checkOptions(); err != nil {
+		return err
+	}
+	rt.compose(r)
+	if rt.Source == "" && !r.DisableCallerSource {
+		rt.Source = callerSource()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.immutable.Load() {
+		return ErrFrozen
+	}
+	if r.sealed.Load() && !r.ConcurrentRegistration {
+		return fmt.Errorf("route '%s %s': %w", method, path, ErrSealed)
+	}
+
+	if r.StrictParamCase {
+		if err := r.checkParamCase(method, path); err != nil {
+			return err
+		}
+	}
+
+	path = r.intern(path)
+	rt.Path = path
+
+	t := r.tree(method)
+	added := t == nil
+	if added {
+		t = r.addTree(method)
+	}
+	if err := t.root.addRoute(path, rt); err != nil {
+		if added {
+			r.removeTree(method)
+		}
+		if conflict, ok := err.(*ConflictError); ok {
+			conflict.Source = rt.Source
+		}
+		return err
+	}
+	r.addPath(path)
+	// fall back to the mutable tree
+	t.compact = nil
+	r.relayout(t)
+	r.invalidateCache()
+	if countParams(path) == 0 {
+		t.static.add(rt)
+	}
+	if strings.Contains(path, "/*") {
+		t.catchAlls++
+	}
+	if r.StrictParamCase {
+		r.addParamNames(method, path)
+	}
+	return nil
+}
+
+// isNil reports whether handle is nil or holds a nil func or pointer, which
+// would be dereferenced when serving a request.
+func isNil(handle interface{}) bool {
+	if handle == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(handle); v.Kind() {
+	case reflect.Func, reflect.Ptr, reflect.Map, reflect.Chan, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// ServeFiles serves files from the given file system root, for GET and HEAD
+// requests. The path must end with "/*filepath", files are then served from
+// the local path /defined/root/dir/*filepath.
+// For example if root is "/etc" and *filepath is "passwd", the local file
+// "/etc/passwd" would be served.
+// Internally a http.FileServer is used, answering HEAD requests with the
+// headers of the file, e.g. its Content-Length, but no body.
+// To use the operating system's file system implementation,
+// use http.Dir:
+//
+//	router.ServeFiles("/src/*filepath", http.Dir("/var/www"))
+func (r *Router) ServeFiles(path string, root http.FileSystem) error {
+	if len(path) < 10 || path[len(path)-10:] != "/*filepath" {
+		return fmt.Errorf("path must end with /*filepath in path '%s': %w", path, ErrInvalidPath)
+	}
+
+	fileServer := http.FileServer(root)
+	handle := Handle(func(w http.ResponseWriter, req *http.Request, ps Params) {
+		// rewrite a copy, the request may be shared with middleware
+		u := *req.URL
+		u.Path, u.RawPath = ps.ByName("filepath"), ""
+		freq := new(http.Request)
+		*freq = *req
+		freq.URL = &u
+		fileServer.ServeHTTP(w, freq)
+	})
+
+	var failed MultiError
+	for _, method := range [...]string{http.MethodGet, http.MethodHead} {
+		if err := r.Handle(method, path, handle); err != nil {
+			failed.add(method, path, err)
+		}
+	}
+	return failed.err()
+}
+
+// HandleMatch registers a new request handle with the given path and method,
+// matching only if the values of the parameters satisfy the given predicates,
+// see RouteOptions.Match.
+func (r *Router) HandleMatch(method, path string, handle interface{}, match map[string]func(string) bool) error {
+	return r.HandleOptions(method, path, handle, RouteOptions{Match: match})
+}
+
+// Replace atomically replaces the handle registered for exactly the given
+// method and path, keeping the options of the route. The trie is not modified,
+// so Replace is safe to call while the router serves requests; lookups
+// running concurrently return either the old or the new handle.
+// It returns ErrNotRegistered if no handle is registered for the path.
+func (r *Router) Replace(method, path string, handle interface{}) error {
+	if isNil(handle) {
+		return fmt.Errorf("path '%s': %w", path, ErrNilHandle)
+	}
+	handle, err := r.adapt(path, handle)
+	if err != nil {
+		return err
+	}
+	if err := r.checkHandleType(path, handle); err != nil {
+		return err
+	}
+
+	method = r.normalizeMethod(method)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.immutable.Load() {
+		return ErrFrozen
+	}
+
+	t := r.tree(method)
+	var n *node
+	if t != nil {
+		n = t.root.findNode(path)
+	}
+	if n == nil {
+		return fmt.Errorf("path '%s': %w", path, ErrNotRegistered)
+	}
+
+	// routes are never modified after registration, replace it by a copy
+	rt := *n.data.(*Route)
+	rt.Handle = handle
+	rt.compose(r)
+	n.data = &rt
+	if countParams(path) == 0 {
+		t.static.add(&rt)
+	}
+	r.relayout(t)
+	r.invalidateCache()
+	return nil
+}
+
+// Remove unregisters the handle registered for exactly the given method and
+// path, wildcards included. Subsequent lookups of the path miss, or match
+// another route that matches the path. Remove is safe to call while the
+// router serves requests.
+// It returns ErrNotRegistered if no handle is registered for the path.
+func (r *Router) Remove(method, path string) error {
+	method = r.normalizeMethod(method)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.immutable.Load() {
+		return ErrFrozen
 	}
 
-	if r.trees == nil {
-		r.trees = make(map[string]*node)
+	t := r.tree(method)
+	if t == nil {
+		return fmt.Errorf("path '%s': %w", path, ErrNotRegistered)
+	}
+	if _, err := t.root.removeRoute(path); err != nil {
+		return err
+	}
+	r.removeQueries(method, path)
+	r.removePath(path)
+	if countParams(path) == 0 {
+		delete(t.static.routes, path)
 	}
-	root := r.trees[method]
-	if root == nil {
-		root = new(node)
-		r.trees[method] = root
+	if strings.Contains(path, "/*") {
+		t.catchAlls--
+	}
+	r.relayout(t)
+	r.invalidateCache()
+	return nil
+}
+
+// checkParamCase returns an error if path declares a parameter whose name
+// equals another parameter name of the path or of the method's routes under
+// Unicode case-folding without being identical.
+func (r *Router) checkParamCase(method, path string) error {
+	names := paramNames(path)
+	for i, name := range names {
+		if other, ok := foldConflict(name, names[:i]); ok {
+			return fmt.Errorf("parameter '%s' differs only by case from parameter '%s' in path '%s': %w", name, other, path, ErrInvalidWildcard)
+		}
+		if other, ok := foldConflict(name, r.paramNames[method]); ok {
+			return fmt.Errorf("parameter '%s' differs only by case from existing parameter '%s' in path '%s': %w", name, other, path, ErrConflict)
+		}
+	}
+	return nil
+}
+
+// foldConflict returns the first of names which equals name under Unicode
+// case-folding without being identical to it.
+func foldConflict(name string, names []string) (string, bool) {
+	for _, other := range names {
+		if name != other && strings.EqualFold(name, other) {
+			return other, true
+		}
+	}
+	return "", false
+}
+
+// addParamNames records the parameter names of path for checkParamCase.
+func (r *Router) addParamNames(method, path string) {
+	if r.paramNames == nil {
+		r.paramNames = make(map[string][]string)
+	}
+walk:
+	for _, name := range paramNames(path) {
+		for _, known := range r.paramNames[method] {
+			if known == name {
+				continue walk
+			}
+		}
+		r.paramNames[method] = append(r.paramNames[method], name)
 	}
-	return root.addRoute(path, handle)
 }
 
 // Lookup allows the manual lookup of a method + path combo.
 // This is e.g. useful to build a framework around this router.
 // If the path was found, it returns the handle function and the path parameter
 // values. Otherwise the third return value indicates whether a redirection to
-// the same path with an extra / without the trailing slash should be performed.
+// the same path with an extra / without the trailing slash should be performed,
+// and the Params hold the values captured before the lookup failed, e.g. the
+// id of "/users/:id/posts" for "/users/42/likes".
+// Lookup allocates nothing for paths without parameter values and a single
+// Params slice of exactly the needed capacity otherwise.
 func (r *Router) Lookup(method, path string) (interface{}, Params, bool) {
-	if root := r.trees[method]; root != nil {
-		return root.getValue(path)
+	rt, ps, tsr := r.LookupRoute(method, path)
+	if rt == nil {
+		return nil, ps, tsr
+	}
+	return rt.Handle, ps, tsr
+}
+
+// LookupRoute is like Lookup, but returns the matched Route, giving access to
+// the options the route was registered with.
+func (r *Router) LookupRoute(method, path string) (*Route, Params, bool) {
+	if r.onLookup == nil {
+		return r.lookupAny(method, path)
+	}
+	start := time.Now()
+	rt, ps, tsr := r.lookupAny(method, path)
+	r.onLookup(newLookupEvent(method, path, rt, tsr, start))
+	return rt, ps, tsr
+}
+
+// lookupAny implements LookupRoute, using the lookup cache if enabled.
+func (r *Router) lookupAny(method, path string) (*Route, Params, bool) {
+	if r.cache != nil {
+		return r.lookupCached(method, path)
+	}
+	return r.lookupRoute(method, path)
+}
+
+func (r *Router) lookupRoute(method, path string) (*Route, Params, bool) {
+	if l := r.locales.Load(); l != nil {
+		return r.lookupLocale(l, method, path, false)
+	}
+	return r.lookup(method, path, false)
+}
+
+// MethodAny is the method of routes which LookupAny matches for requests of
+// any method, e.g. router.Handle(MethodAny, "/health", health). Lookup is
+// method-strict: it treats it like any other method, matching its routes only
+// for the method "*". ServeHTTP falls back to them like LookupAny, so they are
+// never listed in the Allow header of 405 responses.
+const MethodAny = "*"
+
+// LookupAny is like LookupRoute, but falls back to the routes registered for
+// MethodAny if no route of method matches the path, e.g. for frameworks
+// dispatching routes of all methods themselves. Routes of MethodAny take
+// precedence over subtree defaults, see SubtreeDefault. If neither matches,
+// the trailing slash recommendation and Params of the lookup of method are
+// returned, see Lookup.
+// Like ServeHTTP, it reports a single event of method to OnLookup.
+func (r *Router) LookupAny(method, path string) (*Route, Params, bool) {
+	var start time.Time
+	if r.onLookup != nil {
+		start = time.Now()
+	}
+	rt, ps, tsr := r.lookupAny(method, path)
+	// subtree defaults have no method
+	if (rt == nil || rt.Method == "") && r.normalizeMethod(method) != MethodAny {
+		if fallback, fallbackPs, _ := r.lookupAny(MethodAny, path); fallback != nil && fallback.Method != "" {
+			rt, ps, tsr = fallback, fallbackPs, false
+		}
+	}
+	if r.onLookup != nil {
+		r.onLookup(newLookupEvent(method, path, rt, tsr, start))
+	}
+	return rt, ps, tsr
+}
+
+// LookupNoTSR is like Lookup, but skips computing trailing slash
+// recommendations, which makes misses cheaper. Params are only returned if the
+// path was found. For users who never redirect.
+func (r *Router) LookupNoTSR(method, path string) (interface{}, Params) {
+	var start time.Time
+	if r.onLookup != nil {
+		start = time.Now()
+	}
+	var rt *Route
+	var ps Params
+	if l := r.locales.Load(); l != nil {
+		rt, ps, _ = r.lookupLocale(l, method, path, true)
+	} else {
+		rt, ps, _ = r.lookup(method, path, true)
+	}
+	if r.onLookup != nil {
+		r.onLookup(newLookupEvent(method, path, rt, false, start))
+	}
+	if rt == nil {
+		return nil, nil
+	}
+	return rt.Handle, ps
+}
+
+// LookupFunc is like Lookup, but instead of returning the parameter values it
+// calls visit with the key and value of each of them, in path order, without
+// allocating. The values are buffered until the route is matched, so visit is
+// only called if a handle is returned.
+func (r *Router) LookupFunc(method, path string, visit func(key, value string)) (interface{}, bool) {
+	var start time.Time
+	if r.onLookup != nil {
+		start = time.Now()
+	}
+	lookupPath := path
+	var locale string
+	l := r.locales.Load()
+	if l != nil {
+		locale, lookupPath = l.split(path)
+	}
+	var buf [paramsBufSize]Param
+	rt, ps, tsr := r.find(method, lookupPath, buf[:0], false)
+	if r.onLookup != nil {
+		r.onLookup(newLookupEvent(method, path, rt, tsr, start))
+	}
+	if rt == nil {
+		return nil, tsr
+	}
+	if l != nil {
+		visit(LocaleParam, locale)
+	}
+	for i := range ps {
+		// like lookupLocale, the locale hides the values of routes
+		// registered before LocalePrefix
+		if l != nil && ps[i].Key == LocaleParam {
+			continue
+		}
+		visit(ps[i].Key, ps[i].Value)
+	}
+	return rt.Handle, false
+}
+
+func (r *Router) lookup(method, path string, noTSR bool) (*Route, Params, bool) {
+	// collect the values on the stack and copy them out once the route is
+	// known, so the number of values is known
+	var buf [paramsBufSize]Param
+	rt, values, tsr := r.find(method, path, buf[:0], noTSR)
+	var ps Params
+	if len(values) > 0 {
+		ps = make(Params, len(values))
+		copy(ps, values)
+	}
+	return rt, ps, tsr
+}
+
+// find implements lookup, appending the parameter values to buf like
+// node.find. Values are only returned along with a route, or a trailing slash
+// recommendation unless noTSR is set. The matched route is counted for
+// CountLookups and EnableHitSampling.
+func (r *Router) find(method, path string, buf Params, noTSR bool) (*Route, Params, bool) {
+	return r.findRoute(method, path, buf, noTSR, true)
+}
+
+// findRoute implements find, counting the matched route only if count is
+// set, e.g. not for probing the methods allowed for a path.
+func (r *Router) findRoute(method, path string, buf Params, noTSR, count bool) (*Route, Params, bool) {
+	if !r.frozen && !r.immutable.Load() {
+		if !r.sealed.Load() {
+			r.seal()
+		}
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+	}
+
+	t := r.tree(r.normalizeMethod(method))
+	if t == nil {
+		return r.subtreeDefault(path), nil, false
+	}
+	noTSR = noTSR || t.strictSlash
+	if r.exceedsLimits(t, path) {
+		return nil, nil, false
+	}
+
+	// fast path for routes without parameters, falling back to the tree
+	// which also handles trailing slash recommendations
+	if rt := t.static.get(path); rt != nil && !rt.reserved() {
+		if count && r.sampler != nil {
+			r.sampler.sample(rt)
+		}
+		if count && r.CountLookups {
+			rt.lookups.Add(1)
+		}
+		return rt, nil, false
+	}
+	var data interface{}
+	var ps Params
+	var tsr bool
+	if t.compact != nil {
+		data, ps, tsr = t.compact.find(path, buf, noTSR)
+	} else {
+		data, ps, tsr = t.root.find(path, buf, noTSR)
+	}
+	if r.MaxParams > 0 && len(ps) > r.MaxParams {
+		return nil, nil, false
+	}
+	rt, _ := data.(*Route)
+	if rt != nil && rt.reserved() {
+		rt, ps, tsr = nil, nil, false
+	}
+	if rt != nil && !rt.matches(ps) {
+		rt, tsr = nil, false
+	}
+	if path == "/" {
+		// the tree recommends removing the slash if no route matches the
+		// root, which leaves no path to redirect to
+		tsr = false
+	}
+	if rt != nil && count && r.sampler != nil {
+		r.sampler.sample(rt)
+	}
+	if rt != nil && count && r.CountLookups {
+		rt.lookups.Add(1)
+	}
+	if rt == nil {
+		if !tsr && len(r.defaults) > 0 {
+			if def := r.subtreeDefault(path); def != nil {
+				return def, nil, false
+			}
+		}
+		if noTSR {
+			return nil, nil, false
+		}
+	}
+	return rt, ps, tsr
+}
+
+// LookupBytes is like Lookup, but takes the path as a byte slice, avoiding the
+// conversion to a string. The trie is walked over the bytes of path directly;
+// only the captured parameter values are copied, into a single new string, so
+// nothing returned refers to path after the call. An empty or nil path
+// matches nothing, not even a subtree default, and recommends no redirect.
+func (r *Router) LookupBytes(method string, path []byte) (handle interface{}, ps Params, tsr bool) {
+	if len(path) == 0 {
+		return nil, nil, false
+	}
+	// the string shares the memory of path and must not outlive this call
+	handle, ps, tsr = r.Lookup(method, unsafe.String(&path[0], len(path)))
+	ps.copyValues()
+	return handle, ps, tsr
+}
+
+// ServeHTTP makes the router implement the http.Handler interface.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.draining.Load() {
+		r.drain(w, req)
+		return
+	}
+	if r.KeyFunc != nil {
+		if table := r.table(r.KeyFunc(req)); table != nil {
+			table.ServeHTTP(w, req)
+			return
+		}
+	}
+	path, decode := r.servedPath(req)
+	method := req.Method
+	if alias, ok := r.MethodAliases[method]; ok {
+		method = alias
+	}
+	var start time.Time
+	if r.onLookup != nil {
+		start = time.Now()
 	}
-	return nil, nil, false
+	rt, ps, tsr := r.lookupAny(method, path)
+	if rt == nil && r.AutoHead && method == http.MethodHead {
+		if get, getPs, _ := r.lookupAny(http.MethodGet, path); get != nil {
+			hw := HeadFromGet(w)
+			defer hw.Finish()
+			rt, ps, w = get, getPs, hw
+		}
+	}
+	// like LookupAny, routes of MethodAny take precedence over subtree defaults
+	if (rt == nil || rt.Method == "") && method != MethodAny && r.hasTree(MethodAny) {
+		if fallback, fallbackPs, _ := r.lookupAny(MethodAny, path); fallback != nil && fallback.Method != "" {
+			rt, ps, tsr = fallback, fallbackPs, false
+		}
+	}
+	// a single event for the final result of the lookups above
+	if r.onLookup != nil {
+		r.onLookup(newLookupEvent(method, path, rt, tsr, start))
+	}
+	if rt == nil {
+		if tsr && r.RedirectTrailingSlash && req.Method != http.MethodConnect {
+			redirectTrailingSlash(w, req)
+			return
+		}
+		if decode && r.NotFoundParams {
+			if decoded, err := decodeParams(ps); err == nil {
+				ps = decoded
+			}
+		}
+		r.miss(w, req, method, path, ps)
+		return
+	}
+	if rt.hits != nil && !isHealth(rt) {
+		rt.hits.Add(1)
+	}
+	capturePattern(req.Context(), rt)
+	if len(rt.Options.Schemes) > 0 && !rt.allowsScheme(r.requestScheme(req)) {
+		r.schemeMismatch(w, req)
+		return
+	}
+	if decode {
+		var err error
+		if ps, err = decodeParams(ps); err != nil {
+			r.malformedParam(w, req, err)
+			return
+		}
+	}
+	if r.StrictCatchAll && traversal(rt, ps) {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	if err := rt.Validate(ps); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rt.push(w)
+	if r.Observe != nil && !isHealth(rt) {
+		defer func(start time.Time) {
+			r.Observe(req, rt, time.Since(start))
+		}(time.Now())
+	}
+	if r.onServe != nil && !isHealth(rt) {
+		rec := &statusRecorder{ResponseWriter: w}
+		w = rec
+		defer r.reportServe(req, rt, ps, rec, time.Now())
+	}
+
+	req = req.WithContext(&matchContext{Context: req.Context(), route: rt, params: ps})
+	h := r.queryHandle(rt, req)
+	if len(r.middleware) == 0 {
+		r.serve(h, w, req, ps)
+		return
+	}
+	handle := r.toHandle(h)
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handle = r.middleware[i](handle)
+	}
+	handle(w, req, ps)
+}
+
+// HTTPHandlerFor resolves the handle registered for the method + path combo
+// and adapts it to a http.Handler with the path parameter values bound. The
+// values are passed to handles not taking Params through the request
+// context, see ParamsFromContext.
+// If no handle was found, the third return value is false.
+func (r *Router) HTTPHandlerFor(method, path string) (http.Handler, Params, bool) {
+	handle, ps, _ := r.Lookup(method, path)
+	if handle == nil {
+		return nil, nil, false
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.serve(handle, w, req, ps)
+	}), ps, true
+}
+
+// standardMethods are the methods whose trees are stored in an array instead
+// of a map, the position in the array is given by methodIndex.
+var standardMethods = [...]string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// methodIndex returns the position of method in standardMethods, or -1 if it
+// is not a standard method.
+func methodIndex(method string) int {
+	switch method {
+	case "GET":
+		return 0
+	case "POST":
+		return 1
+	case "PUT":
+		return 2
+	case "PATCH":
+		return 3
+	case "DELETE":
+		return 4
+	case "HEAD":
+		return 5
+	case "OPTIONS":
+		return 6
+	}
+	return -1
+}
+
+// methodTree holds the routes registered for a method.
+type methodTree struct {
+	root *node
+
+	// routes without parameters, consulted before the tree
+	static staticTable
+
+	// compact form of root, used for lookups if set, see Compact
+	compact *compactTree
+
+	// whether trailing slashes are significant, see StrictSlash
+	strictSlash bool
+
+	// number of routes with a catch-all, see exceedsLimits
+	catchAlls int
+}
+
+// normalizeMethod returns method upper-cased unless CaseSensitiveMethods is
+// set. It only allocates for methods with lower-case letters.
+func (r *Router) normalizeMethod(method string) string {
+	if r.CaseSensitiveMethods {
+		return method
+	}
+	for i := 0; i < len(method); i++ {
+		if c := method[i]; 'a' <= c && c <= 'z' {
+			return strings.ToUpper(method)
+		}
+	}
+	return method
+}
+
+// validMethod reports whether method is a token as defined by RFC 7230.
+func validMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	for i := 0; i < len(method); i++ {
+		c := method[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+			continue
+		}
+		if strings.IndexByte("!#$%&'*+-.^_`|~", c) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// tree returns the tree of method, or nil if no route is registered for it.
+func (r *Router) tree(method string) *methodTree {
+	if i := methodIndex(method); i >= 0 {
+		return r.trees[i]
+	}
+	return r.otherTrees[method]
+}
+
+// addTree adds an empty tree for method.
+func (r *Router) addTree(method string) *methodTree {
+	t := &methodTree{root: new(node)}
+	if i := methodIndex(method); i >= 0 {
+		r.trees[i] = t
+	} else {
+		if r.otherTrees == nil {
+			r.otherTrees = make(map[string]*methodTree)
+		}
+		r.otherTrees[method] = t
+	}
+	return t
+}
+
+// hasTree reports whether routes are registered or settings are made for
+// method.
+func (r *Router) hasTree(method string) bool {
+	if !r.frozen && !r.immutable.Load() {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+	}
+	return r.tree(method) != nil
+}
+
+// removeTree drops the tree of method.
+func (r *Router) removeTree(method string) {
+	if i := methodIndex(method); i >= 0 {
+		r.trees[i] = nil
+	} else {
+		delete(r.otherTrees, method)
+	}
+}
+
+// eachTree calls fn for the tree of each method routes are registered or
+// settings are made for.
+func (r *Router) eachTree(fn func(method string, t *methodTree)) {
+	for i, t := range r.trees {
+		if t != nil {
+			fn(standardMethods[i], t)
+		}
+	}
+	for method, t := range r.otherTrees {
+		fn(method, t)
+	}
+}
+
+// staticTable maps the paths of routes without parameters to the routes.
+type staticTable struct {
+	routes map[string]*Route
+
+	// set of the lengths of the paths in routes, used to skip the map lookup
+	// for most paths of parameterized routes
+	lengths [8]uint64
+}
+
+func (s *staticTable) add(rt *Route) {
+	if s.routes == nil {
+		s.routes = make(map[string]*Route)
+	}
+	if n := len(rt.Path); n < 64*len(s.lengths) {
+		s.lengths[n/64] |= 1 << (n % 64)
+	}
+	s.routes[rt.Path] = rt
+}
+
+func (s *staticTable) get(path string) *Route {
+	if n := len(path); n < 64*len(s.lengths) && s.lengths[n/64]&(1<<(n%64)) == 0 {
+		return nil
+	}
+	return s.routes[path]
+}
+
+// HandlerFromLookup returns a http.Handler which looks up the handle for each
+// request in r and invokes it. Requests without a matching route are passed to
+// onMiss, or answered with http.NotFound if onMiss is nil.
+//
+// It is a reference implementation of the minimal dispatch loop for users
+// embedding the router in their own request handling.
+func HandlerFromLookup(r *Router, onMiss http.Handler) http.Handler {
+	if onMiss == nil {
+		onMiss = http.NotFoundHandler()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		handle, ps, _ := r.Lookup(req.Method, req.URL.Path)
+		if handle == nil {
+			onMiss.ServeHTTP(w, req)
+			return
+		}
+		r.serve(handle, w, req, ps)
+	})
+}
+
+// Fallthrough returns a http.Handler which looks up the route for each request
+// in the given routers in order and invokes the handle of the first match,
+// wrapped by the middleware of its route, e.g. to try a new route table before
+// falling back to the old one. Requests matching no router are answered with
+// http.NotFound. Like HandlerFromLookup, it doesn't apply the settings of the
+// routers, such as trailing slash redirects or the middleware added by Use.
+func Fallthrough(routers ...*Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, r := range routers {
+			if rt, ps, _ := r.LookupRoute(req.Method, req.URL.Path); rt != nil {
+				r.serve(rt.serveHandle(), w, req, ps)
+				return
+			}
+		}
+		http.NotFound(w, req)
+	})
 }
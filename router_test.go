@@ -5,9 +5,22 @@
 package xrouter
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"unsafe"
 )
 
 func TestParams(t *testing.T) {
@@ -26,6 +39,31 @@ func TestParams(t *testing.T) {
 	}
 }
 
+func TestParamsGet(t *testing.T) {
+	ps := Params{
+		Param{"format", ""},
+		Param{"id", "1"},
+		Param{"id", "2"},
+	}
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"format", "", true},
+		{"id", "1", true},
+		{"page", "", false},
+	}
+	for _, test := range tests {
+		if val, ok := ps.Get(test.name); val != test.value || ok != test.ok {
+			t.Errorf("Wrong result for %s: Got %q, %v; Want %q, %v", test.name, val, ok, test.value, test.ok)
+		}
+	}
+	if _, ok := Params(nil).Get("id"); ok {
+		t.Error("Expected nil Params to hold no key")
+	}
+}
+
 type handlerStruct struct {
 	handled *bool
 }
@@ -36,12 +74,28 @@ func (h handlerStruct) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func TestRouterRoot(t *testing.T) {
 	router := New()
-	recv := router.GET("noSlashRoot", nil)
+	recv := router.GET("noSlashRoot", "h")
 	if recv == nil {
 		t.Fatal("registering path not beginning with '/' did not panic")
 	}
 }
 
+// lookupRouter is the read-only interface shared by Router and FrozenRouter.
+type lookupRouter interface {
+	http.Handler
+	Lookup(method, path string) (interface{}, Params, bool)
+	LookupRoute(method, path string) (*Route, Params, bool)
+	LookupBytes(method string, path []byte) (interface{}, Params, bool)
+	LookupNoTSR(method, path string) (interface{}, Params)
+	LookupFunc(method, path string, visit func(key, value string)) (interface{}, bool)
+}
+
+// forBoth runs test against router and against a frozen snapshot of it.
+func forBoth(t *testing.T, router *Router, test func(t *testing.T, r lookupRouter)) {
+	t.Run("mutable", func(t *testing.T) { test(t, router) })
+	t.Run("frozen", func(t *testing.T) { test(t, router.snapshot()) })
+}
+
 func TestRouterLookup(t *testing.T) {
 	routed := false
 	wantHandle := func(_ http.ResponseWriter, _ *http.Request, _ Params) {
@@ -50,40 +104,1591 @@ func TestRouterLookup(t *testing.T) {
 	wantParams := Params{Param{"name", "gopher"}}
 
 	router := New()
+	router.ConcurrentRegistration = true
 
 	// try empty router first
-	handle, _, tsr := router.Lookup("GET", "/nope")
-	if handle != nil {
-		t.Fatalf("Got handle for unregistered pattern: %v", handle)
-	}
-	if tsr {
-		t.Error("Got wrong TSR recommendation!")
-	}
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		handle, _, tsr := router.Lookup("GET", "/nope")
+		if handle != nil {
+			t.Fatalf("Got handle for unregistered pattern: %v", handle)
+		}
+		if tsr {
+			t.Error("Got wrong TSR recommendation!")
+		}
+	})
 
 	// insert route and try again
 	router.GET("/user/:name", wantHandle)
 
-	handle, params, tsr := router.Lookup("GET", "/user/gopher")
-	if handle == nil {
-		t.Fatal("Got no handle!")
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		routed = false
+		handle, params, tsr := router.Lookup("GET", "/user/gopher")
+		if handle == nil {
+			t.Fatal("Got no handle!")
+		} else {
+			handle.(func(http.ResponseWriter, *http.Request, Params))(nil, nil, nil)
+			if !routed {
+				t.Fatal("Routing failed!")
+			}
+		}
+		if !reflect.DeepEqual(params, wantParams) {
+			t.Fatalf("Wrong parameter values: want %v, got %v", wantParams, params)
+		}
+
+		handle, _, tsr = router.Lookup("GET", "/user/gopher/")
+		if handle != nil {
+			t.Fatalf("Got handle for unregistered pattern: %v", handle)
+		}
+		if !tsr {
+			t.Error("Got no TSR recommendation!")
+		}
+
+		handle, _, tsr = router.Lookup("GET", "/nope")
+		if handle != nil {
+			t.Fatalf("Got handle for unregistered pattern: %v", handle)
+		}
+		if tsr {
+			t.Error("Got wrong TSR recommendation!")
+		}
+	})
+}
+
+func TestRouterLookupAny(t *testing.T) {
+	router := New()
+	router.GET("/user/:name", "get")
+	router.Handle(MethodAny, "/user/:name", "any")
+	router.Handle(MethodAny, "/health", "health")
+	router.GET("/src/", "src")
+	router.SubtreeDefault("/", "default")
+
+	tests := []struct {
+		method, path string
+		strict, any  interface{}
+		strictTSR    bool
+	}{
+		{"GET", "/user/gopher", "get", "get", false},
+		{"POST", "/user/gopher", "default", "any", false},
+		{"DELETE", "/health", "default", "health", false},
+		{"*", "/health", "health", "health", false},
+		{"GET", "/health", "default", "health", false},
+		{"GET", "/src", nil, nil, true},
+		{"GET", "/nope/x", "default", "default", false},
+	}
+	for _, test := range tests {
+		handle, _, tsr := router.Lookup(test.method, test.path)
+		if handle != test.strict || tsr != test.strictTSR {
+			t.Errorf("Lookup(%s, %s) = %v, %v, want %v, %v", test.method, test.path, handle, tsr, test.strict, test.strictTSR)
+		}
+		var got interface{}
+		rt, ps, tsr := router.LookupAny(test.method, test.path)
+		if rt != nil {
+			got = rt.Handle
+		}
+		if got != test.any || tsr != test.strictTSR {
+			t.Errorf("LookupAny(%s, %s) = %v, %v, want %v, %v", test.method, test.path, got, tsr, test.any, test.strictTSR)
+		}
+		if got == "any" && ps.ByName("name") != "gopher" {
+			t.Errorf("wrong params of the fallback: %v", ps)
+		}
+	}
+}
+
+func TestRouterMethodAnyServeHTTP(t *testing.T) {
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) { w.Write([]byte(body)) }
+	}
+	router := New()
+	router.HandleMethodNotAllowed = true
+	router.GET("/x", respond("get"))
+	router.Handle(MethodAny, "/x", respond("any"))
+	router.POST("/y", respond("post"))
+	router.Handle(MethodAny, "/docs/any", respond("any docs"))
+	router.SubtreeDefault("/docs/", respond("default"))
+
+	for _, test := range []struct {
+		method, path string
+		code         int
+		body, allow  string
+	}{
+		{"GET", "/x", http.StatusOK, "get", ""},
+		{"PUT", "/x", http.StatusOK, "any", ""},
+		{"PUT", "/y", http.StatusMethodNotAllowed, "Method Not Allowed\n", "POST"},
+		{"GET", "/docs/any", http.StatusOK, "any docs", ""},
+		{"GET", "/docs/intro", http.StatusOK, "default", ""},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.code || w.Body.String() != test.body || w.Header().Get("Allow") != test.allow {
+			t.Errorf("%s %s: got %d %q with Allow %q, want %d %q with Allow %q", test.method, test.path,
+				w.Code, w.Body.String(), w.Header().Get("Allow"), test.code, test.body, test.allow)
+		}
+	}
+}
+
+func TestHandlerFromLookup(t *testing.T) {
+	var got string
+	router := New()
+	router.GET("/user/:name", func(_ http.ResponseWriter, _ *http.Request, ps Params) {
+		got = ps.ByName("name")
+	})
+	handled := false
+	router.POST("/handler", handlerStruct{&handled})
+
+	missed := false
+	h := HandlerFromLookup(router, http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		missed = true
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/gopher", nil))
+	if got != "gopher" {
+		t.Errorf("wrong parameter value: want %q, got %q", "gopher", got)
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/handler", nil))
+	if !handled {
+		t.Error("http.Handler not invoked")
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/handler", nil))
+	if !missed {
+		t.Error("onMiss not invoked for unmatched request")
+	}
+
+	w := httptest.NewRecorder()
+	HandlerFromLookup(router, nil).ServeHTTP(w, httptest.NewRequest("GET", "/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("wrong status code for miss: want %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestFallthrough(t *testing.T) {
+	var got []string
+	handle := func(name string) Handle {
+		return func(_ http.ResponseWriter, _ *http.Request, ps Params) {
+			got = append(got, name+" "+ps.ByName("id"))
+		}
+	}
+	a, b := New(), New()
+	a.GET("/items/:id", handle("a"))
+	b.GET("/items/:id", handle("b"))
+	b.GET("/legacy/:id", handle("b"))
+	h := Fallthrough(a, b)
+
+	for _, path := range []string{"/items/1", "/legacy/2"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if want := []string{"a 1", "b 2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong handles invoked: %v, want %v", got, want)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("wrong status code for miss: want %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestParamsClone(t *testing.T) {
+	router := New()
+	router.GET("/files/:dir/*filepath", "files")
+	path := "/files/js/" + strings.Repeat("x", 2048)
+	_, ps, _ := router.Lookup("GET", path)
+
+	// the values alias the path
+	if unsafe.StringData(ps[1].Value) != unsafe.StringData(path[len(path)-len(ps[1].Value):]) {
+		t.Error("catch-all value does not alias the path")
+	}
+
+	c := ps.Clone()
+	if !reflect.DeepEqual(c, ps) {
+		t.Fatalf("wrong clone: got %v, want %v", c, ps)
+	}
+	for i := range c {
+		if unsafe.StringData(c[i].Value) == unsafe.StringData(ps[i].Value) {
+			t.Errorf("cloned value %s shares the memory of the path", c[i].Key)
+		}
+	}
+	c[0].Value = "css"
+	if ps[0].Value != "js" {
+		t.Errorf("modifying the clone modified the original: %v", ps[0])
+	}
+	if Params(nil).Clone() != nil {
+		t.Error("clone of nil Params is not nil")
+	}
+}
+
+func TestParamsValidate(t *testing.T) {
+	numeric := func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return errors.New("not numeric")
+		}
+		if n < 1 || n > 100 {
+			return errors.New("out of range")
+		}
+		return nil
+	}
+	rules := map[string]func(string) error{
+		"id":   numeric,
+		"page": numeric,
+		"size": numeric,
+	}
+
+	if err := (Params{{"id", "42"}, {"page", "1"}, {"name", "gopher"}}).Validate(rules); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := (Params{{"id", "x"}, {"name", "gopher"}, {"page", "1"}, {"size", "1000"}}).Validate(rules)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if want := "invalid parameters: id: not numeric; size: out of range"; err.Error() != want {
+		t.Errorf("wrong error: got %q, want %q", err, want)
+	}
+	if err := Params(nil).Validate(rules); err != nil {
+		t.Errorf("unexpected error for nil Params: %v", err)
+	}
+}
+
+func TestParamsRequire(t *testing.T) {
+	ps := Params{{"id", "42"}, {"name", ""}}
+	if err := ps.Require("id", "name"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ps.Require(); err != nil {
+		t.Errorf("unexpected error without names: %v", err)
+	}
+	err := ps.Require("id", "page", "size")
+	if !errors.Is(err, ErrMissingParam) || err.Error() != "parameter 'page': missing parameter" {
+		t.Errorf("wrong error: %v", err)
+	}
+}
+
+func TestParamsByNameFold(t *testing.T) {
+	ps := Params{
+		Param{"userId", "1"},
+		Param{"Ünïcode", "2"},
+		Param{"σίγμα", "3"},
+		Param{"\u212Aelvin", "4"}, // KELVIN SIGN
+	}
+	tests := []struct {
+		name, value string
+	}{
+		{"userid", "1"},
+		{"USERID", "1"},
+		{"üNÏCODE", "2"},
+		{"ΣΊΓΜΑ", "3"},
+		{"kelvin", "4"},
+		{"KELVIN", "4"},
+		{"user", ""},
+	}
+	for _, test := range tests {
+		if val := ps.ByNameFold(test.name); val != test.value {
+			t.Errorf("Wrong value for %s: Got %q; Want %q", test.name, val, test.value)
+		}
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { ps.ByNameFold("ΣΊΓΜΑ") }); allocs != 0 {
+		t.Errorf("ByNameFold allocates: %v allocs", allocs)
+	}
+}
+
+func TestRouterStrictParamCase(t *testing.T) {
+	router := New()
+	router.StrictParamCase = true
+
+	if err := router.GET("/users/:userId", "h"); err != nil {
+		t.Fatalf("registering route failed: %v", err)
+	}
+	if err := router.GET("/teams/:teamId/members/:userId", "h"); err != nil {
+		t.Fatalf("registering route with identical param name failed: %v", err)
+	}
+	if err := router.GET("/orgs/:userid", "h"); err == nil {
+		t.Error("no error for param name differing only by case")
+	}
+	if err := router.GET("/a/:straße/b/:STRASSE", "h"); err != nil {
+		t.Errorf("unexpected error for distinct names: %v", err)
+	}
+	if err := router.GET("/b/:Ωmega/c/:ωmega", "h"); err == nil {
+		t.Error("no error for param names differing only by case within one path")
+	}
+	if err := router.POST("/orgs/:userid", "h"); err != nil {
+		t.Errorf("names of other methods must not conflict: %v", err)
+	}
+
+	// not strict by default
+	router = New()
+	router.GET("/users/:userId", "h")
+	if err := router.GET("/orgs/:userid", "h"); err != nil {
+		t.Errorf("unexpected error without strict mode: %v", err)
+	}
+}
+
+func TestRouterHTTPHandlerFor(t *testing.T) {
+	router := New()
+	router.GET("/user/:name", func(w http.ResponseWriter, _ *http.Request, ps Params) {
+		w.Write([]byte("handle " + ps.ByName("name")))
+	})
+	router.GET("/file/:name", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("handler " + ParamsFromContext(r.Context()).ByName("name")))
+	}))
+
+	tests := []struct {
+		path, body string
+	}{
+		{"/user/gopher", "handle gopher"},
+		{"/file/LICENSE", "handler LICENSE"},
+	}
+	for _, test := range tests {
+		h, ps, ok := router.HTTPHandlerFor("GET", test.path)
+		if !ok {
+			t.Fatalf("no handler for %s", test.path)
+		}
+		if len(ps) != 1 {
+			t.Errorf("wrong params for %s: %v", test.path, ps)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Body.String() != test.body {
+			t.Errorf("wrong body for %s: want %q, got %q", test.path, test.body, w.Body.String())
+		}
+	}
+
+	if h, ps, ok := router.HTTPHandlerFor("GET", "/nope"); ok || h != nil || ps != nil {
+		t.Errorf("got handler for unregistered path: %v, %v, %t", h, ps, ok)
+	}
+}
+
+func TestRouterLookupBytes(t *testing.T) {
+	router := New()
+	router.GET("/user/:name/repos/:repo", "repo")
+	router.GET("/src/*filepath", "src")
+
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		path := []byte("/user/gopher/repos/xrouter")
+		handle, ps, _ := router.LookupBytes("GET", path)
+		if handle != "repo" {
+			t.Fatalf("wrong handle: %v", handle)
+		}
+
+		// the values must not alias the input
+		copy(path, "/XXXX/XXXXXX/XXXXX/XXXXXXX")
+		wantParams := Params{Param{"name", "gopher"}, Param{"repo", "xrouter"}}
+		if !reflect.DeepEqual(ps, wantParams) {
+			t.Fatalf("Wrong parameter values: want %v, got %v", wantParams, ps)
+		}
+
+		handle, ps, _ = router.LookupBytes("GET", []byte("/src/a/b.go"))
+		if handle != "src" || ps.ByName("filepath") != "/a/b.go" {
+			t.Errorf("wrong result for catch-all: %v, %v", handle, ps)
+		}
+
+		handle, _, tsr := router.LookupBytes("GET", []byte("/src"))
+		if handle != nil || !tsr {
+			t.Errorf("expected TSR recommendation, got %v, %t", handle, tsr)
+		}
+
+		if handle, _, _ := router.LookupBytes("GET", nil); handle != nil {
+			t.Errorf("got handle for empty path: %v", handle)
+		}
+		if handle, ps, tsr := router.LookupBytes("GET", []byte{}); handle != nil || ps != nil || tsr {
+			t.Errorf("wrong result for empty path: %v, %v, %t", handle, ps, tsr)
+		}
+
+		path = []byte("/src")
+		if allocs := testing.AllocsPerRun(100, func() {
+			router.LookupBytes("GET", path)
+		}); allocs != 0 {
+			t.Errorf("LookupBytes without params allocates: %v allocs", allocs)
+		}
+	})
+
+	// neither the root nor a default covering it match an empty path
+	router = New()
+	router.GET("/", "root")
+	router.SubtreeDefault("/", "default")
+	for _, path := range [][]byte{nil, {}} {
+		if handle, ps, tsr := router.LookupBytes("GET", path); handle != nil || ps != nil || tsr {
+			t.Errorf("wrong result for empty path %#v: %v, %v, %t", path, handle, ps, tsr)
+		}
+	}
+}
+
+func TestRouterHandleTypes(t *testing.T) {
+	router := New()
+	write := func(w http.ResponseWriter, shape string, ps Params) {
+		io.WriteString(w, shape+" "+ps.ByName("name"))
+	}
+	router.GET("/func3/:name", func(w http.ResponseWriter, _ *http.Request, ps Params) {
+		write(w, "func3", ps)
+	})
+	router.GET("/handler/:name", http.Handler(handlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		write(w, "handler", ParamsFromContext(req.Context()))
+	})))
+	router.GET("/handlerfunc/:name", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		write(w, "handlerfunc", ParamsFromContext(req.Context()))
+	}))
+	router.GET("/func2/:name", func(w http.ResponseWriter, req *http.Request) {
+		write(w, "func2", ParamsFromContext(req.Context()))
+	})
+	router.GET("/unknown/:name", 42)
+
+	for _, shape := range []string{"func3", "handler", "handlerfunc", "func2"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/"+shape+"/gopher", nil))
+		if want := shape + " gopher"; w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("wrong response for %s: %d %q", shape, w.Code, w.Body.String())
+		}
+
+		// also without ServeHTTP
+		h, _, _ := router.HTTPHandlerFor("GET", "/"+shape+"/gopher")
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if want := shape + " gopher"; w.Body.String() != want {
+			t.Errorf("wrong response for %s through HTTPHandlerFor: %q", shape, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/unknown/gopher", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("wrong code for an unknown handle type: %d", w.Code)
+	}
+
+	var got interface{}
+	router.UnknownHandleType = func(w http.ResponseWriter, req *http.Request, handle interface{}) {
+		got = handle
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/unknown/gopher", nil))
+	if w.Code != http.StatusNotImplemented || got != 42 {
+		t.Errorf("hook not called: %d, %v", w.Code, got)
+	}
+}
+
+// handlerFunc is a http.Handler of another type than http.HandlerFunc.
+type handlerFunc func(http.ResponseWriter, *http.Request)
+
+func (f handlerFunc) ServeHTTP(w http.ResponseWriter, req *http.Request) { f(w, req) }
+
+func TestRouterNotAllowed(t *testing.T) {
+	router := New()
+	router.POST("/path", "post")
+	router.PUT("/path", "put")
+	router.GET("/other", "get")
+
+	// off by default
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/path", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("wrong code: %d", w.Code)
+	}
+
+	router.HandleMethodNotAllowed = true
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/path", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "POST, PUT" {
+		t.Errorf("wrong response: %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	// custom handlers
+	router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	for path, code := range map[string]int{"/path": http.StatusTeapot, "/nope": http.StatusGone} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("DELETE", path, nil))
+		if w.Code != code {
+			t.Errorf("wrong code for %s: %d, want %d", path, w.Code, code)
+		}
+	}
+}
+
+func TestRouterNotFoundParams(t *testing.T) {
+	router := New()
+	router.GET("/users/:id/things/:thing", "thing")
+	var got Params
+	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = ParamsFromContext(req.Context())
+		http.NotFound(w, req)
+	})
+
+	// off by default
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42/widgets", nil))
+	if got != nil {
+		t.Errorf("params passed without NotFoundParams: %v", got)
+	}
+
+	router.NotFoundParams = true
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/users/42/widgets", nil))
+	if want := (Params{{"id", "42"}}); w.Code != http.StatusNotFound || !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong response: %d, params %v, want %v", w.Code, got, want)
+	}
+
+	got = nil
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+	if got != nil {
+		t.Errorf("params for a path matching no prefix: %v", got)
+	}
+}
+
+func TestRouterStaticRoutes(t *testing.T) {
+	router := New()
+	router.ConcurrentRegistration = true
+	router.GET("/static/route", "static")
+	router.GET("/user/:name", "param")
+	router.GET("/dir/", "dir")
+
+	if rt := router.tree("GET").static.get("/static/route"); rt == nil || rt.Handle != "static" {
+		t.Fatalf("static route not in map: %v", rt)
+	}
+	if rt := router.tree("GET").static.get("/user/:name"); rt != nil {
+		t.Fatalf("route with parameters in static map: %v", rt)
+	}
+
+	tests := []struct {
+		path   string
+		handle interface{}
+		tsr    bool
+	}{
+		{"/static/route", "static", false},
+		{"/user/gopher", "param", false},
+		{"/dir/", "dir", false},
+		{"/dir", nil, true},
+		{"/static/route/", nil, true},
+	}
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		for _, test := range tests {
+			handle, _, tsr := router.Lookup("GET", test.path)
+			if handle != test.handle || tsr != test.tsr {
+				t.Errorf("wrong result for %s: got %v, %t; want %v, %t", test.path, handle, tsr, test.handle, test.tsr)
+			}
+		}
+	})
+
+	// failed registrations must not end up in the map
+	router.GET("/static/route", "duplicate")
+	if handle, _, _ := router.Lookup("GET", "/static/route"); handle != "static" {
+		t.Errorf("duplicate registration replaced static route: %v", handle)
+	}
+}
+
+func TestRouterReplace(t *testing.T) {
+	router := New()
+	router.GET("/user/:name", "v1")
+	router.GET("/static", "v1")
+	router.HandleOptions("GET", "/item/:id", "v1", RouteOptions{
+		Validate: map[string]func(string) error{"id": checkULID},
+	})
+
+	for _, path := range [...]string{"/user/:name", "/static", "/item/:id"} {
+		if err := router.Replace("GET", path, "v2"); err != nil {
+			t.Fatalf("replacing %s failed: %v", path, err)
+		}
+	}
+
+	if handle, ps, _ := router.Lookup("GET", "/user/gopher"); handle != "v2" || ps.ByName("name") != "gopher" {
+		t.Errorf("wrong result after replace: %v, %v", handle, ps)
+	}
+	if handle, _, _ := router.Lookup("GET", "/static"); handle != "v2" {
+		t.Errorf("wrong handle for static route after replace: %v", handle)
+	}
+	rt, _, _ := router.LookupRoute("GET", "/item/42")
+	if rt == nil || rt.Handle != "v2" || rt.Options.Validate["id"] == nil {
+		t.Errorf("options lost on replace: %+v", rt)
+	}
+
+	for _, path := range [...]string{"/user/gopher", "/user", "/nope"} {
+		if err := router.Replace("GET", path, "v3"); !errors.Is(err, ErrNotRegistered) {
+			t.Errorf("expected ErrNotRegistered replacing unregistered path %s, got %v", path, err)
+		}
+	}
+	if err := router.Replace("POST", "/static", "v3"); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("expected ErrNotRegistered replacing path of unregistered method, got %v", err)
+	}
+}
+
+func TestRouterReplaceConcurrent(t *testing.T) {
+	body := func(s string) Handle {
+		return func(w http.ResponseWriter, _ *http.Request, _ Params) {
+			w.Write([]byte(s))
+		}
+	}
+	router := New()
+	router.GET("/user/:name", body("blue"))
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest("GET", "/user/gopher", nil))
+				if b := w.Body.String(); b != "blue" && b != "green" {
+					t.Errorf("unexpected body: %q", b)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		color := "blue"
+		if i%2 == 0 {
+			color = "green"
+		}
+		if err := router.Replace("GET", "/user/:name", body(color)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestRouterRemove(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		router := New()
+		router.ConcurrentRegistration = true
+		router.GET("/user/:name", "user")
+		router.GET("/user/:name/posts", "posts")
+		router.GET("/static", "static")
+		router.GET("/files/*filepath", "files")
+		if compact {
+			router.Compact()
+		}
+
+		for _, path := range [...]string{"/user/:name", "/static", "/files/*filepath"} {
+			if err := router.Remove("GET", path); err != nil {
+				t.Fatalf("removing %s failed: %v", path, err)
+			}
+		}
+
+		for _, path := range [...]string{"/user/gopher", "/static", "/files/a.txt"} {
+			if handle, _, _ := router.Lookup("GET", path); handle != nil {
+				t.Errorf("%s still matches after removal: %v", path, handle)
+			}
+		}
+		if handle, ps, _ := router.Lookup("GET", "/user/gopher/posts"); handle != "posts" || ps.ByName("name") != "gopher" {
+			t.Errorf("wrong result for remaining route: %v, %v", handle, ps)
+		}
+
+		if err := router.Remove("GET", "/static"); !errors.Is(err, ErrNotRegistered) {
+			t.Errorf("expected ErrNotRegistered removing route twice, got %v", err)
+		}
+		if err := router.Remove("POST", "/user/:name/posts"); !errors.Is(err, ErrNotRegistered) {
+			t.Errorf("expected ErrNotRegistered removing path of unregistered method, got %v", err)
+		}
+		// neither a prefix of a route nor a path matching one
+		for _, path := range [...]string{"/user/", "/user/gopher/posts", "/user/:name/post"} {
+			if err := router.Remove("GET", path); !errors.Is(err, ErrNotRegistered) {
+				t.Errorf("expected ErrNotRegistered removing %s, got %v", path, err)
+			}
+		}
+
+		// removed paths can be registered again
+		if err := router.Handle("GET", "/files/*filepath", "files"); err != nil {
+			t.Errorf("registering removed path failed: %v", err)
+		}
+	}
+}
+
+func TestRouterLookupNoTSR(t *testing.T) {
+	router := loadRoutes(githubAPI)
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		for _, route := range githubAPI {
+			path := requestPath(route.path)
+			handle, ps, _ := router.Lookup(route.method, path)
+			gotHandle, gotPs := router.LookupNoTSR(route.method, path)
+			if gotHandle != handle || !reflect.DeepEqual(gotPs, ps) {
+				t.Errorf("wrong result for %s %s: got %v, %v; want %v, %v", route.method, path, gotHandle, gotPs, handle, ps)
+			}
+		}
+
+		// the first ones are TSR recommendations for Lookup
+		misses := [...]string{
+			"/user/keys/42/",
+			"/user/keys/",
+			"/repos/gopher/xrouter/nope",
+			"/nope",
+		}
+		for i, path := range misses {
+			if _, _, tsr := router.Lookup("GET", path); i < 2 && !tsr {
+				t.Fatalf("expected TSR recommendation for %s", path)
+			}
+			if handle, ps := router.LookupNoTSR("GET", path); handle != nil || ps != nil {
+				t.Errorf("got result for %s: %v, %v", path, handle, ps)
+			}
+		}
+	})
+}
+
+func TestRouterServeFiles(t *testing.T) {
+	dir := t.TempDir()
+	content := "body { color: red }\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.css"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
 	}
-	if !reflect.DeepEqual(params, wantParams) {
-		t.Fatalf("Wrong parameter values: want %v, got %v", wantParams, params)
+
+	router := New()
+	if err := router.ServeFiles("/noFilepath", http.Dir(dir)); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("wrong error for path without /*filepath: %v", err)
+	}
+	var seen string
+	router.Use(func(next Handle) Handle {
+		return func(w http.ResponseWriter, req *http.Request, ps Params) {
+			next(w, req, ps)
+			seen = req.URL.Path
+		}
+	})
+	if err := router.ServeFiles("/static/*filepath", http.Dir(dir)); err != nil {
+		t.Fatal(err)
 	}
 
-	handle, _, tsr = router.Lookup("GET", "/user/gopher/")
-	if handle != nil {
-		t.Fatalf("Got handle for unregistered pattern: %v", handle)
+	for _, method := range []string{"GET", "HEAD"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/static/main.css", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("wrong status for %s: %d", method, w.Code)
+		}
+		if got := w.Header().Get("Content-Length"); got != strconv.Itoa(len(content)) {
+			t.Errorf("wrong Content-Length for %s: %s", method, got)
+		}
+		wantBody := content
+		if method == "HEAD" {
+			wantBody = ""
+		}
+		if w.Body.String() != wantBody {
+			t.Errorf("wrong body for %s: %q", method, w.Body)
+		}
+		// the request of the middleware is left alone
+		if seen != "/static/main.css" {
+			t.Errorf("request rewritten for the middleware: %s", seen)
+		}
 	}
-	if !tsr {
-		t.Error("Got no TSR recommendation!")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("HEAD", "/static/missing.css", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("wrong status for a missing file: %d", w.Code)
 	}
+}
+
+func TestRouterLookupFunc(t *testing.T) {
+	router := loadRoutes(githubAPI)
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		for _, route := range githubAPI {
+			path := requestPath(route.path)
+			handle, ps, _ := router.Lookup(route.method, path)
+			var got Params
+			gotHandle, tsr := router.LookupFunc(route.method, path, func(key, value string) {
+				got = append(got, Param{key, value})
+			})
+			if gotHandle != handle || tsr || !reflect.DeepEqual(got, ps) {
+				t.Errorf("wrong result for %s %s: got %v, %v, %v; want %v, %v", route.method, path, gotHandle, got, tsr, handle, ps)
+			}
+		}
 
-	handle, _, tsr = router.Lookup("GET", "/nope")
-	if handle != nil {
-		t.Fatalf("Got handle for unregistered pattern: %v", handle)
+		// values captured before the walk fails are not visited
+		misses := [...]struct {
+			path string
+			tsr  bool
+		}{
+			{"/user/keys/42/", true},
+			{"/repos/gopher/xrouter/nope", false},
+			{"/repos/gopher/xrouter/issues/42/nope", false},
+		}
+		for _, miss := range misses {
+			visited := false
+			handle, tsr := router.LookupFunc("GET", miss.path, func(key, value string) { visited = true })
+			if handle != nil || tsr != miss.tsr || visited {
+				t.Errorf("wrong result for %s: got %v, %v, visited %v; want nil, %v", miss.path, handle, tsr, visited, miss.tsr)
+			}
+		}
+	})
+
+	router.ConcurrentRegistration = true
+	if err := router.LocalePrefix([]string{"en", "de"}); err != nil {
+		t.Fatal(err)
 	}
-	if tsr {
-		t.Error("Got wrong TSR recommendation!")
+	var got Params
+	router.LookupFunc("GET", "/de/users/gopher", func(key, value string) {
+		got = append(got, Param{key, value})
+	})
+	if want := (Params{{LocaleParam, "de"}, {"user", "gopher"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong params with locale: got %v, want %v", got, want)
 	}
 }
+
+func TestRouterCustomMethod(t *testing.T) {
+	router := New()
+	purged := ""
+	router.Handle("PURGE", "/cache/:key", Handle(func(_ http.ResponseWriter, _ *http.Request, ps Params) {
+		purged = ps.ByName("key")
+	}))
+	router.GET("/cache/:key", "get")
+
+	if router.otherTrees["PURGE"] == nil {
+		t.Fatal("custom method not stored in the map")
+	}
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		purged = ""
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("PURGE", "/cache/users", nil))
+		if w.Code != http.StatusOK || purged != "users" {
+			t.Errorf("wrong result for custom method: status %d, key %q", w.Code, purged)
+		}
+		if handle, _, _ := router.Lookup("GET", "/cache/users"); handle != "get" {
+			t.Errorf("wrong handle for standard method: %v", handle)
+		}
+		if handle, _, _ := router.Lookup("BAN", "/cache/users"); handle != nil {
+			t.Errorf("got handle for unregistered method: %v", handle)
+		}
+	})
+
+	if err := router.Remove("PURGE", "/cache/:key"); err != nil {
+		t.Fatalf("removing route of custom method failed: %v", err)
+	}
+	if handle, _, _ := router.Lookup("PURGE", "/cache/users"); handle != nil {
+		t.Errorf("got handle for removed route: %v", handle)
+	}
+}
+
+func TestRouterMaxParams(t *testing.T) {
+	router := New()
+	router.GET("/user/:name", "user")
+	router.GET("/repos/:owner/:repo/issues/:number", "issue")
+	router.GET("/src/*filepath", "src")
+	router.MaxParams = 2
+
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		tests := []struct {
+			path   string
+			handle interface{}
+		}{
+			{"/user/gopher", "user"},
+			{"/src/some/deep/file.go", "src"},
+			{"/repos/gopher/xrouter/issues/42", nil},
+		}
+		for _, test := range tests {
+			if handle, ps, _ := router.Lookup("GET", test.path); handle != test.handle || (handle == nil && ps != nil) {
+				t.Errorf("wrong result for %s: got %v, %v; want %v", test.path, handle, ps, test.handle)
+			}
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/repos/gopher/xrouter/issues/42", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("wrong status code for request exceeding the limit: %d", w.Code)
+		}
+	})
+}
+
+func TestRouterRedirectTrailingSlashQuery(t *testing.T) {
+	router := New()
+	router.GET("/search", Handle(func(http.ResponseWriter, *http.Request, Params) {}))
+	router.GET("/files/:name/", Handle(func(http.ResponseWriter, *http.Request, Params) {}))
+
+	tests := []struct {
+		path     string
+		location string
+	}{
+		{"/search/?q=x", "/search?q=x"},
+		{"/search/?q=a%20b&page=2&x=%2F", "/search?q=a%20b&page=2&x=%2F"},
+		{"/search/?", "/search?"},
+		{"/files/a%20b?download=1", "/files/a%20b/?download=1"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != test.location {
+			t.Errorf("%s: got %d to %q; want %d to %q", test.path, w.Code, w.Header().Get("Location"), http.StatusMovedPermanently, test.location)
+		}
+	}
+}
+
+func TestRouterRedirectTrailingSlashOpenRedirect(t *testing.T) {
+	for _, decode := range []bool{false, true} {
+		router := New()
+		router.DecodeParams = decode
+		router.GET("/:a/:b", "ab")
+		router.GET("/:a/:b/:c/", "abc")
+
+		for _, test := range []struct {
+			path, location string
+		}{
+			{"//evil.com/", "/evil.com"},
+			{"///evil.com", "/evil.com/"},
+			{"//evil.com/x", "/evil.com/x/"},
+			{"/%2Fevil.com/", "/%2Fevil.com"},
+			{"/\\evil.com/", "/%5Cevil.com"},
+		} {
+			req := httptest.NewRequest("GET", "/", nil)
+			u, err := url.ParseRequestURI(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.URL = u
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusMovedPermanently {
+				t.Errorf("%s (DecodeParams %t): got status %d, want %d", test.path, decode, w.Code, http.StatusMovedPermanently)
+			} else if location := w.Header().Get("Location"); location != test.location {
+				t.Errorf("%s (DecodeParams %t): redirected to %q, want %q", test.path, decode, location, test.location)
+			}
+		}
+	}
+}
+
+func TestRouterStrictSlash(t *testing.T) {
+	var served string
+	handle := func(name string) Handle {
+		return func(http.ResponseWriter, *http.Request, Params) { served = name }
+	}
+
+	router := New()
+	router.StrictSlash("POST", true)
+	router.GET("/users", handle("GET /users"))
+	router.GET("/hooks/", handle("GET /hooks/"))
+	router.POST("/users", handle("POST /users"))
+	router.POST("/hooks", handle("POST /hooks"))
+	router.POST("/hooks/", handle("POST /hooks/"))
+	router.PUT("/users", handle("PUT /users"))
+
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		tests := []struct {
+			method   string
+			path     string
+			code     int
+			served   string
+			location string
+		}{
+			{"GET", "/users", http.StatusOK, "GET /users", ""},
+			{"GET", "/users/?page=2", http.StatusMovedPermanently, "", "/users?page=2"},
+			{"GET", "/hooks?x=1", http.StatusMovedPermanently, "", "/hooks/?x=1"},
+			{"PUT", "/users/", http.StatusTemporaryRedirect, "", "/users"},
+			{"POST", "/users", http.StatusOK, "POST /users", ""},
+			{"POST", "/users/?page=2", http.StatusNotFound, "", ""},
+			{"POST", "/hooks", http.StatusOK, "POST /hooks", ""},
+			{"POST", "/hooks/", http.StatusOK, "POST /hooks/", ""},
+		}
+		for _, test := range tests {
+			served = ""
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+			if w.Code != test.code || served != test.served || w.Header().Get("Location") != test.location {
+				t.Errorf("%s %s: got %d, served %q, location %q; want %d, %q, %q",
+					test.method, test.path, w.Code, served, w.Header().Get("Location"),
+					test.code, test.served, test.location)
+			}
+		}
+
+		if _, _, tsr := router.Lookup("GET", "/users/"); !tsr {
+			t.Error("expected TSR recommendation for GET")
+		}
+		if _, _, tsr := router.Lookup("POST", "/users/"); tsr {
+			t.Error("got TSR recommendation for strict POST")
+		}
+	})
+
+	router.RedirectTrailingSlash = false
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/users/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("wrong status code with redirects disabled: %d", w.Code)
+	}
+}
+
+// TestRouterHijack verifies that handles receive the http.ResponseWriter of the
+// server unwrapped, so connections can be hijacked, e.g. to upgrade to
+// websocket.
+func TestRouterHijack(t *testing.T) {
+	upgrade := func(w http.ResponseWriter, req *http.Request, ps Params) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		buf.WriteString("hello " + ps.ByName("name"))
+		buf.Flush()
+	}
+
+	router := New()
+	router.GET("/ws/:name", Handle(upgrade))
+	router.GET("/handler/:name", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		upgrade(w, req, ParamsFromContext(req.Context()))
+	}))
+	router.Use(func(next Handle) Handle {
+		return func(w http.ResponseWriter, req *http.Request, ps Params) {
+			w.Header().Set("X-Middleware", "1")
+			next(w, req, ps)
+		}
+	})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	for _, path := range [...]string{"/ws/gopher", "/handler/gopher"} {
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(conn, "GET "+path+" HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		// the raw bytes written after the upgrade
+		body, err := io.ReadAll(br)
+		conn.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols || string(body) != "hello gopher" {
+			t.Errorf("%s: got %d %q", path, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestRouterErrors(t *testing.T) {
+	tests := []struct {
+		existing []string
+		path     string
+		err      error
+		conflict *ConflictError
+	}{
+		{nil, "user", ErrInvalidPath, nil},
+		{nil, "", ErrInvalidPath, nil},
+		{nil, "/user/:", ErrInvalidWildcard, nil},
+		{nil, "/user/:a:b", ErrInvalidWildcard, nil},
+		{nil, "/src/*filepath/x", ErrCatchAllPosition, nil},
+		{nil, "/src*filepath", ErrCatchAllPosition, nil},
+		{[]string{"/user/:id"}, "/user/:id", ErrConflict, &ConflictError{Path: "/user/:id", Existing: "/user/:id", Method: "GET"}},
+		{[]string{"/user/:id/repos"}, "/user/:name", ErrConflict, &ConflictError{Path: "/user/:name", Existing: "/user/:id/repos", Method: "GET"}},
+		{[]string{"/user/new"}, "/user/:id", ErrConflict, &ConflictError{Path: "/user/:id", Existing: "/user/new", Method: "GET"}},
+		{[]string{"/src/*filepath"}, "/src/static", ErrConflict, &ConflictError{Path: "/src/static", Existing: "/src/*filepath", Method: "GET"}},
+		{[]string{"/src/"}, "/src/*filepath", ErrConflict, &ConflictError{Path: "/src/*filepath", Existing: "/src/", Method: "GET"}},
+	}
+	for _, test := range tests {
+		router := New()
+		for _, path := range test.existing {
+			if err := router.GET(path, "existing"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := router.GET(test.path, "new")
+		if !errors.Is(err, test.err) {
+			t.Errorf("wrong error for '%s': got %v, want %v", test.path, err, test.err)
+			continue
+		}
+		var conflict *ConflictError
+		if errors.As(err, &conflict) != (test.conflict != nil) {
+			t.Errorf("wrong error type for '%s': %T", test.path, err)
+		} else if conflict != nil {
+			if conflict.Path != test.conflict.Path || conflict.Existing != test.conflict.Existing || conflict.Method != test.conflict.Method {
+				t.Errorf("wrong conflict for '%s': got %+v, want %+v", test.path, conflict, test.conflict)
+			}
+			// both patterns are named
+			want := fmt.Sprintf("'%s' conflicts with existing route '%s' registered for GET", test.path, conflict.Existing)
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error for '%s' does not name the existing route: %q", test.path, err)
+			}
+		}
+	}
+}
+
+// TestRouterWrappedErrors checks that the sentinel errors and typed errors
+// can be found through the context added by wrapping them.
+func TestRouterWrappedErrors(t *testing.T) {
+	router := New()
+	router.GET("/user/:id", "user")
+
+	tests := []struct {
+		err     error
+		target  error
+		context []string
+	}{
+		{router.GET("user", "h"), ErrInvalidPath, []string{"'user'"}},
+		{router.Handle("GE T", "/x", "h"), ErrInvalidMethod, []string{"'GE T'", "'/x'"}},
+		{router.GET("/user/:name", "h"), ErrConflict, []string{"'/user/:name'", "'/user/:id'", "GET"}},
+		{router.Replace("GET", "/user/:id", nil), ErrNilHandle, []string{"'/user/:id'"}},
+		{fmt.Errorf("loading routes: %w", router.GET("/user/:id", "h")), ErrConflict, []string{"loading routes: ", "'/user/:id'"}},
+	}
+	for _, test := range tests {
+		if !errors.Is(test.err, test.target) {
+			t.Errorf("%v does not match %v", test.err, test.target)
+		}
+		for _, want := range test.context {
+			if !strings.Contains(fmt.Sprint(test.err), want) {
+				t.Errorf("%v lacks the context %s", test.err, want)
+			}
+		}
+	}
+
+	var conflict *ConflictError
+	if err := fmt.Errorf("wrapped: %w", router.GET("/user/:id", "h")); !errors.As(err, &conflict) || conflict.Path != "/user/:id" {
+		t.Errorf("no ConflictError found in %v", err)
+	}
+}
+
+func TestRouterPathErrors(t *testing.T) {
+	tests := []struct {
+		path    string
+		err     error
+		segment int
+		offset  int
+		msg     string
+	}{
+		{"/users/:/posts", ErrInvalidWildcard, 1, 7, "wildcards must be named with a non-empty name, has: ':'"},
+		{"/users/:", ErrInvalidWildcard, 1, 7, "wildcards must be named with a non-empty name, has: ':'"},
+		{"/:", ErrInvalidWildcard, 0, 1, "wildcards must be named with a non-empty name, has: ':'"},
+		{"/files/*", ErrInvalidWildcard, 1, 7, "wildcards must be named with a non-empty name, has: '*'"},
+		{"/a/:b:c", ErrInvalidWildcard, 1, 5, "only one wildcard per path segment is allowed, has: ':b:c'"},
+		{"/a/:b*c", ErrInvalidWildcard, 1, 5, "only one wildcard per path segment is allowed, has: ':b*c'"},
+		{"/a/*b:c", ErrInvalidWildcard, 1, 5, "only one wildcard per path segment is allowed, has: '*b:c'"},
+		{"/a/b/x:y:z/c", ErrInvalidWildcard, 2, 8, "only one wildcard per path segment is allowed, has: ':y:z'"},
+		{"/files/*path/x", ErrCatchAllPosition, 1, 7, "catch-all routes are only allowed at the end of the path, followed by '/x'"},
+		{"/files/*path/", ErrCatchAllPosition, 1, 7, "catch-all routes must not have a trailing slash, which the catch-all value holds"},
+		{"/files*path", ErrCatchAllPosition, 0, 6, "no / before catch-all"},
+		{"/a/:b/files/x*", ErrInvalidWildcard, 3, 13, "wildcards must be named with a non-empty name, has: '*'"},
+	}
+	existing := []string{"/users/:id", "/files/static", "/a/:b/c"}
+	sibling := "/users/:id/posts"
+
+	// the trees are compared, including the sources of the routes
+	reference := New()
+	reference.DisableCallerSource = true
+	for _, path := range append(existing, sibling) {
+		if err := reference.GET(path, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range tests {
+		router := New()
+		router.DisableCallerSource = true
+		for _, path := range existing {
+			if err := router.GET(path, path); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := router.GET(test.path, "new")
+		var pathErr *PathError
+		if !errors.Is(err, test.err) || !errors.As(err, &pathErr) {
+			t.Errorf("wrong error for '%s': got %v, want %v", test.path, err, test.err)
+			continue
+		}
+		if pathErr.Path != test.path || pathErr.Segment != test.segment || pathErr.Offset != test.offset {
+			t.Errorf("wrong position for '%s': got segment %d, offset %d, want segment %d, offset %d",
+				test.path, pathErr.Segment, pathErr.Offset, test.segment, test.offset)
+		}
+		want := fmt.Sprintf("%s, at segment %d, offset %d in path '%s'", test.msg, test.segment, test.offset, test.path)
+		if err.Error() != want {
+			t.Errorf("wrong error text for '%s':\n got %q\nwant %q", test.path, err, want)
+		}
+
+		// the tree is left untouched
+		if err := router.GET(sibling, sibling); err != nil {
+			t.Errorf("registering '%s' after '%s' failed: %v", sibling, test.path, err)
+		}
+		if !reflect.DeepEqual(router.tree("GET").root, reference.tree("GET").root) {
+			t.Errorf("tree modified by '%s'", test.path)
+		}
+	}
+}
+
+func TestRouterPathChars(t *testing.T) {
+	tests := []struct {
+		path    string
+		segment int
+		offset  int
+		msg     string
+	}{
+		{"/users /:id", 0, 6, "whitespace is not allowed"},
+		{"/users/ :id", 1, 7, "whitespace is not allowed"},
+		{"/users/\t", 1, 7, "whitespace is not allowed"},
+		{"/users/:id\n", 1, 10, `control character '\n' is not allowed`},
+		{"/a\x00b", 0, 2, `control character '\x00' is not allowed`},
+		{"/a\x7f", 0, 2, `control character '\x7f' is not allowed`},
+		{"//", 0, 1, "empty path segments are not allowed"},
+		{"/a//b", 1, 3, "empty path segments are not allowed"},
+		{"/a/b//", 2, 5, "empty path segments are not allowed"},
+		{"/caf\xe9", 0, 4, "invalid UTF-8"},
+		{"/a/\xf0\x9f\x98/b", 1, 3, "invalid UTF-8"},
+	}
+	for _, test := range tests {
+		router := New()
+		err := router.GET(test.path, "h")
+		var pathErr *PathError
+		if !errors.Is(err, ErrInvalidPath) || !errors.As(err, &pathErr) {
+			t.Errorf("wrong error for %q: %v", test.path, err)
+			continue
+		}
+		if pathErr.Segment != test.segment || pathErr.Offset != test.offset {
+			t.Errorf("wrong position for %q: got segment %d, offset %d, want segment %d, offset %d",
+				test.path, pathErr.Segment, pathErr.Offset, test.segment, test.offset)
+		}
+		want := fmt.Sprintf("%s, at segment %d, offset %d in path '%s'", test.msg, test.segment, test.offset, test.path)
+		if err.Error() != want {
+			t.Errorf("wrong error text for %q:\n got %q\nwant %q", test.path, err, want)
+		}
+
+		// permissive mode
+		router = New()
+		router.PermissivePaths = true
+		if err := router.GET(test.path, "h"); err != nil {
+			t.Errorf("permissive registration of %q failed: %v", test.path, err)
+		}
+		if handle, _, _ := router.Lookup("GET", requestPath(test.path)); handle != "h" {
+			t.Errorf("permissive route %q not found", test.path)
+		}
+	}
+
+	// valid paths
+	router := New()
+	for _, path := range []string{"/", "/users/", "/caf\u00e9/:id", "/%20/x", "/a-b_c.d~e"} {
+		if err := router.GET(path, "h"); err != nil {
+			t.Errorf("valid path %q rejected: %v", path, err)
+		}
+	}
+}
+
+func TestRouterDuplicateParams(t *testing.T) {
+	tests := []struct {
+		path          string
+		name          string
+		first, second int
+	}{
+		{"/orgs/:id/repos/:id", "id", 6, 16},
+		{"/orgs/:id/repos/:name/:id", "id", 6, 22},
+		{"/:a/:b/:b", "b", 4, 7},
+		{"/files/:path/*path", "path", 7, 13},
+	}
+	for _, test := range tests {
+		router := New()
+		err := router.GET(test.path, "h")
+		var dup *DuplicateParamError
+		if !errors.Is(err, ErrDuplicateParam) || !errors.As(err, &dup) {
+			t.Errorf("wrong error for '%s': %v", test.path, err)
+			continue
+		}
+		if dup.Path != test.path || dup.Name != test.name || dup.First != test.first || dup.Second != test.second {
+			t.Errorf("wrong error for '%s': got %+v", test.path, dup)
+		}
+		want := fmt.Sprintf("duplicate parameter name: '%s' at offset %d repeats the wildcard at offset %d in path '%s'",
+			test.name, test.second, test.first, test.path)
+		if err.Error() != want {
+			t.Errorf("wrong error text for '%s': %q", test.path, err)
+		}
+		if router.tree("GET") != nil {
+			t.Errorf("tree modified by '%s'", test.path)
+		}
+
+		// opting out
+		router = New()
+		router.AllowDuplicateParams = true
+		if err := router.GET(test.path, "h"); err != nil {
+			t.Errorf("registering '%s' failed despite AllowDuplicateParams: %v", test.path, err)
+		}
+	}
+
+	// names which only share a prefix
+	router := New()
+	for _, path := range []string{"/orgs/:id/repos/:idx", "/users/:user/*username"} {
+		if err := router.GET(path, "h"); err != nil {
+			t.Errorf("distinct names rejected for '%s': %v", path, err)
+		}
+	}
+}
+
+func TestRouterCatchAllPosition(t *testing.T) {
+	tests := []struct {
+		path      string
+		segment   int
+		remainder string
+	}{
+		{"/src/*filepath/x", 1, "/x"},
+		{"/src/*filepath/:name", 1, "/:name"},
+		{"/*filepath/a/b", 0, "/a/b"},
+		{"/a/b/c/*rest/d/", 3, "/d/"},
+
+		// the catch-all value would hold the trailing slash
+		{"/x/*y/", 1, "/"},
+	}
+	for _, test := range tests {
+		router := New()
+		err := router.GET(test.path, "h")
+		var pathErr *PathError
+		if !errors.Is(err, ErrCatchAllPosition) || !errors.As(err, &pathErr) {
+			t.Errorf("wrong error for '%s': %v", test.path, err)
+			continue
+		}
+		if pathErr.Path != test.path || pathErr.Segment != test.segment || pathErr.Remainder != test.remainder {
+			t.Errorf("wrong error for '%s': got segment %d, remainder '%s', want segment %d, remainder '%s'",
+				test.path, pathErr.Segment, pathErr.Remainder, test.segment, test.remainder)
+		}
+		if test.remainder != "/" && !strings.Contains(err.Error(), "followed by '"+test.remainder+"'") {
+			t.Errorf("error for '%s' does not name the remainder: %v", test.path, err)
+		}
+	}
+
+	// valid at the end of the path
+	router := New()
+	if err := router.GET("/x/*y", "h"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ps, _ := router.Lookup("GET", "/x/a/b/"); ps.ByName("y") != "/a/b/" {
+		t.Errorf("wrong catch-all value: %v", ps)
+	}
+}
+
+func TestRouterCatchAllConflictRoutes(t *testing.T) {
+	router := New()
+	router.DisableCallerSource = true
+	router.GET("/files/images/logo.png", "logo")
+	router.GET("/files/css/site.css", "css")
+
+	err := router.GET("/files/*path", "files")
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Existing != "/files/images/logo.png" ||
+		!reflect.DeepEqual(conflict.Others, []string{"GET /files/css/site.css"}) || conflict.More != 0 {
+		t.Fatalf("wrong conflict: %#v", err)
+	}
+	if want := "'GET /files/images/logo.png', 'GET /files/css/site.css'"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error doesn't list the routes: %v", err)
+	}
+
+	// capped at maxConflictRoutes
+	for i := 0; i < maxConflictRoutes+1; i++ {
+		router.GET(fmt.Sprintf("/files/css/%d.css", i), "css")
+	}
+	err = router.GET("/files/*path", "files")
+	if !errors.As(err, &conflict) || len(conflict.Others) != maxConflictRoutes-1 || conflict.More != 3 {
+		t.Fatalf("wrong conflict: %#v", err)
+	}
+	if !strings.HasSuffix(err.Error(), ", +3 more") {
+		t.Errorf("error doesn't count the omitted routes: %v", err)
+	}
+
+	// static routes below an existing catch-all
+	router = New()
+	router.DisableCallerSource = true
+	router.GET("/files/*path", "files")
+	err = router.GET("/files/images/logo.png", "logo")
+	if !errors.As(err, &conflict) || conflict.Existing != "/files/*path" || len(conflict.Others) != 0 {
+		t.Fatalf("wrong conflict: %#v", err)
+	}
+	if want := "path '/files/images/logo.png' is shadowed by existing catch-all '/files/*path'"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("wrong error: %v", err)
+	}
+}
+
+func TestRouterNilHandle(t *testing.T) {
+	router := New()
+	var handler *handlerStruct
+	for _, handle := range []interface{}{nil, Handle(nil), http.HandlerFunc(nil), handler} {
+		if err := router.GET("/nil", handle); !errors.Is(err, ErrNilHandle) {
+			t.Errorf("wrong error for %#v: %v", handle, err)
+		}
+	}
+	if router.tree("GET") != nil {
+		t.Error("nil handle registered")
+	}
+
+	// reserving paths
+	if err := router.GET("/reserved", Placeholder{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.GET("/users/:id", Placeholder{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.GET("/reserved", "other"); !errors.Is(err, ErrConflict) {
+		t.Errorf("reserved path registered again: %v", err)
+	}
+	if err := router.GET("/users/:name", "other"); !errors.Is(err, ErrConflict) {
+		t.Errorf("route conflicting with reserved path registered: %v", err)
+	}
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		for _, path := range []string{"/reserved", "/users/1"} {
+			if handle, ps, tsr := router.Lookup("GET", path); handle != nil || ps != nil || tsr {
+				t.Errorf("lookup of reserved %s: got %v, %v, %v", path, handle, ps, tsr)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if w.Code != http.StatusNotFound {
+				t.Errorf("wrong status code for reserved %s: %d", path, w.Code)
+			}
+		}
+	})
+
+	if err := router.Replace("GET", "/reserved", nil); !errors.Is(err, ErrNilHandle) {
+		t.Errorf("wrong error replacing with nil: %v", err)
+	}
+	if err := router.Replace("GET", "/reserved", "now"); err != nil {
+		t.Fatal(err)
+	}
+	if handle, _, _ := router.Lookup("GET", "/reserved"); handle != "now" {
+		t.Errorf("wrong handle after replacing placeholder: %v", handle)
+	}
+}
+
+func TestRouterMethodNormalization(t *testing.T) {
+	router := New()
+	if err := router.Handle("get", "/user/:name", "user"); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.Handle("purge", "/cache", "purge"); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{"", "GE T", "GET\n", "G(ET)"} {
+		if err := router.Handle(method, "/x", "x"); !errors.Is(err, ErrInvalidMethod) {
+			t.Errorf("wrong error for method %q: %v", method, err)
+		}
+	}
+	if err := router.Handle("M-SEARCH", "/x", "x"); err != nil {
+		t.Errorf("unexpected error for method with token characters: %v", err)
+	}
+
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		tests := []struct {
+			method, path string
+			handle       interface{}
+		}{
+			{"GET", "/user/gopher", "user"},
+			{"get", "/user/gopher", "user"},
+			{"Get", "/user/gopher", "user"},
+			{"PURGE", "/cache", "purge"},
+			{"purge", "/cache", "purge"},
+		}
+		for _, test := range tests {
+			if handle, _, _ := router.Lookup(test.method, test.path); handle != test.handle {
+				t.Errorf("wrong handle for %s %s: got %v, want %v", test.method, test.path, handle, test.handle)
+			}
+		}
+		if rt, _, _ := router.LookupRoute("get", "/user/gopher"); rt == nil || rt.Method != "GET" {
+			t.Errorf("route not registered for the normalized method: %+v", rt)
+		}
+	})
+
+	if allocs := testing.AllocsPerRun(100, func() { router.Lookup("GET", "/cache") }); allocs != 0 {
+		t.Errorf("lookup of an upper-case method allocates: %v allocs", allocs)
+	}
+
+	// opting out
+	router = New()
+	router.CaseSensitiveMethods = true
+	router.Handle("get", "/user/:name", "lower")
+	router.Handle("GET", "/user/:name", "upper")
+	if handle, _, _ := router.Lookup("get", "/user/gopher"); handle != "lower" {
+		t.Errorf("wrong handle for case-sensitive method: %v", handle)
+	}
+	if handle, _, _ := router.Lookup("GET", "/user/gopher"); handle != "upper" {
+		t.Errorf("wrong handle for case-sensitive method: %v", handle)
+	}
+}
+
+func TestRouterMethodAliases(t *testing.T) {
+	var methods []string
+	handler := http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		methods = append(methods, req.Method)
+	})
+	router := New()
+	router.CaseSensitiveMethods = true
+	router.MethodAliases = map[string]string{"get": "GET", "Get": "GET", "DEL": "DELETE"}
+	router.GET("/user/:name", handler)
+	router.DELETE("/user/:name", handler)
+
+	forBoth(t, router, func(t *testing.T, r lookupRouter) {
+		methods = nil
+		for _, method := range []string{"GET", "get", "Get", "DEL", "DELETE"} {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(method, "/user/gopher", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("%s not routed: %d", method, w.Code)
+			}
+		}
+		// the handles see the method as sent
+		if want := []string{"GET", "get", "Get", "DEL", "DELETE"}; !reflect.DeepEqual(methods, want) {
+			t.Errorf("wrong methods: got %v, want %v", methods, want)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("gEt", "/user/gopher", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("method without alias routed: %d", w.Code)
+		}
+	})
+
+	// by default methods are upper-cased
+	router = New()
+	router.GET("/user/:name", handler)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("get", "/user/gopher", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("lower-case method not routed: %d", w.Code)
+	}
+}
+
+// FuzzRouterLookup checks that lookups never panic, whatever the path.
+func FuzzRouterLookup(f *testing.F) {
+	for _, route := range githubAPI {
+		f.Add(requestPath(route.path))
+	}
+	for _, path := range []string{"", "/", "//", "/%", "/users/\x00", "/src/", "/repos/a/b/c/d/e/f/g/h"} {
+		f.Add(path)
+	}
+
+	router := loadRoutes(githubAPI)
+	router.GET("/src/*filepath", "src")
+	compact := loadRoutes(githubAPI)
+	compact.GET("/src/*filepath", "src")
+	compact.Compact()
+	routers := []lookupRouter{router, compact, compact.snapshot()}
+
+	f.Fuzz(func(t *testing.T, path string) {
+		for _, r := range routers {
+			for _, method := range []string{"GET", "get", "PURGE"} {
+				r.Lookup(method, path)
+				r.LookupNoTSR(method, path)
+				r.LookupBytes(method, []byte(path))
+				r.LookupFunc(method, path, func(string, string) {})
+			}
+		}
+	})
+}
+
+// FuzzRouterHandle checks that registering any pattern returns an error
+// instead of panicking, and that registered patterns can be looked up.
+func FuzzRouterHandle(f *testing.F) {
+	for _, route := range githubAPI {
+		f.Add(route.path)
+	}
+	for _, path := range []string{"", "/", "/:", "/*", "/:a:b", "/*a/b", "/a*b", "/:a/*b/", "/user_:name", "/users/:id/*rest"} {
+		f.Add(path)
+	}
+
+	f.Fuzz(func(t *testing.T, path string) {
+		router := loadRoutes(githubAPI)
+		router.ConcurrentRegistration = true
+		if err := router.GET(path, "fuzz"); err != nil {
+			return
+		}
+		handle, _, _ := router.Lookup("GET", requestPath(path))
+		if handle == nil {
+			t.Errorf("registered pattern '%s' not found", path)
+		}
+	})
+}
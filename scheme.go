@@ -0,0 +1,47 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"strings"
+)
+
+// requestScheme returns the scheme of req, see SchemeHeader.
+func (r *Router) requestScheme(req *http.Request) string {
+	if r.SchemeHeader != "" {
+		if value := req.Header.Get(r.SchemeHeader); value != "" {
+			if i := strings.IndexByte(value, ','); i >= 0 {
+				value = value[:i]
+			}
+			return strings.TrimSpace(value)
+		}
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// allowsScheme reports whether the route serves requests with scheme, see
+// RouteOptions.Schemes.
+func (rt *Route) allowsScheme(scheme string) bool {
+	for _, s := range rt.Options.Schemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemeMismatch responds to a request whose scheme the matched route is not
+// restricted to, see SchemeMismatch.
+func (r *Router) schemeMismatch(w http.ResponseWriter, req *http.Request) {
+	if r.SchemeMismatch != nil {
+		r.SchemeMismatch.ServeHTTP(w, req)
+		return
+	}
+	http.NotFound(w, req)
+}
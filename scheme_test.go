@@ -0,0 +1,64 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterSchemes(t *testing.T) {
+	router := New()
+	router.SchemeHeader = "X-Forwarded-Proto"
+	ok := func(w http.ResponseWriter, _ *http.Request, _ Params) { w.WriteHeader(http.StatusNoContent) }
+	router.HandleOptions("GET", "/account", Handle(ok), RouteOptions{Schemes: []string{"https"}})
+	router.GET("/public", Handle(ok))
+
+	tests := []struct {
+		path, proto string
+		tls         bool
+		code        int
+	}{
+		{"/account", "https", false, http.StatusNoContent},
+		{"/account", "HTTPS", false, http.StatusNoContent},
+		{"/account", "https, http", false, http.StatusNoContent},
+		{"/account", "", true, http.StatusNoContent},
+		{"/account", "http", false, http.StatusNotFound},
+		{"/account", "", false, http.StatusNotFound},
+		{"/public", "http", false, http.StatusNoContent},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.path, nil)
+		if test.proto != "" {
+			req.Header.Set("X-Forwarded-Proto", test.proto)
+		}
+		if test.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != test.code {
+			t.Errorf("wrong code for %s with %q, TLS %v: got %d, want %d", test.path, test.proto, test.tls, w.Code, test.code)
+		}
+	}
+
+	// redirect to https
+	router.SchemeMismatch = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, "https://"+req.Host+req.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://example.com/account?x=1", nil)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "https://example.com/account?x=1" {
+		t.Errorf("wrong redirect: %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	// lookups are unaffected
+	if handle, _, _ := router.Lookup("GET", "/account"); handle == nil {
+		t.Error("lookup missed the route")
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"time"
+)
+
+// ServeEvent describes a request served by the handle of a route, see
+// OnServe.
+type ServeEvent struct {
+	Method string
+
+	// Pattern is the path the matched route was registered with, e.g.
+	// "/user/:name", so events of a route can be aggregated regardless of
+	// their parameter values.
+	Pattern string
+
+	Params Params
+
+	// Status is the status code of the response, 200 OK if the handle
+	// wrote nothing, and 101 Switching Protocols if it hijacked the
+	// connection without writing a status.
+	Status int
+
+	// Duration is the time the handle took, including middleware.
+	Duration time.Duration
+}
+
+// OnServe sets a function called by ServeHTTP after the handle of a matched
+// route returned, e.g. to write structured access logs. The status code is
+// captured by wrapping the http.ResponseWriter passed to the handle, which
+// supports http.Flusher, http.Hijacker and http.Pusher like the wrapped one, and
+// http.ResponseController through Unwrap. Without it, nothing is wrapped.
+// Requests which are not served by a handle are not reported, nor are health
+// checks, see Health.
+// OnServe must not be called while the router serves requests.
+func (r *Router) OnServe(fn func(ServeEvent)) {
+	r.onServe = fn
+}
+
+func (r *Router) reportServe(req *http.Request, rt *Route, ps Params, rec *statusRecorder, start time.Time) {
+	r.onServe(ServeEvent{
+		Method:   req.Method,
+		Pattern:  rt.Path,
+		Params:   ps,
+		Status:   rec.status(),
+		Duration: time.Since(start),
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// hijackRecorder is a httptest.ResponseRecorder supporting http.Hijacker.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestRouterOnServe(t *testing.T) {
+	router := New()
+	router.GET("/user/:name", func(w http.ResponseWriter, _ *http.Request, _ Params) {
+		w.Write([]byte("implicit"))
+	})
+	router.POST("/user/:name", func(w http.ResponseWriter, _ *http.Request, _ Params) {
+		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(http.StatusTeapot)
+	})
+	router.GET("/empty", func(http.ResponseWriter, *http.Request, Params) {})
+	router.GET("/stream", func(w http.ResponseWriter, _ *http.Request, _ Params) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("writer is no http.Flusher")
+		}
+		w.(http.Flusher).Flush()
+	})
+	router.GET("/ws", func(w http.ResponseWriter, _ *http.Request, _ Params) {
+		if _, _, err := http.NewResponseController(w).Hijack(); err != nil {
+			t.Errorf("hijacking failed: %v", err)
+		}
+	})
+
+	var events []ServeEvent
+	router.OnServe(func(e ServeEvent) {
+		e.Duration = 0
+		events = append(events, e)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/gopher", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/user/gopher", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/empty", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+	flushed := httptest.NewRecorder()
+	router.ServeHTTP(flushed, httptest.NewRequest("GET", "/stream", nil))
+	if !flushed.Flushed {
+		t.Error("Flush not passed through")
+	}
+	hijacked := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	router.ServeHTTP(hijacked, httptest.NewRequest("GET", "/ws", nil))
+	if !hijacked.hijacked {
+		t.Error("Hijack not passed through")
+	}
+
+	want := []ServeEvent{
+		{Method: "GET", Pattern: "/user/:name", Params: Params{{"name", "gopher"}}, Status: http.StatusOK},
+		{Method: "POST", Pattern: "/user/:name", Params: Params{{"name", "gopher"}}, Status: http.StatusCreated},
+		{Method: "GET", Pattern: "/empty", Status: http.StatusOK},
+		{Method: "GET", Pattern: "/stream", Status: http.StatusOK},
+		{Method: "GET", Pattern: "/ws", Status: http.StatusSwitchingProtocols},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("wrong events:\ngot  %+v\nwant %+v", events, want)
+	}
+}
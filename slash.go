@@ -0,0 +1,58 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StrictSlash sets whether trailing slashes are significant for the routes of
+// method. If strict, a path with and without a trailing slash are distinct
+// paths: lookups never recommend a trailing slash redirect for the method and
+// ServeHTTP responds to such requests as if no route matched. Other methods
+// keep redirecting, see RedirectTrailingSlash.
+func (r *Router) StrictSlash(method string, strict bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.immutable.Load() {
+		return
+	}
+
+	method = r.normalizeMethod(method)
+	t := r.tree(method)
+	if t == nil {
+		t = r.addTree(method)
+	}
+	t.strictSlash = strict
+}
+
+// redirectTrailingSlash redirects req to its path with the trailing slash
+// added or removed, keeping the query. The slash is changed in the escaped
+// path, so escaped slashes, matched as part of a segment if DecodeParams is
+// set, stay escaped. Leading slashes are collapsed, so a path like
+// "//evil.com/" doesn't redirect to another host.
+func redirectTrailingSlash(w http.ResponseWriter, req *http.Request) {
+	// Permanent redirect, request with GET method
+	code := http.StatusMovedPermanently
+	if req.Method != http.MethodGet {
+		// Temporary redirect, request with same method
+		code = http.StatusTemporaryRedirect
+	}
+
+	u := *req.URL
+	if path := u.EscapedPath(); len(path) > 1 && path[len(path)-1] == '/' {
+		u.RawPath = path[:len(path)-1]
+	} else {
+		u.RawPath = path + "/"
+	}
+	// a target beginning with "//" is a protocol-relative URL
+	u.RawPath = "/" + strings.TrimLeft(u.RawPath, "/")
+	// the escaped path is valid, it only differs by the slash
+	u.Path, _ = url.PathUnescape(u.RawPath)
+	http.Redirect(w, req, u.String(), code)
+}
@@ -0,0 +1,55 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"path"
+)
+
+// SPA returns a http.Handler for single-page apps, serving the file of root
+// at the request path if it exists and the file index otherwise, so paths
+// routed by the app in the browser load the app. Directories are not listed,
+// their paths are answered with index as well. Requests with methods other
+// than GET and HEAD are answered with 404 Not Found.
+//
+// Registered as the subtree default of "/", the explicit routes of the router
+// take precedence, unlike a catch-all route "/*filepath", which conflicts
+// with them. A subtree default of a longer prefix, e.g. "/api", keeps unknown
+// API paths from being answered with index:
+//
+//	router.GET("/api/users", users)
+//	router.SubtreeDefault("/", xrouter.SPA(http.Dir("/var/www"), "/index.html"))
+//	router.SubtreeDefault("/api", http.NotFoundHandler())
+func SPA(root http.FileSystem, index string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			http.NotFound(w, req)
+			return
+		}
+		if serveFile(w, req, root, path.Clean("/"+req.URL.Path)) {
+			return
+		}
+		if !serveFile(w, req, root, path.Clean("/"+index)) {
+			http.NotFound(w, req)
+		}
+	})
+}
+
+// serveFile serves the file name of root, reporting whether it is a regular
+// file which could be opened.
+func serveFile(w http.ResponseWriter, req *http.Request, root http.FileSystem, name string) bool {
+	f, err := root.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+	http.ServeContent(w, req, info.Name(), info.ModTime(), f)
+	return true
+}
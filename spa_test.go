@@ -0,0 +1,58 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSPA(t *testing.T) {
+	root := http.FS(fstest.MapFS{
+		"index.html":    {Data: []byte("app")},
+		"assets/app.js": {Data: []byte("script")},
+	})
+	router := New()
+	router.GET("/api/users", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("users"))
+	}))
+	if err := router.SubtreeDefault("/", SPA(root, "/index.html")); err != nil {
+		t.Fatal(err)
+	}
+	router.SubtreeDefault("/api", http.NotFoundHandler())
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/api/users", http.StatusOK, "users"},
+		{"GET", "/some/route", http.StatusOK, "app"},
+		// not redirected to a trailing slash
+		{"GET", "/", http.StatusOK, "app"},
+		{"GET", "/assets/app.js", http.StatusOK, "script"},
+		{"GET", "/assets", http.StatusOK, "app"},
+		{"GET", "/../index.html", http.StatusOK, "app"},
+		{"HEAD", "/some/route", http.StatusOK, ""},
+		{"POST", "/some/route", http.StatusNotFound, "404 page not found\n"},
+		{"GET", "/api/nope", http.StatusNotFound, "404 page not found\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("%s %s: got %d %q, want %d %q", test.method, test.path, w.Code, w.Body, test.code, test.body)
+		}
+	}
+
+	// without index
+	w := httptest.NewRecorder()
+	SPA(root, "/missing.html").ServeHTTP(w, httptest.NewRequest("GET", "/some/route", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("wrong status code without index: %d", w.Code)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import "unsafe"
+
+// Stats describes the routing table of a router, see Router.Stats.
+type Stats struct {
+	// number of registered routes, of all methods
+	Routes int
+
+	// number of nodes of the trees of all methods
+	Nodes int
+
+	// number of bytes of the paths of the routes, counting memory shared by
+	// several routes once. The path fragments of the nodes are substrings of
+	// them.
+	PathBytes int
+
+	// number of bytes saved by sharing the memory of identical paths
+	// registered for several methods
+	InternedBytes int
+}
+
+// Stats returns statistics about the routing table of the router.
+func (r *Router) Stats() Stats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s := Stats{InternedBytes: r.internedBytes}
+	seen := make(map[*byte]bool)
+	r.eachTree(func(_ string, t *methodTree) {
+		t.root.walk(func(n *node) {
+			s.Nodes++
+			if rt, ok := n.data.(*Route); ok {
+				s.Routes++
+				if p := unsafe.StringData(rt.Path); !seen[p] {
+					seen[p] = true
+					s.PathBytes += len(rt.Path)
+				}
+			}
+		})
+	})
+	return s
+}
+
+// internedPath is a path shared by the routes of several methods, see intern.
+type internedPath struct {
+	path string
+
+	// number of routes registered with the path
+	refs int
+}
+
+// intern returns the path registered before which equals path, if any, so
+// the routes of several methods share the memory of their paths and thereby
+// of the path fragments of their nodes. The path is recorded by addPath once
+// the route is registered.
+func (r *Router) intern(path string) string {
+	if p, ok := r.paths[path]; ok {
+		return p.path
+	}
+	return path
+}
+
+// addPath records a route registered with the interned path.
+func (r *Router) addPath(path string) {
+	p, ok := r.paths[path]
+	if ok {
+		r.internedBytes += len(path)
+	} else {
+		if r.paths == nil {
+			r.paths = make(map[string]internedPath)
+		}
+		p.path = path
+	}
+	p.refs++
+	r.paths[path] = p
+}
+
+// removePath drops a removed route's path, forgetting it with its last route.
+func (r *Router) removePath(path string) {
+	p, ok := r.paths[path]
+	if !ok {
+		return
+	}
+	if p.refs--; p.refs == 0 {
+		delete(r.paths, path)
+		return
+	}
+	r.internedBytes -= len(path)
+	r.paths[path] = p
+}
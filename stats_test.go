@@ -0,0 +1,100 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+// restPath returns the i-th of 1000 paths, built anew on each call.
+func restPath(i int) string {
+	return fmt.Sprintf("/organizations/org%d/projects/:project/users/%d", i/10, i%10)
+}
+
+// pathMemory returns the number of bytes of the distinct memory holding the
+// paths of the routes and nodes of the router.
+func pathMemory(r *Router) int {
+	used := make(map[uintptr]bool)
+	add := func(s string) {
+		p := uintptr(unsafe.Pointer(unsafe.StringData(s)))
+		for i := 0; i < len(s); i++ {
+			used[p+uintptr(i)] = true
+		}
+	}
+	r.eachTree(func(_ string, t *methodTree) {
+		t.root.walk(func(n *node) {
+			add(n.path)
+			if rt, ok := n.data.(*Route); ok {
+				add(rt.Path)
+			}
+		})
+	})
+	return len(used)
+}
+
+func TestRouterStatsInterned(t *testing.T) {
+	methods := []string{"GET", "POST", "PUT", "DELETE"}
+	single, router := New(), New()
+	for i := 0; i < 1000; i++ {
+		single.GET(restPath(i), "GET")
+		for _, method := range methods {
+			router.Handle(method, restPath(i), method)
+		}
+	}
+
+	s, want := router.Stats(), single.Stats()
+	if s.Routes != 4*want.Routes || s.Nodes != 4*want.Nodes {
+		t.Errorf("wrong number of routes or nodes: got %+v, single tree %+v", s, want)
+	}
+	if s.PathBytes != want.PathBytes || s.InternedBytes != 3*want.PathBytes {
+		t.Errorf("paths not shared: got %+v, single tree %+v", s, want)
+	}
+	if got, one := pathMemory(router), pathMemory(single); got > one+one/2 {
+		t.Errorf("path memory of 4 trees is %d bytes, single tree %d bytes", got, one)
+	}
+
+	for i := 0; i < 1000; i += 7 {
+		path := requestPath(restPath(i))
+		_, wantPs, _ := single.Lookup("GET", path)
+		for _, method := range methods {
+			handle, ps, _ := router.Lookup(method, path)
+			if handle != method || !reflect.DeepEqual(ps, wantPs) {
+				t.Errorf("wrong result for %s %s: got %v, %v", method, path, handle, ps)
+			}
+		}
+	}
+}
+
+func TestRouterStatsInternedRemove(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", "user")
+	router.POST("/users/:id", "user")
+	if err := router.PUT("/users/:id", "user"); err != nil {
+		t.Fatal(err)
+	}
+	// failed registrations are not recorded
+	if err := router.GET("/users/:name", "user"); err == nil {
+		t.Fatal("conflicting route registered")
+	}
+	if _, ok := router.paths["/users/:name"]; ok {
+		t.Error("path of a failed registration interned")
+	}
+	if got, want := router.Stats().InternedBytes, 2*len("/users/:id"); got != want {
+		t.Errorf("wrong interned bytes: got %d, want %d", got, want)
+	}
+
+	router.Remove("PUT", "/users/:id")
+	if got, want := router.Stats().InternedBytes, len("/users/:id"); got != want {
+		t.Errorf("wrong interned bytes after Remove: got %d, want %d", got, want)
+	}
+	router.Remove("GET", "/users/:id")
+	router.Remove("POST", "/users/:id")
+	if len(router.paths) != 0 || router.Stats().InternedBytes != 0 {
+		t.Errorf("paths left after removing all routes: %v, %d bytes", router.paths, router.Stats().InternedBytes)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SubtreeDefault registers a fallback handle for all paths below prefix, for
+// all methods. It serves e.g. any path under "/admin" unless a more specific
+// route exists: it loses to every route matching the path, and to trailing
+// slash recommendations. If the defaults of several prefixes cover a path, the
+// one of the longest prefix is used. The handle receives no Params.
+// SubtreeDefault returns an error if a default is already registered for the
+// prefix, and validates the handle like Handle.
+func (r *Router) SubtreeDefault(prefix string, handle interface{}) error {
+	if prefix == "" || prefix[0] != '/' {
+		return fmt.Errorf("prefix must begin with '/' in prefix '%s': %w", prefix, ErrInvalidPath)
+	}
+	if prefix != "/" {
+		prefix = strings.TrimSuffix(prefix, "/")
+	}
+	if isNil(handle) {
+		return fmt.Errorf("prefix '%s': %w", prefix, ErrNilHandle)
+	}
+	handle, err := r.adapt(prefix, handle)
+	if err != nil {
+		return err
+	}
+	if err := r.checkHandleType(prefix, handle); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.immutable.Load() {
+		return ErrFrozen
+	}
+	if r.sealed.Load() && !r.ConcurrentRegistration {
+		return fmt.Errorf("subtree default '%s': %w", prefix, ErrSealed)
+	}
+
+	// keep the defaults ordered by descending prefix length, so the first
+	// matching one is the most specific
+	i := 0
+	for ; i < len(r.defaults); i++ {
+		if r.defaults[i].Path == prefix {
+			return fmt.Errorf("a subtree default is already registered for prefix '%s'", prefix)
+		}
+		if len(r.defaults[i].Path) < len(prefix) {
+			break
+		}
+	}
+	defaults := make([]*Route, 0, len(r.defaults)+1)
+	defaults = append(defaults, r.defaults[:i]...)
+	defaults = append(defaults, &Route{Path: prefix, Handle: handle})
+	r.defaults = append(defaults, r.defaults[i:]...)
+	r.invalidateCache()
+	return nil
+}
+
+// subtreeDefault returns the default route of the longest prefix covering
+// path, or nil if there is none.
+func (r *Router) subtreeDefault(path string) *Route {
+	for _, rt := range r.defaults {
+		prefix := rt.Path
+		if prefix == "/" || (strings.HasPrefix(path, prefix) &&
+			(len(path) == len(prefix) || path[len(prefix)] == '/')) {
+			return rt
+		}
+	}
+	return nil
+}
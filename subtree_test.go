@@ -0,0 +1,100 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterSubtreeDefault(t *testing.T) {
+	router := New()
+	router.GET("/admin/users", "users")
+	router.GET("/admin/users/:id", "user")
+	router.GET("/admin/settings/", "settings")
+	router.GET("/about", "about")
+	if err := router.SubtreeDefault("/admin/", "admin"); err != nil {
+		t.Fatalf("registering subtree default failed: %v", err)
+	}
+	if err := router.SubtreeDefault("/admin/reports", "reports"); err != nil {
+		t.Fatalf("registering subtree default failed: %v", err)
+	}
+	if err := router.SubtreeDefault("/admin", "duplicate"); err == nil {
+		t.Error("no error registering duplicate subtree default")
+	}
+	if err := router.SubtreeDefault("admin", "invalid"); err == nil {
+		t.Error("no error registering subtree default without leading '/'")
+	}
+
+	forBoth(t, router, func(t *testing.T, router lookupRouter) {
+		tests := []struct {
+			method, path string
+			handle       interface{}
+			tsr          bool
+		}{
+			{"GET", "/admin/users", "users", false},
+			{"GET", "/admin/users/42", "user", false},
+			{"GET", "/admin/anything-else", "admin", false},
+			{"GET", "/admin/anything/else/", "admin", false},
+			{"GET", "/admin", "admin", false},
+			{"POST", "/admin/users", "admin", false},
+			{"GET", "/admin/reports/2024", "reports", false},
+			{"GET", "/admin/reportsx", "admin", false},
+			{"GET", "/admin/settings", nil, true},
+			{"GET", "/administrator", nil, false},
+			{"GET", "/nope", nil, false},
+		}
+		for _, test := range tests {
+			handle, _, tsr := router.Lookup(test.method, test.path)
+			if handle != test.handle || tsr != test.tsr {
+				t.Errorf("wrong result for %s %s: got %v, %t; want %v, %t", test.method, test.path, handle, tsr, test.handle, test.tsr)
+			}
+		}
+	})
+
+	// registered after the lookups above
+	served := false
+	router.ConcurrentRegistration = true
+	if err := router.SubtreeDefault("/", http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		served = true
+	})); err != nil {
+		t.Fatalf("registering subtree default failed: %v", err)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/nope", nil))
+	if !served || w.Code != http.StatusOK {
+		t.Errorf("root subtree default not served: status %d", w.Code)
+	}
+}
+
+func TestRouterSubtreeDefaultInvalid(t *testing.T) {
+	router := NewStrict((*http.Handler)(nil))
+	var nilFunc http.HandlerFunc
+	tests := []struct {
+		prefix string
+		handle interface{}
+		err    error
+	}{
+		{"admin", http.NotFoundHandler(), ErrInvalidPath},
+		{"/admin", nil, ErrNilHandle},
+		{"/admin", nilFunc, ErrNilHandle},
+		{"/admin", "admin", ErrHandleType},
+	}
+	for _, test := range tests {
+		if err := router.SubtreeDefault(test.prefix, test.handle); !errors.Is(err, test.err) {
+			t.Errorf("wrong error for %s %#v: got %v, want %v", test.prefix, test.handle, err, test.err)
+		}
+	}
+	if len(router.defaults) != 0 {
+		t.Errorf("invalid defaults registered: %d", len(router.defaults))
+	}
+
+	router.Lookup("GET", "/")
+	if err := router.SubtreeDefault("/admin", http.NotFoundHandler()); !errors.Is(err, ErrSealed) {
+		t.Errorf("wrong error after the first lookup: %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// Suggest returns up to max patterns of registered routes similar to path,
+// most similar first, e.g. "/users/:id" for "/userz/42", to tell clients which
+// route a request matching none was meant for. Patterns of routes of other
+// methods are prefixed with their method, e.g. "POST /login", and follow the
+// ones of method at the same distance.
+//
+// Paths and patterns are compared segment-wise: a parameter matches any
+// segment, a catch-all any number of them. A differing segment, a missing and
+// an extra one each count as one edit, so typos in a segment, missing
+// segments and extra segments are found; replacing a segment by a dissimilar
+// one counts as two. Patterns one edit away are suggested if one of their
+// static segments resembles a segment of the path. Patterns with equally many
+// edits are ranked by the number of differing characters.
+// The patterns are compared in order, reusing the distances for the segments
+// they share with the previous one, and skipping all patterns sharing a
+// prefix which is already too far away.
+func (r *Router) Suggest(method, path string, max int) []string {
+	if max <= 0 {
+		return nil
+	}
+	method = r.normalizeMethod(method)
+	query := splitSegments(path)
+	const limit = 1 // edits between the path and suggested patterns
+
+	first := make([]suggestCost, len(query)+1)
+	for j, seg := range query {
+		first[j+1] = first[j].add(suggestCost{1, len(seg)})
+	}
+	rows := [][]suggestCost{first}
+	var prev []string
+	pruned := -1 // depth of the shared prefix too far away
+	var found []suggestion
+	for _, rt := range r.routes() {
+		if rt.reserved() {
+			continue
+		}
+		segs := splitSegments(rt.Path)
+		common := 0
+		for common < len(segs) && common < len(prev) && segs[common] == prev[common] {
+			common++
+		}
+		prev = segs
+		if pruned >= 0 && pruned <= common {
+			continue
+		}
+		pruned = -1
+		rows = rows[:common+1]
+		for i := common; i < len(segs); i++ {
+			row := nextSuggestRow(rows[i], segs[i], query, i == 0)
+			rows = append(rows, row)
+			if minSuggestCost(row) > limit {
+				pruned = i + 1
+				break
+			}
+		}
+		if pruned >= 0 {
+			continue
+		}
+		if dist := rows[len(segs)][len(query)]; dist.seg <= limit && sharesLiteral(segs, query) {
+			s := suggestion{text: rt.Path, dist: dist, other: rt.Method != method}
+			if s.other {
+				s.text = rt.Method + " " + rt.Path
+			}
+			found = append(found, s)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.dist != b.dist {
+			return a.dist.less(b.dist)
+		}
+		if a.other != b.other {
+			return !a.other
+		}
+		return a.text < b.text
+	})
+	if len(found) > max {
+		found = found[:max]
+	}
+	suggestions := make([]string, len(found))
+	for i, s := range found {
+		suggestions[i] = s.text
+	}
+	return suggestions
+}
+
+type suggestionsKey struct{}
+
+// SuggestionsFromContext returns the suggestions passed to the NotFound
+// handler for the request of the given context, see Router.SuggestOnMiss, or
+// nil if there are none.
+func SuggestionsFromContext(ctx context.Context) []string {
+	s, _ := ctx.Value(suggestionsKey{}).([]string)
+	return s
+}
+
+// suggestion is a pattern considered by Suggest.
+type suggestion struct {
+	text  string
+	dist  suggestCost
+	other bool // registered for another method
+}
+
+// suggestCost is the distance between a path and a pattern: the number of
+// segment edits, and of character edits within them.
+type suggestCost struct {
+	seg, char int
+}
+
+func (c suggestCost) add(d suggestCost) suggestCost {
+	return suggestCost{c.seg + d.seg, c.char + d.char}
+}
+
+func (c suggestCost) less(d suggestCost) bool {
+	return c.seg < d.seg || c.seg == d.seg && c.char < d.char
+}
+
+// nextSuggestRow returns the distances of the prefixes of query from the
+// pattern prefix with the distances prev, extended by the segment seg, which
+// is the first of the pattern if first is set.
+func nextSuggestRow(prev []suggestCost, seg string, query []string, first bool) []suggestCost {
+	row := make([]suggestCost, len(prev))
+	missing := suggestCost{1, len(seg)}
+	if seg != "" && seg[0] == '*' {
+		// a catch-all matches any number of the remaining segments, but
+		// only if the segments before it consumed some of the path, else
+		// dropping them would make the pattern similar to any path
+		row[0] = prev[0]
+		if !first {
+			row[0] = row[0].add(missing)
+		}
+		best := row[0]
+		if !first {
+			best = prev[1]
+		}
+		for j := 1; j < len(row); j++ {
+			if prev[j].less(best) {
+				best = prev[j]
+			}
+			row[j] = best
+		}
+		return row
+	}
+	row[0] = prev[0].add(missing)
+	for j := 1; j < len(row); j++ {
+		c := prev[j].add(missing)
+		if extra := row[j-1].add(suggestCost{1, len(query[j-1])}); extra.less(c) {
+			c = extra
+		}
+		if subst := prev[j-1].add(segmentCost(seg, query[j-1])); subst.less(c) {
+			c = subst
+		}
+		row[j] = c
+	}
+	return row
+}
+
+// segmentCost returns the distance of the path segment from the pattern
+// segment seg.
+func segmentCost(seg, path string) suggestCost {
+	if seg == path || seg != "" && seg[0] == ':' {
+		return suggestCost{}
+	}
+	d := levenshtein(seg, path)
+	if 2*d <= len(seg) || 2*d <= len(path) {
+		return suggestCost{1, d}
+	}
+	return suggestCost{2, d}
+}
+
+// sharesLiteral reports whether a static segment of the pattern segs equals
+// or resembles a segment of query, or segs has no static segments. Patterns
+// whose wildcards match the segments which a missing static segment leaves
+// over aren't suggested without such a resemblance.
+func sharesLiteral(segs, query []string) bool {
+	static := false
+	for _, seg := range segs {
+		if seg != "" && (seg[0] == ':' || seg[0] == '*') {
+			continue
+		}
+		static = true
+		for _, q := range query {
+			if segmentCost(seg, q).seg <= 1 {
+				return true
+			}
+		}
+	}
+	return !static
+}
+
+func minSuggestCost(row []suggestCost) int {
+	lowest := row[0].seg
+	for _, c := range row[1:] {
+		if c.seg < lowest {
+			lowest = c.seg
+		}
+	}
+	return lowest
+}
+
+// splitSegments returns the segments of path, without its leading slash.
+func splitSegments(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+// levenshtein returns the number of byte insertions, deletions and
+// substitutions turning a into b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	row := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			row[j] = min(min(prev[j], row[j-1])+1, prev[j-1]+cost)
+		}
+		prev, row = row, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,78 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRouterSuggest(t *testing.T) {
+	router := New()
+	for _, path := range []string{
+		"/users/:id",
+		"/users/:id/posts",
+		"/users/:id/posts/:post",
+		"/posts/:id",
+		"/repos/:owner/:repo",
+		"/src/*filepath",
+		"/admin/settings",
+		"/admin/stats",
+	} {
+		router.GET(path, path)
+	}
+	router.POST("/login", "login")
+	router.POST("/users/:id", "update")
+
+	tests := []struct {
+		method, path string
+		max          int
+		want         []string
+	}{
+		// typos
+		{"GET", "/userz/42", 3, []string{"/users/:id", "POST /users/:id"}},
+		{"GET", "/users/42/postz", 3, []string{"/users/:id/posts", "/users/:id", "POST /users/:id"}},
+		{"GET", "/admin/stat", 3, []string{"/admin/stats"}},
+		// missing and extra segments
+		{"GET", "/repos/gopher", 3, []string{"/repos/:owner/:repo"}},
+		{"GET", "/posts/1/comments", 3, []string{"/posts/:id"}},
+		// wrong method
+		{"GET", "/login", 3, []string{"POST /login"}},
+		{"DELETE", "/users/42", 3, []string{"GET /users/:id", "POST /users/:id", "GET /users/:id/posts"}},
+		// catch-all
+		{"GET", "/srcs/js/app.js", 3, []string{"/src/*filepath"}},
+		// limited
+		{"GET", "/users/42/postz", 1, []string{"/users/:id/posts"}},
+		{"GET", "/userz/42", 0, nil},
+		// nothing similar
+		{"GET", "/completely/different/thing", 3, []string{}},
+	}
+	for _, test := range tests {
+		if got := router.Suggest(test.method, test.path, test.max); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Suggest(%s, %s, %d) = %q, want %q", test.method, test.path, test.max, got, test.want)
+		}
+	}
+}
+
+func TestRouterSuggestOnMiss(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", "user")
+	var got []string
+	router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = SuggestionsFromContext(req.Context())
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/userz/42", nil))
+	if got != nil {
+		t.Errorf("suggestions without SuggestOnMiss: %v", got)
+	}
+	router.SuggestOnMiss = 3
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/userz/42", nil))
+	if want := []string{"/users/:id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong suggestions: %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+// Table returns the route table of the given key, creating it with the
+// settings of New on the first call, e.g. to register the routes of a tier
+// of API keys. ServeHTTP dispatches the requests for which KeyFunc returns
+// key to the table, which serves them like any router, with its own routes
+// and settings. Lookups of the router don't consult the tables.
+// Table is safe to call while the router serves requests.
+func (r *Router) Table(key string) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if table, ok := r.tables[key]; ok {
+		return table
+	}
+	if r.tables == nil {
+		r.tables = make(map[string]*Router)
+	}
+	table := New()
+	r.tables[key] = table
+	return table
+}
+
+// table returns the route table of key, or nil if there is none.
+func (r *Router) table(key string) *Router {
+	if !r.frozen {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+	}
+	return r.tables[key]
+}
@@ -0,0 +1,70 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterKeyFunc(t *testing.T) {
+	serve := func(name string) Handle {
+		return func(w http.ResponseWriter, _ *http.Request, ps Params) {
+			w.Write([]byte(name + ":" + ps.ByName("id")))
+		}
+	}
+	router := New()
+	router.GET("/items/:id", serve("default"))
+	router.Table("gold").GET("/items/:id", serve("gold"))
+	router.Table("gold").GET("/reports", serve("reports"))
+	if router.Table("gold") != router.Table("gold") {
+		t.Fatal("Table created a second table for the same key")
+	}
+
+	get := func(path, tier string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if tier != "" {
+			req.Header.Set("X-Tier", tier)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// without KeyFunc the tables are ignored
+	if w := get("/items/1", "gold"); w.Body.String() != "default:1" {
+		t.Errorf("wrong response without KeyFunc: %q", w.Body.String())
+	}
+
+	router.KeyFunc = func(req *http.Request) string { return req.Header.Get("X-Tier") }
+	for _, test := range []struct {
+		path, tier string
+		code       int
+		body       string
+	}{
+		{"/items/1", "gold", http.StatusOK, "gold:1"},
+		{"/items/2", "free", http.StatusOK, "default:2"},
+		{"/items/3", "", http.StatusOK, "default:3"},
+		{"/reports", "gold", http.StatusOK, "reports:"},
+		// the routes of a table are only served for its key
+		{"/reports", "", http.StatusNotFound, "404 page not found\n"},
+	} {
+		w := get(test.path, test.tier)
+		if w.Code != test.code || w.Body.String() != test.body {
+			t.Errorf("%s with tier %q: got %d %q, want %d %q", test.path, test.tier, w.Code, w.Body.String(), test.code, test.body)
+		}
+	}
+
+	// frozen routers keep dispatching
+	frozen := router.Freeze()
+	req := httptest.NewRequest("GET", "/items/4", nil)
+	req.Header.Set("X-Tier", "gold")
+	w := httptest.NewRecorder()
+	frozen.ServeHTTP(w, req)
+	if w.Body.String() != "gold:4" {
+		t.Errorf("wrong response of the frozen router: %q", w.Body.String())
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RouteTemplate is a set of routes relative to a prefix, e.g. the CRUD routes
+// of a resource, which can be registered under several prefixes with
+// Router.MountTemplate. The handles are created per prefix by factories
+// receiving the resource name.
+type RouteTemplate struct {
+	routes []templateRoute
+}
+
+// templateRoute is a route of a RouteTemplate.
+type templateRoute struct {
+	method, path string
+	factory      func(resource string) interface{}
+	opts         RouteOptions
+	// source is where the route was added to the template
+	source string
+}
+
+// Handle adds a route for method and path, relative to the prefix the
+// template is mounted under, to the template. The path must be empty, for the
+// prefix itself, or begin with '/'. When mounted, factory is called with the
+// resource name to create the handle of the route.
+func (t *RouteTemplate) Handle(method, path string, factory func(resource string) interface{}) {
+	t.HandleOptions(method, path, factory, RouteOptions{})
+}
+
+// HandleOptions is like Handle but additionally sets options of the route,
+// see Router.HandleOptions.
+func (t *RouteTemplate) HandleOptions(method, path string, factory func(resource string) interface{}, opts RouteOptions) {
+	t.routes = append(t.routes, templateRoute{method: method, path: path, factory: factory, opts: opts, source: callerSource()})
+}
+
+// MountTemplate registers the routes of tmpl under prefix, e.g. "/users".
+// The resource name passed to the handle factories is the last segment of
+// the prefix, "users" in the example. The routes are registered by
+// HandleOptions, with the place they were added to the template as source,
+// see Route.Source. If any of them fails, MountTemplate continues with the
+// others and returns a *MultiError listing all failed routes.
+func (r *Router) MountTemplate(prefix string, tmpl *RouteTemplate) error {
+	if prefix == "" || prefix[0] != '/' || prefix == "/" {
+		return fmt.Errorf("prefix must begin with '/' and name a resource in prefix '%s': %w", prefix, ErrInvalidPath)
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	resource := prefix[strings.LastIndexByte(prefix, '/')+1:]
+
+	var failed MultiError
+	for _, tr := range tmpl.routes {
+		path := prefix + tr.path
+		if tr.path != "" && tr.path[0] != '/' {
+			failed.add(tr.method, path, fmt.Errorf("template path must be empty or begin with '/' in path '%s': %w", tr.path, ErrInvalidPath))
+			continue
+		}
+		opts := tr.opts
+		if opts.Source == "" && !r.DisableCallerSource {
+			opts.Source = tr.source
+		}
+		if err := r.HandleOptions(tr.method, path, tr.factory(resource), opts); err != nil {
+			failed.add(tr.method, path, err)
+		}
+	}
+	return failed.err()
+}
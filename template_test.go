@@ -0,0 +1,71 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func crudTemplate() *RouteTemplate {
+	tmpl := new(RouteTemplate)
+	for _, route := range []struct{ method, path, action string }{
+		{"GET", "", "list"},
+		{"POST", "", "create"},
+		{"GET", "/:id", "show"},
+		{"PUT", "/:id", "update"},
+		{"DELETE", "/:id", "delete"},
+	} {
+		action := route.action
+		tmpl.Handle(route.method, route.path, func(resource string) interface{} {
+			return resource + "." + action
+		})
+	}
+	return tmpl
+}
+
+func TestRouterMountTemplate(t *testing.T) {
+	router := New()
+	tmpl := crudTemplate()
+	for _, prefix := range []string{"/users", "/shop/orders/"} {
+		if err := router.MountTemplate(prefix, tmpl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// mounting twice fails for every route
+	err := router.MountTemplate("/users", tmpl)
+	var multi *MultiError
+	if !errors.As(err, &multi) || len(multi.Errors) != len(tmpl.routes) || !errors.Is(err, ErrConflict) {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	tests := []struct {
+		method, path, handle, id string
+	}{
+		{"GET", "/users", "users.list", ""},
+		{"POST", "/users", "users.create", ""},
+		{"GET", "/users/42", "users.show", "42"},
+		{"DELETE", "/users/42", "users.delete", "42"},
+		{"GET", "/shop/orders", "orders.list", ""},
+		{"PUT", "/shop/orders/7", "orders.update", "7"},
+	}
+	for _, test := range tests {
+		handle, ps, _ := router.Lookup(test.method, test.path)
+		if handle != test.handle || ps.ByName("id") != test.id {
+			t.Errorf("wrong result for %s %s: got %v, %v", test.method, test.path, handle, ps)
+		}
+	}
+
+	rt, _, _ := router.LookupRoute("GET", "/users/42")
+	if !strings.Contains(rt.Source, "crudTemplate") {
+		t.Errorf("wrong source: %q", rt.Source)
+	}
+
+	if err := router.MountTemplate("/", tmpl); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("wrong error for the root prefix: %v", err)
+	}
+}
@@ -5,11 +5,11 @@
 package xrouter
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 	"unicode"
 	"unicode/utf8"
-
-	"github.com/pkg/errors"
 )
 
 func min(a, b int) int {
@@ -42,15 +42,17 @@ const (
 	catchAll
 )
 
+// The small fields are grouped at the end, so they share the padding behind
+// priority instead of taking up a word of their own.
 type node struct {
 	path      string
-	wildChild bool
-	nType     nodeType
-	maxParams uint8
 	indices   string
 	children  []*node
 	data      interface{}
 	priority  uint32
+	wildChild bool
+	nType     nodeType
+	maxParams uint8
 }
 
 // increments priority of the given child and reorders if necessary
@@ -77,10 +79,50 @@ func (n *node) incrementChildPrio(pos int) int {
 	return newPos
 }
 
-// addRoute adds a node with the given handle to the path.
+// addRoute adds a node with the given handle to the path. If the path can't
+// be added, e.g. because it conflicts with an existing route, the tree is
+// left unchanged.
 // Not concurrency-safe!
 func (n *node) addRoute(path string, handle interface{}) error {
+	var undo treeUndo
+	if err := n.insertRoute(path, handle, &undo); err != nil {
+		undo.restore()
+		return err
+	}
+	return nil
+}
+
+// treeUndo records the state of the nodes insertRoute modifies, before their
+// first modification.
+type treeUndo []savedNode
+
+type savedNode struct {
+	n     *node
+	state node
+}
+
+// save records the current state of n.
+func (u *treeUndo) save(n *node) {
+	state := *n
+	// the children are reordered in place
+	state.children = append([]*node(nil), n.children...)
+	*u = append(*u, savedNode{n, state})
+}
+
+// restore resets the recorded nodes to their state, the earliest recorded
+// state last.
+func (u treeUndo) restore() {
+	for i := len(u) - 1; i >= 0; i-- {
+		*u[i].n = u[i].state
+	}
+}
+
+// insertRoute implements addRoute, saving each node to undo before modifying
+// it. New nodes are only reachable through modified ones, so they need not be
+// saved.
+func (n *node) insertRoute(path string, handle interface{}, undo *treeUndo) error {
 	fullPath := path
+	undo.save(n)
 	n.priority++
 	numParams := countParams(path)
 
@@ -135,6 +177,7 @@ func (n *node) addRoute(path string, handle interface{}) error {
 
 				if n.wildChild {
 					n = n.children[0]
+					undo.save(n)
 					n.priority++
 
 					// Update maxParams of the child node
@@ -152,12 +195,18 @@ func (n *node) addRoute(path string, handle interface{}) error {
 						// Wildcard conflict
 						var pathSeg string
 						if n.nType == catchAll {
+							// A path without wildcards below a catch-all
+							// would never be matched
+							if countParams(path) == 0 {
+								catchAllPath := fullPath[:len(fullPath)-len(path)] + n.path
+								return newSubtreeConflictError(fullPath, n, catchAllPath, "path '%s' is shadowed by existing catch-all '%s'", fullPath, catchAllPath)
+							}
 							pathSeg = path
 						} else {
 							pathSeg = strings.SplitN(path, "/", 2)[0]
 						}
 						prefix := fullPath[:strings.Index(fullPath, pathSeg)] + n.path
-						return errors.Errorf("'%s' in new path '%s' conflicts with existing wildcard '%s' in existing prefix '%s'", pathSeg, fullPath, n.path, prefix)
+						return newSubtreeConflictError(fullPath, n, prefix, "'%s' in new path '%s' conflicts with existing wildcard '%s' in existing prefix '%s'", pathSeg, fullPath, n.path, prefix)
 					}
 				}
 
@@ -166,6 +215,7 @@ func (n *node) addRoute(path string, handle interface{}) error {
 				// slash after param
 				if n.nType == param && c == '/' && len(n.children) == 1 {
 					n = n.children[0]
+					undo.save(n)
 					n.priority++
 					continue walk
 				}
@@ -173,6 +223,7 @@ func (n *node) addRoute(path string, handle interface{}) error {
 				// Check if a child with the next path byte exists
 				for i := 0; i < len(n.indices); i++ {
 					if c == n.indices[i] {
+						undo.save(n.children[i])
 						i = n.incrementChildPrio(i)
 						n = n.children[i]
 						continue walk
@@ -188,13 +239,21 @@ func (n *node) addRoute(path string, handle interface{}) error {
 					}
 					n.children = append(n.children, child)
 					n.incrementChildPrio(len(n.indices) - 1)
-					n = child
+					if err := child.insertChild(numParams, path, fullPath, handle); err != nil {
+						return err
+					}
+					// a catch-all right at the start of the path leaves the
+					// child empty, which is only expected below a param
+					if n.nType != param {
+						child.collapse()
+					}
+					return nil
 				}
 				return n.insertChild(numParams, path, fullPath, handle)
 
 			} else if i == len(path) { // Make node a (in-path) leaf
 				if n.data != nil {
-					return errors.Errorf("a handle is already registered for path '%s'", fullPath)
+					return newConflictError(fullPath, n, fullPath, "a handle is already registered for path '%s'", fullPath)
 				}
 				n.data = handle
 			}
@@ -222,7 +281,7 @@ func (n *node) insertChild(numParams uint8, path, fullPath string, data interfac
 			switch path[end] {
 			// the wildcard name must not contain ':' and '*'
 			case ':', '*':
-				return errors.Errorf("only one wildcard per path segment is allowed, has: '%s' in path '%s'", path[i:], fullPath)
+				return fmt.Errorf("only one wildcard per path segment is allowed, has: '%s' in path '%s': %w", path[i:], fullPath, ErrInvalidWildcard)
 			default:
 				end++
 			}
@@ -231,12 +290,13 @@ func (n *node) insertChild(numParams uint8, path, fullPath string, data interfac
 		// check if this Node existing children which would be
 		// unreachable if we insert the wildcard here
 		if len(n.children) > 0 {
-			return errors.Errorf("wildcard route '%s' conflicts with existing children in path '%s'", path[i:end], fullPath)
+			prefix := fullPath[:len(fullPath)-len(path)+i]
+			return newSubtreeConflictError(fullPath, n, prefix, "wildcard route '%s' conflicts with existing children in path '%s'", path[i:end], fullPath)
 		}
 
 		// check if the wildcard has a name
 		if end-i < 2 {
-			return errors.Errorf("wildcards must be named with a non-empty name in path '%s'", fullPath)
+			return fmt.Errorf("wildcards must be named with a non-empty name in path '%s': %w", fullPath, ErrInvalidWildcard)
 		}
 
 		if c == ':' { // param
@@ -272,17 +332,18 @@ func (n *node) insertChild(numParams uint8, path, fullPath string, data interfac
 
 		} else { // catchAll
 			if end != max || numParams > 1 {
-				return errors.Errorf("catch-all routes are only allowed at the end of the path in path '%s'", fullPath)
+				return fmt.Errorf("catch-all routes are only allowed at the end of the path in path '%s': %w", fullPath, ErrCatchAllPosition)
 			}
 
 			if len(n.path) > 0 && n.path[len(n.path)-1] == '/' {
-				return errors.Errorf("catch-all conflicts with existing handle for the path segment root in path '%s'", fullPath)
+				prefix := fullPath[:len(fullPath)-len(path)+i]
+				return newConflictError(fullPath, n, prefix, "catch-all conflicts with existing handle for the path segment root in path '%s'", fullPath)
 			}
 
 			// currently fixed width 1 for '/'
 			i--
 			if path[i] != '/' {
-				return errors.Errorf("no / before catch-all in path '%s'", fullPath)
+				return fmt.Errorf("no / before catch-all in path '%s': %w", fullPath, ErrCatchAllPosition)
 			}
 
 			n.path = path[offset:i]
@@ -318,12 +379,291 @@ func (n *node) insertChild(numParams uint8, path, fullPath string, data interfac
 	return nil
 }
 
+// removeRoute removes the handle registered for exactly the given path,
+// wildcards included, and returns it. Nodes left without any handle below them
+// are pruned and static nodes left with a single static child are merged with
+// it, so the tree has the same shape as if the path was never added.
+// Not concurrency-safe!
+func (n *node) removeRoute(path string) (interface{}, error) {
+	fullPath := path
+
+	// nodes on the way to the one holding the handle
+	var stack []*node
+walk:
+	for {
+		if !strings.HasPrefix(path, n.path) {
+			return nil, fmt.Errorf("path '%s': %w", fullPath, ErrNotRegistered)
+		}
+		path = path[len(n.path):]
+		stack = append(stack, n)
+		if path == "" {
+			break
+		}
+
+		// wildcard child, or the subpath after a param
+		if n.wildChild || (n.nType == param && len(n.children) == 1) {
+			n = n.children[0]
+			continue
+		}
+		c := path[0]
+		for i := 0; i < len(n.indices); i++ {
+			if c == n.indices[i] {
+				n = n.children[i]
+				continue walk
+			}
+		}
+		return nil, fmt.Errorf("path '%s': %w", fullPath, ErrNotRegistered)
+	}
+	if n.data == nil {
+		return nil, fmt.Errorf("path '%s': %w", fullPath, ErrNotRegistered)
+	}
+	data := n.data
+	n.data = nil
+
+	// walk back up, repairing the nodes below on the way
+	for i := len(stack) - 1; i >= 0; i-- {
+		n = stack[i]
+		n.priority--
+		if i+1 < len(stack) {
+			n.removeChild(stack[i+1])
+		}
+		n.mergeChild()
+
+		// Update maxParams (max of all children)
+		n.maxParams = 0
+		for _, child := range n.children {
+			if child.maxParams > n.maxParams {
+				n.maxParams = child.maxParams
+			}
+		}
+		if n.nType > root && !n.wildChild {
+			n.maxParams++
+		}
+	}
+
+	// the last handle is gone, start over with an empty tree
+	if root := stack[0]; root.empty() {
+		*root = node{}
+	}
+	return data, nil
+}
+
+// removeChild removes the given child if it has no handle below it anymore,
+// otherwise it moves it back to keep the children ordered by priority.
+func (n *node) removeChild(child *node) {
+	pos := 0
+	for n.children[pos] != child {
+		pos++
+	}
+
+	if !child.empty() {
+		// adjust position (move to back)
+		newPos := pos
+		for newPos+1 < len(n.indices) && n.children[newPos+1].priority > child.priority {
+			// swap node positions
+			n.children[newPos+1], n.children[newPos] = n.children[newPos], n.children[newPos+1]
+			newPos++
+		}
+
+		// build new index char string
+		if newPos != pos {
+			n.indices = n.indices[:pos] + // unchanged prefix, might be empty
+				n.indices[pos+1:newPos+1] + // chars of the nodes moved to the front
+				n.indices[pos:pos+1] + // the index char we move
+				n.indices[newPos+1:] // unchanged rest
+		}
+		return
+	}
+
+	if pos >= len(n.indices) {
+		// wildcard child, or the subpath after a param
+		n.children = nil
+		n.wildChild = false
+		return
+	}
+	n.children = append(n.children[:pos:pos], n.children[pos+1:]...)
+	n.indices = n.indices[:pos] + n.indices[pos+1:]
+}
+
+// empty reports whether neither n nor any node below it holds a handle. The
+// nodes below are pruned bottom-up by removeRoute, so only leaves are checked.
+func (n *node) empty() bool {
+	return n.data == nil && len(n.children) == 0
+}
+
+// collapse replaces a static node with an empty path and no handle by its
+// only child, a catch-all. insertChild leaves such a node for a path
+// continuing with a catch-all. Except below a param, where lookups expect it,
+// it would make the shape of the tree depend on the order of registration and
+// hide the catch-all from trailing slash recommendations.
+func (n *node) collapse() {
+	if n.nType != static || n.path != "" || n.data != nil || len(n.children) != 1 {
+		return
+	}
+	*n = *n.children[0]
+}
+
+// mergeChild merges a static node without a handle with its only child if
+// that child is static as well, undoing the edge split of addRoute.
+func (n *node) mergeChild() {
+	if n.data != nil || len(n.children) != 1 || len(n.indices) != 1 ||
+		(n.nType != static && n.nType != root) || n.children[0].nType != static {
+		return
+	}
+	child := n.children[0]
+	n.path += child.path
+	n.wildChild = child.wildChild
+	n.indices = child.indices
+	n.children = child.children
+	n.data = child.data
+}
+
+// walk calls fn for n and all nodes below it, parents before their children.
+func (n *node) walk(fn func(*node)) {
+	fn(n)
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+}
+
+// dump writes n and the nodes below it to b, one line per node indented by
+// depth, see Router.Dump.
+func (n *node) dump(b *strings.Builder, depth int) {
+	fmt.Fprintf(b, "%s%q", strings.Repeat("  ", depth), n.path)
+	switch n.nType {
+	case param:
+		b.WriteString(" param")
+	case catchAll:
+		b.WriteString(" catch-all")
+	}
+	if n.wildChild {
+		b.WriteString(" wildChild")
+	}
+	switch data := n.data.(type) {
+	case nil:
+	case *Route:
+		fmt.Fprintf(b, " -> %s", data.Path)
+	default:
+		b.WriteString(" -> handle")
+	}
+	b.WriteByte('\n')
+	for _, child := range n.children {
+		child.dump(b, depth+1)
+	}
+}
+
+// Dump returns a textual representation of the tree of each method, for
+// debugging conflicts and shadowed routes: the methods are listed by name,
+// followed by the nodes of their tree, children indented below their parent
+// in the order lookups check them. For each node Dump prints the quoted path
+// prefix, whether it is a param or catch-all node or has a wildcard child,
+// and the path of the route it holds, if any. The format may change and should
+// not be parsed.
+func (r *Router) Dump() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	trees := make(map[string]*methodTree)
+	var methods []string
+	r.eachTree(func(method string, t *methodTree) {
+		trees[method] = t
+		methods = append(methods, method)
+	})
+	sort.Strings(methods)
+
+	var b strings.Builder
+	for _, method := range methods {
+		b.WriteString(method + "\n")
+		if root := trees[method].root; root != nil && (root.path != "" || len(root.children) > 0 || root.data != nil) {
+			root.dump(&b, 1)
+		}
+	}
+	return b.String()
+}
+
+// firstRoute returns the route held by n or, if none, by the first node below n
+// holding one, in the order of the children.
+func (n *node) firstRoute() *Route {
+	var rt *Route
+	n.walk(func(n *node) {
+		if rt == nil {
+			rt, _ = n.data.(*Route)
+		}
+	})
+	return rt
+}
+
+// findNode returns the node holding the handle registered for exactly the
+// given path, wildcards included, or nil if there is none.
+func (n *node) findNode(path string) *node {
+walk:
+	for {
+		if !strings.HasPrefix(path, n.path) {
+			return nil
+		}
+		path = path[len(n.path):]
+		if path == "" {
+			if n.data == nil {
+				return nil
+			}
+			return n
+		}
+
+		// wildcard child, or the subpath after a param
+		if n.wildChild || (n.nType == param && len(n.children) == 1) {
+			n = n.children[0]
+			continue
+		}
+		c := path[0]
+		for i := 0; i < len(n.indices); i++ {
+			if c == n.indices[i] {
+				n = n.children[i]
+				continue walk
+			}
+		}
+		return nil
+	}
+}
+
+// size of the stack buffer collecting the wildcard values in getValue
+const paramsBufSize = 8
+
 // Returns the handle registered with the given path (key). The values of
 // wildcards are saved to a map.
 // If no handle can be found, a TSR (trailing slash redirect) recommendation is
 // made if a handle exists with an extra (without the) trailing slash for the
 // given path.
+// The returned Params are allocated at most once, with a capacity of exactly
+// the number of captured values. Nothing is allocated if no value is captured.
 func (n *node) getValue(path string) (data interface{}, p Params, tsr bool) {
+	// collect the values on the stack and copy them out once the walk is done,
+	// so the number of values is known
+	var buf [paramsBufSize]Param
+	data, values, tsr := n.find(path, buf[:0], false)
+	if len(values) > 0 {
+		p = make(Params, len(values))
+		copy(p, values)
+	}
+	return data, p, tsr
+}
+
+// getValueNoTSR is like getValue, but skips the trailing slash recommendation
+// and returns no Params if no handle is found.
+func (n *node) getValueNoTSR(path string) (data interface{}, p Params) {
+	var buf [paramsBufSize]Param
+	data, values, _ := n.find(path, buf[:0], true)
+	if data != nil && len(values) > 0 {
+		p = make(Params, len(values))
+		copy(p, values)
+	}
+	return data, p
+}
+
+// find implements getValue, appending the wildcard values to buf. If buf is
+// too small, a new buffer large enough for all remaining values is allocated.
+// If noTSR is set, no trailing slash recommendation is made.
+func (n *node) find(path string, buf Params, noTSR bool) (data interface{}, p Params, tsr bool) {
+	p = buf
 walk: // outer loop for walking the tree
 	for {
 		if len(path) > len(n.path) {
@@ -344,7 +684,7 @@ walk: // outer loop for walking the tree
 					// Nothing found.
 					// We can recommend to redirect to the same URL without a
 					// trailing slash if a leaf exists for that path.
-					tsr = (path == "/" && n.data != nil)
+					tsr = !noTSR && path == "/" && n.data != nil
 					return
 
 				}
@@ -360,9 +700,9 @@ walk: // outer loop for walking the tree
 					}
 
 					// save param value
-					if p == nil {
-						// lazy allocation
-						p = make(Params, 0, n.maxParams)
+					if cap(p)-len(p) < int(n.maxParams) {
+						// grow once to hold all remaining values
+						p = append(make(Params, 0, len(p)+int(n.maxParams)), p...)
 					}
 					i := len(p)
 					p = p[:i+1] // expand slice within preallocated capacity
@@ -378,13 +718,18 @@ walk: // outer loop for walking the tree
 						}
 
 						// ... but we can't
-						tsr = (len(path) == end+1)
+						tsr = !noTSR && len(path) == end+1
 						return
 					}
 
 					if data = n.data; data != nil {
 						return
-					} else if len(n.children) == 1 {
+					} else if len(n.children) == 1 && n.children[0].path == "" {
+						// a catch-all follows the param, e.g. /:service/*rest,
+						// below a node with an empty path
+						path, n = "", n.children[0]
+						continue walk
+					} else if !noTSR && len(n.children) == 1 {
 						// No handle found. Check if a handle for this path + a
 						// trailing slash exists for TSR recommendation
 						n = n.children[0]
@@ -395,9 +740,9 @@ walk: // outer loop for walking the tree
 
 				case catchAll:
 					// save param value
-					if p == nil {
-						// lazy allocation
-						p = make(Params, 0, n.maxParams)
+					if cap(p)-len(p) < int(n.maxParams) {
+						// grow once to hold all remaining values
+						p = append(make(Params, 0, len(p)+int(n.maxParams)), p...)
 					}
 					i := len(p)
 					p = p[:i+1] // expand slice within preallocated capacity
@@ -408,7 +753,8 @@ walk: // outer loop for walking the tree
 					return
 
 				default:
-					panic("invalid node type")
+					// a corrupted tree matches nothing
+					return nil, nil, false
 				}
 			}
 		} else if path == n.path {
@@ -418,6 +764,16 @@ walk: // outer loop for walking the tree
 				return
 			}
 
+			if leaf := n.emptyCatchAll(); leaf != nil {
+				data = leaf.data
+				p = append(p, Param{Key: leaf.path[2:]})
+				return
+			}
+
+			if noTSR {
+				return
+			}
+
 			if path == "/" && n.wildChild && n.nType != root {
 				tsr = true
 				return
@@ -439,13 +795,34 @@ walk: // outer loop for walking the tree
 
 		// Nothing found. We can recommend to redirect to the same URL with an
 		// extra trailing slash if a leaf exists for that path
-		tsr = (path == "/") ||
+		tsr = !noTSR && (path == "/" ||
 			(len(n.path) == len(path)+1 && n.path[len(path)] == '/' &&
-				path == n.path[:len(n.path)-1] && n.data != nil)
+				path == n.path[:len(n.path)-1] && n.data != nil))
 		return
 	}
 }
 
+// emptyCatchAll returns the catch-all leaf below n if its route matches the
+// path of n itself, with an empty value, see RouteOptions.EmptyCatchAll.
+func (n *node) emptyCatchAll() *node {
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] == '/' {
+			if c := n.children[i]; c.nType == catchAll && matchesEmpty(c.children[0].data) {
+				return c.children[0]
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// matchesEmpty reports whether data is a route set to match an empty
+// catch-all value.
+func matchesEmpty(data interface{}) bool {
+	rt, ok := data.(*Route)
+	return ok && rt.Options.EmptyCatchAll
+}
+
 // Makes a case-insensitive lookup of the given path and tries to find a handler.
 // It can optionally also fix trailing slashes.
 // It returns the case-corrected path and a bool indicating whether the lookup
@@ -610,7 +987,8 @@ walk: // outer loop for walking the tree
 				return append(ciPath, path...), true
 
 			default:
-				panic("invalid node type")
+				// a corrupted tree matches nothing
+				return ciPath, false
 			}
 		} else {
 			// We should have reached the node containing the handle.
@@ -6,10 +6,12 @@ package xrouter
 
 import (
 	"fmt"
+	"math/rand"
 	"reflect"
 	"regexp"
 	"strings"
 	"testing"
+	"unsafe"
 )
 
 func printChildren(n *node, prefix string) {
@@ -184,6 +186,58 @@ func TestTreeWildcard(t *testing.T) {
 	checkMaxParams(t, tree)
 }
 
+func TestTreeParamCatchAll(t *testing.T) {
+	tree := &node{}
+	for _, route := range [...]string{
+		"/proxy/:service/*rest",
+		"/proxies",
+		"/p/:a/:b/*rest",
+	} {
+		if err := tree.addRoute(route, route); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkRequests(t, tree, testRequests{
+		{"/proxy/auth/login/callback", false, "/proxy/:service/*rest", Params{{"service", "auth"}, {"rest", "/login/callback"}}},
+		{"/proxy/auth/", false, "/proxy/:service/*rest", Params{{"service", "auth"}, {"rest", "/"}}},
+		{"/proxy/auth/a/b/c/d/", false, "/proxy/:service/*rest", Params{{"service", "auth"}, {"rest", "/a/b/c/d/"}}},
+		{"/proxy/auth//x", false, "/proxy/:service/*rest", Params{{"service", "auth"}, {"rest", "//x"}}},
+		{"/proxy/a.b-c/v1/x?y", false, "/proxy/:service/*rest", Params{{"service", "a.b-c"}, {"rest", "/v1/x?y"}}},
+		{"/proxy/auth", true, "", Params{{"service", "auth"}}},
+		{"/proxy/", true, "", nil},
+		{"/proxies", false, "/proxies", nil},
+		{"/p/x/y/z/w", false, "/p/:a/:b/*rest", Params{{"a", "x"}, {"b", "y"}, {"rest", "/z/w"}}},
+	})
+
+	// the trailing slash is recommended for the path before the catch-all
+	if _, _, tsr := tree.getValue("/proxy/auth"); !tsr {
+		t.Error("no trailing slash recommendation for /proxy/auth")
+	}
+
+	// the compact trees capture the same values
+	for _, impl := range []Impl{ImplIndexed, ImplBinarySearch} {
+		router := NewWithImpl(impl)
+		router.GET("/proxy/:service/*rest", "proxy")
+		router.HandleOptions("GET", "/empty/:service/*rest", "empty", RouteOptions{EmptyCatchAll: true})
+		router.Compact()
+		_, ps, _ := router.Lookup("GET", "/proxy/auth/login/callback")
+		if want := (Params{{"service", "auth"}, {"rest", "/login/callback"}}); !reflect.DeepEqual(ps, want) {
+			t.Errorf("%v: wrong params: %v", impl, ps)
+		}
+		if handle, _, tsr := router.Lookup("GET", "/proxy/auth"); handle != nil || !tsr {
+			t.Errorf("%v: no trailing slash recommendation for /proxy/auth", impl)
+		}
+		handle, ps, _ := router.Lookup("GET", "/empty/auth")
+		if want := (Params{{"service", "auth"}, {"rest", ""}}); handle != "empty" || !reflect.DeepEqual(ps, want) {
+			t.Errorf("%v: wrong result for an empty catch-all: %v, %v", impl, handle, ps)
+		}
+	}
+
+	checkPriorities(t, tree)
+	checkMaxParams(t, tree)
+}
+
 func catchPanic(testFunc func()) (recv interface{}) {
 	defer func() {
 		recv = recover()
@@ -607,8 +661,6 @@ func TestTreeFindCaseInsensitivePath(t *testing.T) {
 }
 
 func TestTreeInvalidNodeType(t *testing.T) {
-	const panicMsg = "invalid node type"
-
 	tree := &node{}
 	tree.addRoute("/", "/")
 	tree.addRoute("/:page", "/:page")
@@ -618,18 +670,25 @@ func TestTreeInvalidNodeType(t *testing.T) {
 
 	// normal lookup
 	recv := catchPanic(func() {
-		tree.getValue("/test")
+		if data, ps, _ := tree.getValue("/test"); data != nil || ps != nil {
+			t.Errorf("corrupted tree matched: %v, %v", data, ps)
+		}
+		if data, _, _ := newCompactTree(tree).getValue("/test"); data != nil {
+			t.Errorf("corrupted compact tree matched: %v", data)
+		}
 	})
-	if rs, ok := recv.(string); !ok || rs != panicMsg {
-		t.Fatalf("Expected panic '"+panicMsg+"', got '%v'", recv)
+	if recv != nil {
+		t.Fatalf("lookup panicked: %v", recv)
 	}
 
 	// case-insensitive lookup
 	recv = catchPanic(func() {
-		tree.findCaseInsensitivePath("/test", true)
+		if _, found := tree.findCaseInsensitivePath("/test", true); found {
+			t.Error("corrupted tree matched case-insensitively")
+		}
 	})
-	if rs, ok := recv.(string); !ok || rs != panicMsg {
-		t.Fatalf("Expected panic '"+panicMsg+"', got '%v'", recv)
+	if recv != nil {
+		t.Fatalf("case-insensitive lookup panicked: %v", recv)
 	}
 }
 
@@ -640,9 +699,8 @@ func TestTreeWildcardConflictEx(t *testing.T) {
 		existPath    string
 		existSegPath string
 	}{
-		{"/who/are/foo", "/foo", `/who/are/\*you`, `/\*you`},
-		{"/who/are/foo/", "/foo/", `/who/are/\*you`, `/\*you`},
-		{"/who/are/foo/bar", "/foo/bar", `/who/are/\*you`, `/\*you`},
+		{"/who/are/:foo", "/:foo", `/who/are/\*you`, `/\*you`},
+		{"/who/are/foo/*bar", `/foo/\*bar`, `/who/are/\*you`, `/\*you`},
 		{"/conxxx", "xxx", `/con:tact`, `:tact`},
 		{"/conooo/xxx", "ooo", `/con:tact`, `:tact`},
 	}
@@ -669,3 +727,423 @@ func TestTreeWildcardConflictEx(t *testing.T) {
 		}
 	}
 }
+
+func TestTreeCatchAllShadowing(t *testing.T) {
+	shadowed := [...]string{
+		"/who/are/foo",
+		"/who/are/foo/",
+		"/who/are/foo/bar",
+		"/who/are/",
+	}
+
+	for _, route := range shadowed {
+		tree := &node{}
+		tree.addRoute("/who/are/*you", "/who/are/*you")
+
+		recv := tree.addRoute(route, route)
+		want := fmt.Sprintf("path '%s' is shadowed by existing catch-all '/who/are/*you'", route)
+		if recv == nil || recv.Error() != want {
+			t.Errorf("invalid shadowing error for route '%s': want %q, got %v", route, want, recv)
+		}
+	}
+
+	// the reverse order is still reported as a conflict of the wildcard route
+	tree := &node{}
+	tree.addRoute("/who/are/foo", "/who/are/foo")
+	recv := tree.addRoute("/who/are/*you", "/who/are/*you")
+	if recv == nil || !strings.Contains(recv.Error(), "conflicts with existing children") {
+		t.Errorf("invalid conflict error for catch-all after static route: %v", recv)
+	}
+
+	// a static route beside the catch-all is not shadowed
+	if recv := tree.addRoute("/who/is/foo", "/who/is/foo"); recv != nil {
+		t.Errorf("unexpected error for route beside catch-all: %v", recv)
+	}
+}
+
+func TestTreeFindNode(t *testing.T) {
+	tree := &node{}
+
+	routes := [...]string{
+		"/",
+		"/cmd/:tool/:sub",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/search/",
+		"/search/:query",
+		"/user_:name",
+		"/user_:name/about",
+		"/doc/go_faq.html",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, route)
+	}
+
+	for _, route := range routes {
+		if n := tree.findNode(route); n == nil || n.data != route {
+			t.Errorf("node for route '%s' not found", route)
+		}
+	}
+
+	missing := [...]string{
+		"/cmd",
+		"/cmd/:tool",
+		"/cmd/:tools/",
+		"/cmd/vet/",
+		"/src/",
+		"/src/*file",
+		"/search",
+		"/user_:name/",
+		"/doc/",
+		"/doc/go_faq.htm",
+	}
+	for _, route := range missing {
+		if n := tree.findNode(route); n != nil {
+			t.Errorf("got node for unregistered route '%s': %v", route, n.data)
+		}
+	}
+}
+
+func TestTreeRemove(t *testing.T) {
+	routes := []string{
+		"/",
+		"/hi",
+		"/contact",
+		"/co",
+		"/c",
+		"/cmd/:tool/:sub",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/search/",
+		"/search/:query",
+		"/user_:name",
+		"/user_:name/about",
+		"/files/:dir/*filepath",
+		"/doc/",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/info/:user/public",
+		"/info/:user/project/:project",
+		"/α",
+		"/β",
+	}
+	probes := []string{
+		"/cmd/test/",
+		"/cmd/test",
+		"/cmd/test/3",
+		"/src/",
+		"/src/some/file.png",
+		"/search/gopher",
+		"/search/gopher/",
+		"/user_gopher",
+		"/user_gopher/about",
+		"/files/js/inc/framework.js",
+		"/info/gordon/public",
+		"/info/gordon/project/go",
+		"/doc",
+		"/con",
+	}
+
+	// remove the routes in both orders, checking after each removal that the
+	// tree behaves like a tree built from the remaining routes only
+	for _, reverse := range []bool{false, true} {
+		tree := &node{}
+		for _, route := range routes {
+			if err := tree.addRoute(route, route); err != nil {
+				t.Fatalf("error inserting route '%s': %v", route, err)
+			}
+		}
+
+		remaining := append([]string(nil), routes...)
+		for len(remaining) > 0 {
+			var route string
+			if reverse {
+				route, remaining = remaining[len(remaining)-1], remaining[:len(remaining)-1]
+			} else {
+				route, remaining = remaining[0], remaining[1:]
+			}
+
+			data, err := tree.removeRoute(route)
+			if err != nil {
+				t.Fatalf("error removing route '%s': %v", route, err)
+			}
+			if data != route {
+				t.Errorf("wrong handle removed for route '%s': %v", route, data)
+			}
+			if data, _, _ := tree.getValue(route); data == route {
+				t.Errorf("route '%s' still matches after removal", route)
+			}
+
+			want := &node{}
+			for _, route := range remaining {
+				want.addRoute(route, route)
+			}
+			for _, path := range append(routes, probes...) {
+				data, ps, tsr := tree.getValue(path)
+				wantData, wantPs, wantTsr := want.getValue(path)
+				if data != wantData || !reflect.DeepEqual(ps, wantPs) || tsr != wantTsr {
+					t.Errorf("mismatch for path '%s' after removing '%s': got (%v, %v, %t), want (%v, %v, %t)",
+						path, route, data, ps, tsr, wantData, wantPs, wantTsr)
+				}
+			}
+
+			checkPriorities(t, tree)
+			checkMaxParams(t, tree)
+		}
+
+		// the tree can be reused once all routes are removed
+		if err := tree.addRoute("/:test", "/:test"); err != nil {
+			t.Errorf("error inserting route into emptied tree: %v", err)
+		}
+	}
+}
+
+// TestTreeRemoveRandom registers random routes, removes a random subset of
+// them and compares the lookups with a tree built from the remaining routes.
+func TestTreeRemoveRandom(t *testing.T) {
+	segments := []string{"a", "ab", "abc", "f", "x_:n", ":p", ":q", "*rest"}
+	rounds := 500
+	if testing.Short() {
+		rounds = 50
+	}
+	for seed := 0; seed < rounds; seed++ {
+		rnd := rand.New(rand.NewSource(int64(seed)))
+
+		var routes []string
+		tree := &node{}
+		for i := 0; i < 12; i++ {
+			var path strings.Builder
+			for j, n := 0, 1+rnd.Intn(4); j < n; j++ {
+				segment := segments[rnd.Intn(len(segments))]
+				path.WriteString("/" + segment)
+				if segment == "*rest" {
+					break
+				}
+			}
+			route := path.String()
+			if !strings.Contains(route, "*") && rnd.Intn(3) == 0 {
+				route += "/"
+			}
+			if tree.addRoute(route, route) == nil {
+				routes = append(routes, route)
+			}
+		}
+
+		var remaining []string
+		for _, route := range routes {
+			if rnd.Intn(2) == 0 {
+				remaining = append(remaining, route)
+				continue
+			}
+			if _, err := tree.removeRoute(route); err != nil {
+				t.Fatalf("seed %d: error removing route '%s': %v", seed, route, err)
+			}
+		}
+		want := &node{}
+		for _, route := range remaining {
+			if err := want.addRoute(route, route); err != nil {
+				t.Fatalf("seed %d: error inserting route '%s': %v", seed, route, err)
+			}
+		}
+
+		var probes []string
+		for _, route := range routes {
+			path := requestPath(route)
+			for i := 1; i <= len(path); i++ {
+				probes = append(probes, path[:i], path[:i]+"/")
+			}
+		}
+		for _, path := range probes {
+			data, ps, tsr := tree.getValue(path)
+			wantData, wantPs, wantTsr := want.getValue(path)
+			if data != wantData || !reflect.DeepEqual(ps, wantPs) || tsr != wantTsr {
+				t.Fatalf("seed %d: mismatch for path '%s' after removing routes of %q, keeping %q: got (%v, %v, %t), want (%v, %v, %t)",
+					seed, path, routes, remaining, data, ps, tsr, wantData, wantPs, wantTsr)
+			}
+		}
+		checkPriorities(t, tree)
+	}
+}
+
+func TestTreeRemoveCatchAllTSR(t *testing.T) {
+	for _, routes := range [][]string{
+		{"/abc/ab/f/f/", "/ab/*rest"},
+		{"/ab", "/ab/*rest"},
+	} {
+		tree := &node{}
+		for _, route := range routes {
+			tree.addRoute(route, route)
+		}
+		tree.removeRoute(routes[0])
+		if _, _, tsr := tree.getValue("/ab"); !tsr {
+			t.Errorf("no trailing slash redirect for /ab after removing %s", routes[0])
+		}
+	}
+}
+
+// TestTreeRemoveAfterFailedAdd removes the route a failed registration
+// conflicted with, which must not leave anything blocking the registration.
+func TestTreeRemoveAfterFailedAdd(t *testing.T) {
+	for _, test := range []struct {
+		route, conflicting string
+	}{
+		{"/files/*filepath", "/files/readme"},
+		{"/user/:name", "/user/:id/x"},
+		{"/src/", "/src/*filepath"},
+	} {
+		router := New()
+		router.GET(test.route, test.route)
+		if err := router.GET(test.conflicting, test.conflicting); err == nil {
+			t.Fatalf("no error registering %s next to %s", test.conflicting, test.route)
+		}
+		if err := router.Remove("GET", test.route); err != nil {
+			t.Fatal(err)
+		}
+		if err := router.GET(test.conflicting, test.conflicting); err != nil {
+			t.Errorf("error registering %s after removing %s: %v", test.conflicting, test.route, err)
+		}
+		if dump := router.Dump(); strings.Count(dump, "->") != 1 {
+			t.Errorf("unexpected routes left after removing %s:\n%s", test.route, dump)
+		}
+	}
+}
+
+func TestTreeRemoveMerge(t *testing.T) {
+	tree := &node{}
+	tree.addRoute("/doc/", "/doc/")
+	tree.addRoute("/doc/go_faq.html", "/doc/go_faq.html")
+	tree.addRoute("/doc/go1.html", "/doc/go1.html")
+
+	tree.removeRoute("/doc/go_faq.html")
+	if tree.path != "/doc/" || len(tree.children) != 1 || tree.children[0].path != "go1.html" {
+		t.Errorf("edge not merged after removal: path '%s', %d children", tree.path, len(tree.children))
+	}
+
+	tree.removeRoute("/doc/")
+	if tree.path != "/doc/go1.html" || len(tree.children) != 0 {
+		t.Errorf("edge not merged after removal: path '%s', %d children", tree.path, len(tree.children))
+	}
+	if tree.nType != root || tree.data != "/doc/go1.html" {
+		t.Errorf("wrong root after merge: type %d, data %v", tree.nType, tree.data)
+	}
+}
+
+func TestTreeRemoveMissing(t *testing.T) {
+	tree := &node{}
+	routes := [...]string{
+		"/cmd/:tool/:sub",
+		"/src/*filepath",
+		"/doc/go_faq.html",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, route)
+	}
+
+	missing := [...]string{
+		"/",
+		"/cmd/:tool",
+		"/cmd/:tools/:sub",
+		"/cmd/vet/",
+		"/src/",
+		"/src/*file",
+		"/doc/",
+		"/doc/go_faq",
+	}
+	for _, route := range missing {
+		if _, err := tree.removeRoute(route); err == nil {
+			t.Errorf("no error removing unregistered route '%s'", route)
+		}
+	}
+	for _, route := range routes {
+		if data, _, _ := tree.getValue(route); data != route {
+			t.Errorf("route '%s' lost by removing unregistered routes", route)
+		}
+	}
+
+	tree.removeRoute("/doc/go_faq.html")
+	if _, err := tree.removeRoute("/doc/go_faq.html"); err == nil {
+		t.Error("no error removing route twice")
+	}
+}
+
+func TestTreeNodeSize(t *testing.T) {
+	// path, indices, children and data take 9 words, priority and the small
+	// fields share another one
+	want := 10 * unsafe.Sizeof(uintptr(0))
+	if size := unsafe.Sizeof(node{}); size != want {
+		t.Errorf("wrong node size: want %d, got %d", want, size)
+	}
+}
+
+func TestRouterDump(t *testing.T) {
+	router := New()
+	router.GET("/", "index")
+	router.GET("/user/:name", "user")
+	router.GET("/user/:name/posts", "posts")
+	router.GET("/src/*filepath", "src")
+	router.GET("/search/", "search")
+	router.POST("/user", "create")
+	router.StrictSlash("PUT", true)
+
+	want := `GET
+  "/" -> /
+    "user/" wildChild
+      ":name" param -> /user/:name
+        "/posts" -> /user/:name/posts
+    "s"
+      "rc"
+        "" catch-all wildChild
+          "/*filepath" catch-all -> /src/*filepath
+      "earch/" -> /search/
+POST
+  "/user" -> /user
+PUT
+`
+	for i := 0; i < 3; i++ {
+		if got := router.Dump(); got != want {
+			t.Fatalf("wrong dump:\n%s\nwant:\n%s", got, want)
+		}
+	}
+}
+
+func TestRouterFailedHandleUnchanged(t *testing.T) {
+	router := New()
+	router.GET("/a/x", "ax")
+	router.GET("/b/1", "b1")
+	router.GET("/b/2", "b2")
+	router.GET("/search/", "search")
+	router.GET("/src/*filepath", "src")
+	router.GET("/user/:name", "user")
+	want := router.Dump()
+
+	for _, path := range []string{
+		// conflicts found below nodes whose priority was raised
+		"/a/:p", "/a/:q", "/a/:r",
+		// conflict found after splitting the edge of "/search/"
+		"/sea:x",
+		"/src/readme",
+		"/user/:id",
+		"/user/:id/x",
+	} {
+		if err := router.GET(path, path); err == nil {
+			t.Fatalf("no error registering %s", path)
+		}
+		if got := router.Dump(); got != want {
+			t.Fatalf("failed registration of %s changed the tree:\n%s\nwant:\n%s", path, got, want)
+		}
+	}
+	if err := router.Handle("PUT", "/sea:x:y", "x"); err == nil {
+		t.Fatal("no error registering an invalid wildcard")
+	}
+	if got := router.Dump(); got != want {
+		t.Errorf("failed registration for a new method added a tree:\n%s", got)
+	}
+
+	// the tree stays usable
+	if err := router.GET("/a/y", "ay"); err != nil {
+		t.Fatal(err)
+	}
+	if handle, _, _ := router.Lookup("GET", "/a/y"); handle != "ay" {
+		t.Errorf("wrong handle for /a/y: %v", handle)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrHandleType is returned by LookupAs for a matched handle which is not of
+// the requested type, and by the registrations of routers returned by
+// NewStrict for handles not of the type of the router, or matching none of the
+// adapters, see RegisterAdapter.
+var ErrHandleType = errors.New("handle of unexpected type")
+
+// TypedRouter is a Router whose handles are of type T, so lookups need no type
+// assertion. It wraps a Router, which holds the routes and settings.
+type TypedRouter[T any] struct {
+	router *Router
+}
+
+// NewTyped returns a new initialized TypedRouter wrapping a Router returned
+// by New.
+func NewTyped[T any]() *TypedRouter[T] {
+	return &TypedRouter[T]{router: New()}
+}
+
+// Router returns the wrapped Router, e.g. to change its settings. Routes
+// registered with it using handles of another type than T are missed by the
+// lookups of the TypedRouter.
+func (r *TypedRouter[T]) Router() *Router {
+	return r.router
+}
+
+// GET is a shortcut for router.Handle("GET", path, handle)
+func (r *TypedRouter[T]) GET(path string, handle T) error {
+	return r.Handle("GET", path, handle)
+}
+
+// HEAD is a shortcut for router.Handle("HEAD", path, handle)
+func (r *TypedRouter[T]) HEAD(path string, handle T) error {
+	return r.Handle("HEAD", path, handle)
+}
+
+// OPTIONS is a shortcut for router.Handle("OPTIONS", path, handle)
+func (r *TypedRouter[T]) OPTIONS(path string, handle T) error {
+	return r.Handle("OPTIONS", path, handle)
+}
+
+// POST is a shortcut for router.Handle("POST", path, handle)
+func (r *TypedRouter[T]) POST(path string, handle T) error {
+	return r.Handle("POST", path, handle)
+}
+
+// PUT is a shortcut for router.Handle("PUT", path, handle)
+func (r *TypedRouter[T]) PUT(path string, handle T) error {
+	return r.Handle("PUT", path, handle)
+}
+
+// PATCH is a shortcut for router.Handle("PATCH", path, handle)
+func (r *TypedRouter[T]) PATCH(path string, handle T) error {
+	return r.Handle("PATCH", path, handle)
+}
+
+// DELETE is a shortcut for router.Handle("DELETE", path, handle)
+func (r *TypedRouter[T]) DELETE(path string, handle T) error {
+	return r.Handle("DELETE", path, handle)
+}
+
+// Handle registers a new handle with the given path and method, see
+// Router.Handle.
+func (r *TypedRouter[T]) Handle(method, path string, handle T) error {
+	return r.router.HandleOptions(method, path, handle, RouteOptions{})
+}
+
+// HandleOptions registers a new handle with the given path, method and
+// per-route options, see Router.HandleOptions.
+func (r *TypedRouter[T]) HandleOptions(method, path string, handle T, opts RouteOptions) error {
+	return r.router.HandleOptions(method, path, handle, opts)
+}
+
+// Lookup looks up the handle registered for the given method and path, see
+// Router.Lookup. If no handle of type T matches, it returns the zero value of
+// T, no Params and false.
+func (r *TypedRouter[T]) Lookup(method, path string) (T, Params, bool) {
+	rt, ps, _ := r.router.LookupRoute(method, path)
+	if rt != nil {
+		if handle, ok := rt.Handle.(T); ok {
+			return handle, ps, true
+		}
+	}
+	var zero T
+	return zero, nil, false
+}
+
+// NewStrict returns a new initialized Router like New which only accepts
+// handles of the type of prototype: Handle, Replace and their variants return
+// an error wrapping ErrHandleType for handles which are not assignable to it.
+// To accept all handles implementing an interface, pass a nil pointer to
+// the interface, e.g.
+//
+//	router := xrouter.NewStrict((*http.Handler)(nil))
+//
+// Placeholder is accepted in any case, and a nil prototype accepts all handles.
+func NewStrict(prototype interface{}) *Router {
+	r := New()
+	r.handleType = reflect.TypeOf(prototype)
+	if t := r.handleType; t != nil && t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Interface {
+		r.handleType = t.Elem()
+	}
+	return r
+}
+
+// checkHandleType verifies that handle is of the type the router accepts, see
+// NewStrict.
+func (r *Router) checkHandleType(path string, handle interface{}) error {
+	if r.handleType == nil {
+		return nil
+	}
+	if _, ok := handle.(Placeholder); ok {
+		return nil
+	}
+	if t := reflect.TypeOf(handle); !t.AssignableTo(r.handleType) {
+		return fmt.Errorf("path '%s': %w: %v is not %v", path, ErrHandleType, t, r.handleType)
+	}
+	return nil
+}
+
+// LookupAs is like r.Lookup, but returns the handle as a T, e.g. for frameworks
+// registering handles of a single type. T may be an interface type like
+// http.Handler, matching all handles implementing it. If the matched handle
+// is not a T, LookupAs returns an error naming the route and the type of the
+// handle, which wraps ErrHandleType.
+// On a miss it returns the zero value of T along with the trailing slash
+// recommendation, see Router.Lookup.
+func LookupAs[T any](r *Router, method, path string) (T, Params, bool, error) {
+	var zero T
+	rt, ps, tsr := r.LookupRoute(method, path)
+	if rt == nil {
+		return zero, ps, tsr, nil
+	}
+	handle, ok := rt.Handle.(T)
+	if !ok {
+		return zero, nil, false, fmt.Errorf("route '%s %s': %w: %T is not %v",
+			rt.Method, rt.Path, ErrHandleType, rt.Handle, reflect.TypeOf((*T)(nil)).Elem())
+	}
+	return handle, ps, tsr, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2013 Julien Schmidt. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be found
+// in the LICENSE file.
+
+package xrouter
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTypedRouterFunc(t *testing.T) {
+	router := NewTyped[func(Params) string]()
+	router.GET("/user/:name", func(ps Params) string { return "user " + ps.ByName("name") })
+	router.POST("/user", func(Params) string { return "create" })
+
+	if err := router.GET("/user/:id", func(Params) string { return "" }); !errors.Is(err, ErrConflict) {
+		t.Errorf("wrong error: %v", err)
+	}
+	if err := router.GET("/nil", nil); !errors.Is(err, ErrNilHandle) {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	handle, ps, ok := router.Lookup("GET", "/user/gopher")
+	if !ok || handle(ps) != "user gopher" {
+		t.Errorf("wrong result: %v, %v", ok, ps)
+	}
+	if handle, _, ok := router.Lookup("POST", "/user"); !ok || handle(nil) != "create" {
+		t.Errorf("wrong result for POST: %v", ok)
+	}
+
+	handle, ps, ok = router.Lookup("GET", "/user/gopher/")
+	if ok || handle != nil || ps != nil {
+		t.Errorf("expected a miss, got %v, %v", ok, ps)
+	}
+
+}
+
+type typedHandler struct {
+	name  string
+	admin bool
+}
+
+func TestTypedRouterStruct(t *testing.T) {
+	router := NewTyped[typedHandler]()
+	router.GET("/admin/*rest", typedHandler{name: "admin", admin: true})
+	router.Handle("PATCH", "/item/:id", typedHandler{name: "item"})
+	if err := router.Router().GET("/other", "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	handle, ps, ok := router.Lookup("GET", "/admin/users")
+	if !ok || handle != (typedHandler{"admin", true}) || ps.ByName("rest") != "/users" {
+		t.Errorf("wrong result: %v, %v, %v", handle, ps, ok)
+	}
+	if handle, _, ok := router.Lookup("PATCH", "/item/1"); !ok || handle.name != "item" {
+		t.Errorf("wrong result: %v, %v", handle, ok)
+	}
+	if handle, _, ok := router.Lookup("GET", "/item/1"); ok || handle != (typedHandler{}) {
+		t.Errorf("expected the zero value, got %v, %v", handle, ok)
+	}
+
+	// handles of other types registered with the wrapped router are missed
+	if _, _, ok := router.Lookup("GET", "/other"); ok {
+		t.Error("handle of another type returned")
+	}
+}
+
+func TestLookupAs(t *testing.T) {
+	router := New()
+	router.GET("/handler", http.NotFoundHandler())
+	router.GET("/func", http.HandlerFunc(http.NotFound))
+	router.GET("/string", "string")
+	router.GET("/dir/", "dir")
+
+	// stored concrete types implementing the interface
+	for _, path := range []string{"/handler", "/func"} {
+		handler, _, _, err := LookupAs[http.Handler](router, "GET", path)
+		if err != nil || handler == nil {
+			t.Errorf("wrong result for %s: %v, %v", path, handler, err)
+		}
+	}
+
+	if handle, _, _, err := LookupAs[string](router, "GET", "/string"); err != nil || handle != "string" {
+		t.Errorf("wrong result: %q, %v", handle, err)
+	}
+
+	handler, _, _, err := LookupAs[http.Handler](router, "GET", "/string")
+	if !errors.Is(err, ErrHandleType) || handler != nil {
+		t.Fatalf("wrong result for a mismatch: %v, %v", handler, err)
+	}
+	for _, part := range []string{"GET /string", "string is not http.Handler"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("error doesn't contain %q: %v", part, err)
+		}
+	}
+
+	// misses are no error
+	handle, _, tsr, err := LookupAs[string](router, "GET", "/dir")
+	if handle != "" || !tsr || err != nil {
+		t.Errorf("wrong result for a miss: %q, %v, %v", handle, tsr, err)
+	}
+}
+
+func TestNewStrict(t *testing.T) {
+	router := NewStrict(Handle(nil))
+	if err := router.GET("/handle", Handle(func(http.ResponseWriter, *http.Request, Params) {})); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.GET("/func", func(http.ResponseWriter, *http.Request, Params) {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.GET("/reserved", Placeholder{}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := router.GET("/handler", http.HandlerFunc(http.NotFound))
+	if !errors.Is(err, ErrHandleType) || err.Error() != "path '/handler': handle of unexpected type: http.HandlerFunc is not xrouter.Handle" {
+		t.Errorf("wrong error: %v", err)
+	}
+	if err := router.Replace("GET", "/handle", "string"); !errors.Is(err, ErrHandleType) {
+		t.Errorf("wrong error for Replace: %v", err)
+	}
+
+	// interface prototypes accept all implementations
+	router = NewStrict((*http.Handler)(nil))
+	if err := router.GET("/func", http.HandlerFunc(http.NotFound)); err != nil {
+		t.Error(err)
+	}
+	if err := router.GET("/handler", http.NotFoundHandler()); err != nil {
+		t.Error(err)
+	}
+	if err := router.GET("/handle", Handle(func(http.ResponseWriter, *http.Request, Params) {})); !errors.Is(err, ErrHandleType) {
+		t.Errorf("wrong error: %v", err)
+	}
+}